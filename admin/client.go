@@ -0,0 +1,168 @@
+package admin
+
+import (
+	"abbysoft/gardarike-online/logging"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client - client of the admin HTTP API used by the operator console
+type Client struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+func NewClient(endpoint string, token string) *Client {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		http:     &http.Client{},
+	}
+}
+
+func (c *Client) do(method, path string, body interface{}, timeout time.Duration) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, c.endpoint+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	client := *c.http
+	client.Timeout = timeout
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		defer response.Body.Close()
+
+		var apiErr errorView
+		data, _ := ioutil.ReadAll(response.Body)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("admin API error (%d): %s", response.StatusCode, apiErr.Error)
+		}
+
+		return nil, fmt.Errorf("admin API error (%d)", response.StatusCode)
+	}
+
+	return response, nil
+}
+
+func (c *Client) call(method, path string, body interface{}, result interface{}) error {
+	response, err := c.do(method, path, body, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Status() (result StatusView, err error) {
+	err = c.call(http.MethodGet, "/api/status", nil, &result)
+	return
+}
+
+func (c *Client) Sessions() (result []SessionView, err error) {
+	err = c.call(http.MethodGet, "/api/sessions", nil, &result)
+	return
+}
+
+func (c *Client) Character(id int64) (result CharacterView, err error) {
+	err = c.call(http.MethodGet, fmt.Sprintf("/api/characters/%d", id), nil, &result)
+	return
+}
+
+func (c *Client) EditCharacter(id int64, patch CharacterPatch) (result CharacterView, err error) {
+	err = c.call(http.MethodPatch, fmt.Sprintf("/api/characters/%d", id), patch, &result)
+	return
+}
+
+func (c *Client) Town(id int64) (result TownView, err error) {
+	err = c.call(http.MethodGet, fmt.Sprintf("/api/towns/%d", id), nil, &result)
+	return
+}
+
+func (c *Client) EditTown(id int64, patch TownPatch) (result TownView, err error) {
+	err = c.call(http.MethodPatch, fmt.Sprintf("/api/towns/%d", id), patch, &result)
+	return
+}
+
+func (c *Client) UpdateResources() error {
+	return c.call(http.MethodPost, "/api/resources/update", nil, nil)
+}
+
+func (c *Client) ModerationLog(limit int) (result []ModerationRecordView, err error) {
+	err = c.call(http.MethodGet, fmt.Sprintf("/api/moderation?limit=%d", limit), nil, &result)
+	return
+}
+
+func (c *Client) Logs(limit int) (result []LogLine, err error) {
+	err = c.call(http.MethodGet, fmt.Sprintf("/api/logs?limit=%d", limit), nil, &result)
+	return
+}
+
+func (c *Client) DebugTargets() (result []logging.DebugTarget, err error) {
+	err = c.call(http.MethodGet, "/api/logs/debug", nil, &result)
+	return
+}
+
+func (c *Client) SetDebugTarget(target logging.DebugTarget, enabled bool) (result []logging.DebugTarget, err error) {
+	err = c.call(http.MethodPost, "/api/logs/debug", DebugTargetPatch{DebugTarget: target, Enabled: enabled}, &result)
+	return
+}
+
+// FollowLogs - calls 'handle' for every log line until the stream is closed
+func (c *Client) FollowLogs(limit int, handle func(line LogLine)) error {
+	response, err := c.do(http.MethodGet, fmt.Sprintf("/api/logs?limit=%d&follow=true", limit), nil, 0)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var line LogLine
+		if err := decoder.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode log line: %w", err)
+		}
+
+		handle(line)
+	}
+}
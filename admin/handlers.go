@@ -0,0 +1,527 @@
+package admin
+
+import (
+	"abbysoft/gardarike-online/logging"
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/tracing"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type StatusView struct {
+	OnlineSessions     int       `json:"onlineSessions"`
+	SelectedCharacters int       `json:"selectedCharacters"`
+	LastTickTime       time.Time `json:"lastTickTime"`
+	LastTickDuration   string    `json:"lastTickDuration"`
+	GlobalChunks       int64     `json:"globalChunks"`
+	LocalChunks        int64     `json:"localChunks"`
+}
+
+type SessionView struct {
+	SessionID       string    `json:"sessionID"`
+	AccountID       int64     `json:"accountID"`
+	CharacterID     int64     `json:"characterID,omitempty"`
+	CharacterName   string    `json:"characterName,omitempty"`
+	LastRequestTime time.Time `json:"lastRequestTime"`
+}
+
+type ResourcesView struct {
+	Wood    uint64 `json:"wood"`
+	Food    uint64 `json:"food"`
+	Stone   uint64 `json:"stone"`
+	Leather uint64 `json:"leather"`
+	Gold    uint64 `json:"gold"`
+}
+
+func newResourcesView(r model.Resources) ResourcesView {
+	return ResourcesView{Wood: r.Wood, Food: r.Food, Stone: r.Stone, Leather: r.Leather, Gold: r.Gold}
+}
+
+func (r ResourcesView) toModel() model.Resources {
+	return model.Resources{Wood: r.Wood, Food: r.Food, Stone: r.Stone, Leather: r.Leather, Gold: r.Gold}
+}
+
+type TownView struct {
+	ID             int64         `json:"id"`
+	X              int64         `json:"x"`
+	Y              int64         `json:"y"`
+	Name           string        `json:"name"`
+	OwnerName      string        `json:"ownerName"`
+	Population     uint64        `json:"population"`
+	MaxPopulation  uint64        `json:"maxPopulation"`
+	Rotation       float32       `json:"rotation"`
+	Resources      ResourcesView `json:"resources"`
+	StorageLimit   ResourcesView `json:"storageLimit"`
+	ProductionRate ResourcesView `json:"productionRate"`
+	Debt           uint64        `json:"debt"`
+}
+
+func newTownView(t model.Town) TownView {
+	return TownView{
+		ID:             t.ID,
+		X:              t.X,
+		Y:              t.Y,
+		Name:           t.Name,
+		OwnerName:      t.OwnerName,
+		Population:     t.Population,
+		MaxPopulation:  t.MaxPopulation(),
+		Rotation:       t.Rotation,
+		Resources:      newResourcesView(t.Resources),
+		StorageLimit:   newResourcesView(t.StorageLimit()),
+		ProductionRate: newResourcesView(t.ProductionRate()),
+		Debt:           t.Debt,
+	}
+}
+
+// CharacterView - the character with the totals of all its towns
+type CharacterView struct {
+	ID                int64         `json:"id"`
+	AccountID         int64         `json:"accountID"`
+	Name              string        `json:"name"`
+	MaxPopulation     uint64        `json:"maxPopulation"`
+	CurrentPopulation uint64        `json:"currentPopulation"`
+	Happiness         uint64        `json:"happiness"`
+	TaxRate           uint64        `json:"taxRate"`
+	Resources         ResourcesView `json:"resources"`
+	ProductionRate    ResourcesView `json:"productionRate"`
+	Towns             []TownView    `json:"towns"`
+	Moderator         bool          `json:"moderator"`
+	Admin             bool          `json:"admin"`
+	MutedUntil        time.Time     `json:"mutedUntil"`
+	BannedUntil       time.Time     `json:"bannedUntil"`
+}
+
+func newCharacterView(c model.Character) CharacterView {
+	view := CharacterView{
+		ID:                c.ID,
+		AccountID:         c.AccountID,
+		Name:              c.Name,
+		MaxPopulation:     c.MaxPopulation(),
+		CurrentPopulation: c.Population(),
+		Happiness:         c.Happiness,
+		TaxRate:           c.TaxRate,
+		Resources:         newResourcesView(c.Resources()),
+		ProductionRate:    newResourcesView(c.ProductionRate()),
+		Towns:             []TownView{},
+		Moderator:         c.IsModerator,
+		Admin:             c.IsAdmin,
+		MutedUntil:        c.MutedUntil,
+		BannedUntil:       c.BannedUntil,
+	}
+
+	for _, town := range c.Towns {
+		view.Towns = append(view.Towns, newTownView(town))
+	}
+
+	return view
+}
+
+// CharacterPatch - character fields which can be changed by the operator, nil fields are left as is.
+// Population and resources belong to the towns
+type CharacterPatch struct {
+	TaxRate   *uint64 `json:"taxRate,omitempty"`
+	Moderator *bool   `json:"moderator,omitempty"`
+	Admin     *bool   `json:"admin,omitempty"`
+}
+
+// ModerationRecordView - action of the chat moderator
+type ModerationRecordView struct {
+	ID        int64     `json:"id"`
+	Moderator string    `json:"moderator"`
+	Target    string    `json:"target"`
+	Action    string    `json:"action"`
+	Until     time.Time `json:"until"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+}
+
+func newModerationRecordView(r model.ModerationRecord) ModerationRecordView {
+	return ModerationRecordView{
+		ID:        r.ID,
+		Moderator: r.Moderator,
+		Target:    r.Target,
+		Action:    r.Action.String(),
+		Until:     r.Until,
+		Reason:    r.Reason,
+		Time:      r.Time,
+	}
+}
+
+// TownPatch - town fields which can be changed by the operator, nil fields are left as is
+type TownPatch struct {
+	Name       *string        `json:"name,omitempty"`
+	OwnerName  *string        `json:"ownerName,omitempty"`
+	Population *uint64        `json:"population,omitempty"`
+	Resources  *ResourcesView `json:"resources,omitempty"`
+}
+
+type errorView struct {
+	Error string `json:"error"`
+}
+
+// logger - returns the admin logger tagged with the request info
+func (s *Server) logger(r *http.Request) *log.Entry {
+	return tracing.Logger(r.Context(), s.log)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorView{Error: message})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	sessions := s.logic.Sessions()
+	stats := s.logic.LoopStats()
+
+	status := StatusView{
+		OnlineSessions:   len(sessions),
+		LastTickTime:     stats.LastTickTime,
+		LastTickDuration: stats.LastTickDuration.String(),
+	}
+
+	for _, session := range sessions {
+		if session.CharacterID != 0 {
+			status.SelectedCharacters++
+		}
+	}
+
+	tx, err := s.logic.Database().BeginTransaction(r.Context(), true, true)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to begin transaction")
+		writeError(w, http.StatusInternalServerError, "failed to begin transaction")
+		return
+	}
+
+	chunks, err := tx.GetChunksCount()
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to count map chunks")
+		writeError(w, http.StatusInternalServerError, "failed to count map chunks")
+		return
+	}
+
+	status.GlobalChunks = chunks.Global
+	status.LocalChunks = chunks.Local
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	result := []SessionView{}
+	for _, session := range s.logic.Sessions() {
+		result = append(result, SessionView{
+			SessionID:       session.SessionID,
+			AccountID:       session.AccountID,
+			CharacterID:     session.CharacterID,
+			CharacterName:   session.CharacterName,
+			LastRequestTime: session.LastRequestTime,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCharacter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathID(r.URL.Path, "/api/characters/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid character id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getCharacter(w, r, id)
+	case http.MethodPatch:
+		s.patchCharacter(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) getCharacter(w http.ResponseWriter, r *http.Request, id int64) {
+	tx, err := s.logic.Database().BeginTransaction(r.Context(), false, true)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to begin transaction")
+		writeError(w, http.StatusInternalServerError, "failed to begin transaction")
+		return
+	}
+
+	character, err := tx.GetCharacter(id)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "character not found")
+		return
+	} else if err != nil {
+		s.logger(r).WithError(err).Error("Failed to get character")
+		writeError(w, http.StatusInternalServerError, "failed to get character")
+		return
+	}
+
+	character.Towns, err = tx.GetTowns(character.Name)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to get character towns")
+		writeError(w, http.StatusInternalServerError, "failed to get character towns")
+		return
+	}
+
+	if err := tx.EndTransaction(); err != nil {
+		s.logger(r).WithError(err).Error("Failed to end transaction")
+	}
+
+	writeJSON(w, http.StatusOK, newCharacterView(character))
+}
+
+func (s *Server) patchCharacter(w http.ResponseWriter, r *http.Request, id int64) {
+	var patch CharacterPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	character, err := s.logic.EditCharacter(r.Context(), id, func(character *model.Character) {
+		if patch.TaxRate != nil {
+			character.TaxRate = *patch.TaxRate
+		}
+		if patch.Moderator != nil {
+			character.IsModerator = *patch.Moderator
+		}
+		if patch.Admin != nil {
+			character.IsAdmin = *patch.Admin
+		}
+	})
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "character not found")
+		return
+	} else if err != nil {
+		s.logger(r).WithError(err).WithField("characterID", id).Error("Failed to edit character")
+		writeError(w, http.StatusInternalServerError, "failed to edit character")
+		return
+	}
+
+	s.logger(r).WithField("characterID", id).WithField("patch", patch).Info("Character edited by operator")
+	writeJSON(w, http.StatusOK, newCharacterView(character))
+}
+
+func (s *Server) handleTown(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathID(r.URL.Path, "/api/towns/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid town id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getTown(w, r, id)
+	case http.MethodPatch:
+		s.patchTown(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) getTown(w http.ResponseWriter, r *http.Request, id int64) {
+	tx, err := s.logic.Database().BeginTransaction(r.Context(), true, true)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to begin transaction")
+		writeError(w, http.StatusInternalServerError, "failed to begin transaction")
+		return
+	}
+
+	town, err := tx.GetTown(id)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "town not found")
+		return
+	} else if err != nil {
+		s.logger(r).WithError(err).Error("Failed to get town")
+		writeError(w, http.StatusInternalServerError, "failed to get town")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newTownView(town))
+}
+
+func (s *Server) patchTown(w http.ResponseWriter, r *http.Request, id int64) {
+	var patch TownPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	town, err := s.logic.EditTown(r.Context(), id, func(town *model.Town) {
+		if patch.Name != nil {
+			town.Name = *patch.Name
+		}
+		if patch.OwnerName != nil {
+			town.OwnerName = *patch.OwnerName
+		}
+		if patch.Population != nil {
+			town.Population = *patch.Population
+		}
+		if patch.Resources != nil {
+			town.Resources = patch.Resources.toModel()
+		}
+	})
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "town not found")
+		return
+	} else if err != nil {
+		s.logger(r).WithError(err).WithField("townID", id).Error("Failed to edit town")
+		writeError(w, http.StatusInternalServerError, "failed to edit town")
+		return
+	}
+
+	s.logger(r).WithField("townID", id).WithField("patch", patch).Info("Town edited by operator")
+	writeJSON(w, http.StatusOK, newTownView(town))
+}
+
+func (s *Server) handleUpdateResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.logic.UpdateMapResources(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleModeration - returns the newest actions of the chat moderators
+func (s *Server) handleModeration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	tx, err := s.logic.Database().BeginTransaction(r.Context(), true, true)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to begin transaction")
+		writeError(w, http.StatusInternalServerError, "failed to begin transaction")
+		return
+	}
+
+	records, err := tx.GetModerationLog(limit)
+	if err != nil {
+		s.logger(r).WithError(err).Error("Failed to get moderation log")
+		writeError(w, http.StatusInternalServerError, "failed to get moderation log")
+		return
+	}
+
+	result := []ModerationRecordView{}
+	for _, record := range records {
+		result = append(result, newModerationRecordView(record))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DebugTargetPatch - enables or disables debug logging for the target
+type DebugTargetPatch struct {
+	logging.DebugTarget
+	Enabled bool `json:"enabled"`
+}
+
+// handleLogDebug - lists the debug logging targets on GET, enables or disables the target on POST
+func (s *Server) handleLogDebug(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, logging.DebugTargets())
+	case http.MethodPost:
+		var patch DebugTargetPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		if err := logging.SetDebugTarget(patch.DebugTarget, patch.Enabled); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.logger(r).WithField("target", patch.DebugTarget).
+			WithField("enabled", patch.Enabled).
+			Info("Debug logging changed by operator")
+		writeJSON(w, http.StatusOK, logging.DebugTargets())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleLogs - returns recent log lines. If 'follow' parameter is set the new lines
+// are streamed as newline delimited JSON until the client disconnects
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	flusher, canFlush := w.(http.Flusher)
+
+	if !follow || !canFlush {
+		writeJSON(w, http.StatusOK, s.logBuffer.Recent(limit))
+		return
+	}
+
+	subscriber := s.logBuffer.Subscribe()
+	defer s.logBuffer.Unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, line := range s.logBuffer.Recent(limit) {
+		_ = encoder.Encode(line)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-subscriber:
+			if err := encoder.Encode(line); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"abbysoft/gardarike-online/logging"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// LogLine - single log entry kept by the LogBuffer
+type LogLine struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// LogBuffer - logrus hook keeping the most recent log entries in memory
+// and broadcasting new entries to the subscribers
+type LogBuffer struct {
+	mutex       sync.Mutex
+	lines       []LogLine
+	next        int
+	full        bool
+	subscribers map[chan LogLine]struct{}
+}
+
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{
+		lines:       make([]LogLine, size),
+		subscribers: make(map[chan LogLine]struct{}),
+	}
+}
+
+func (b *LogBuffer) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (b *LogBuffer) Fire(entry *log.Entry) error {
+	// Entries filtered out by the module levels aren't shown to the operators either
+	if !logging.Enabled(entry) {
+		return nil
+	}
+
+	line := LogLine{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		Fields:  make(map[string]string, len(entry.Data)),
+	}
+
+	for key, value := range entry.Data {
+		line.Fields[key] = fmt.Sprint(value)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.lines) > 0 {
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % len(b.lines)
+		b.full = b.full || b.next == 0
+	}
+
+	// Slow subscribers lose lines instead of blocking the logger
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- line:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Recent - returns up to 'limit' latest lines from the oldest to the newest
+func (b *LogBuffer) Recent(limit int) []LogLine {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var ordered []LogLine
+	if b.full {
+		ordered = append(ordered, b.lines[b.next:]...)
+	}
+	ordered = append(ordered, b.lines[:b.next]...)
+
+	if limit > 0 && len(ordered) > limit {
+		ordered = ordered[len(ordered)-limit:]
+	}
+
+	return ordered
+}
+
+// Subscribe - returns the channel receiving all new log lines.
+// The channel must be released with Unsubscribe
+func (b *LogBuffer) Subscribe() chan LogLine {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber := make(chan LogLine, 100)
+	b.subscribers[subscriber] = struct{}{}
+
+	return subscriber
+}
+
+func (b *LogBuffer) Unsubscribe(subscriber chan LogLine) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.subscribers, subscriber)
+}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/logic"
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/tracing"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const (
+	logBufferSize = 1000
+)
+
+type Config struct {
+	Endpoint string // Listens for admin HTTP requests on this address (e.g. 127.0.0.1:8502)
+	Token    string // Bearer token required by the admin API
+}
+
+// GameLogic - part of the game logic available to the operators
+type GameLogic interface {
+	Sessions() []logic.SessionInfo
+	LoopStats() logic.GameLoopStats
+	Database() db.Database
+	UpdateMapResources(ctx context.Context)
+	EditCharacter(ctx context.Context, characterID int64, update func(character *model.Character)) (model.Character, error)
+	EditTown(ctx context.Context, townID int64, update func(town *model.Town)) (model.Town, error)
+}
+
+type Server struct {
+	config    Config
+	logic     GameLogic
+	log       *log.Entry
+	logBuffer *LogBuffer
+	mux       *http.ServeMux
+}
+
+func NewServer(config Config, gameLogic GameLogic) (*Server, error) {
+	if len(config.Token) == 0 {
+		return nil, fmt.Errorf("admin API token must be set")
+	}
+
+	logBuffer := NewLogBuffer(logBufferSize)
+	log.AddHook(logBuffer)
+
+	s := &Server{
+		config:    config,
+		logic:     gameLogic,
+		log:       log.WithField("module", "admin"),
+		logBuffer: logBuffer,
+		mux:       http.NewServeMux(),
+	}
+
+	s.handle("/api/status", s.handleStatus)
+	s.handle("/api/sessions", s.handleSessions)
+	s.handle("/api/characters/", s.handleCharacter)
+	s.handle("/api/towns/", s.handleTown)
+	s.handle("/api/resources/update", s.handleUpdateResources)
+	s.handle("/api/moderation", s.handleModeration)
+	s.handle("/api/logs", s.handleLogs)
+	s.handle("/api/logs/debug", s.handleLogDebug)
+
+	return s, nil
+}
+
+// Handle - registers a handler which doesn't require authorization
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// handle - registers a handler available only for the authorized operators
+func (s *Server) handle(pattern string, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, s.authorized(handler))
+}
+
+func (s *Server) authorized(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.config.Token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			s.log.WithField("remote", r.RemoteAddr).
+				WithField("path", r.URL.Path).
+				Warn("Unauthorized admin request")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		ctx := tracing.WithRequestInfo(r.Context(), tracing.RequestInfo{
+			RequestID:   tracing.NewTraceID(),
+			RequestName: "Admin " + r.Method + " " + r.URL.Path,
+		})
+
+		tracing.Logger(ctx, s.log).WithField("remote", r.RemoteAddr).Info("Admin request")
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve - listens for the admin requests, always returns non-nil error
+func (s *Server) Serve() error {
+	s.log.WithField("endpoint", s.config.Endpoint).Info("Admin server started")
+	return http.ListenAndServe(s.config.Endpoint, s)
+}
+
+// pathID - returns the last path element after the prefix, e.g. 10 for /api/towns/10
+func pathID(path, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(path, prefix), "/")
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/logging"
+	"abbysoft/gardarike-online/logic"
+	"abbysoft/gardarike-online/model"
+	"context"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type gameLogicMock struct {
+	sessions        []logic.SessionInfo
+	editedCharacter model.Character
+	resourcesUpdate bool
+}
+
+func (g *gameLogicMock) Sessions() []logic.SessionInfo {
+	return g.sessions
+}
+
+func (g *gameLogicMock) LoopStats() logic.GameLoopStats {
+	return logic.GameLoopStats{}
+}
+
+func (g *gameLogicMock) Database() db.Database {
+	return nil
+}
+
+func (g *gameLogicMock) UpdateMapResources(ctx context.Context) {
+	g.resourcesUpdate = true
+}
+
+func (g *gameLogicMock) EditCharacter(ctx context.Context, characterID int64, update func(character *model.Character)) (model.Character, error) {
+	g.editedCharacter.ID = characterID
+	update(&g.editedCharacter)
+	return g.editedCharacter, nil
+}
+
+func (g *gameLogicMock) EditTown(ctx context.Context, townID int64, update func(town *model.Town)) (model.Town, error) {
+	town := model.Town{ID: townID}
+	update(&town)
+	return town, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *gameLogicMock) {
+	gameLogic := &gameLogicMock{}
+	server, err := NewServer(Config{Token: "secret"}, gameLogic)
+	require.NoError(t, err)
+
+	return server, gameLogic
+}
+
+func doRequest(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestNewServer_TokenRequired(t *testing.T) {
+	_, err := NewServer(Config{Endpoint: "127.0.0.1:0"}, &gameLogicMock{})
+	require.Error(t, err)
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	require.Equal(t, http.StatusUnauthorized, doRequest(server, http.MethodGet, "/api/sessions", "", "").Code)
+	require.Equal(t, http.StatusUnauthorized, doRequest(server, http.MethodGet, "/api/sessions", "wrong", "").Code)
+}
+
+func TestServer_Sessions(t *testing.T) {
+	server, gameLogic := newTestServer(t)
+	gameLogic.sessions = []logic.SessionInfo{
+		{SessionID: "session", AccountID: 1, CharacterID: 2, CharacterName: "test", LastRequestTime: time.Now()},
+	}
+
+	response := doRequest(server, http.MethodGet, "/api/sessions", "secret", "")
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var sessions []SessionView
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &sessions))
+	require.Len(t, sessions, 1)
+	require.Equal(t, "session", sessions[0].SessionID)
+	require.Equal(t, "test", sessions[0].CharacterName)
+}
+
+func TestServer_EditCharacter(t *testing.T) {
+	server, gameLogic := newTestServer(t)
+	gameLogic.editedCharacter = model.Character{
+		TaxRate: 10,
+		Towns: []model.Town{
+			{ID: 1, Population: 5, Resources: model.Resources{Wood: 40}},
+			{ID: 2, Population: 2, Resources: model.Resources{Wood: 60}},
+		},
+	}
+
+	response := doRequest(server, http.MethodPatch, "/api/characters/3", "secret", `{"taxRate": 20, "moderator": true, "admin": true}`)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var character CharacterView
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &character))
+	require.Equal(t, int64(3), character.ID)
+	require.Equal(t, uint64(20), character.TaxRate)
+	require.True(t, character.Moderator)
+	require.True(t, character.Admin)
+	require.Equal(t, uint64(7), character.CurrentPopulation)
+	require.Equal(t, uint64(100), character.Resources.Wood)
+	require.Len(t, character.Towns, 2)
+
+	response = doRequest(server, http.MethodPatch, "/api/characters/abc", "secret", `{}`)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestServer_EditTown(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	response := doRequest(server, http.MethodPatch, "/api/towns/5", "secret",
+		`{"population": 12, "resources": {"food": 300}}`)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var town TownView
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &town))
+	require.Equal(t, int64(5), town.ID)
+	require.Equal(t, uint64(12), town.Population)
+	require.Equal(t, uint64(300), town.Resources.Food)
+	require.Equal(t, model.TownStorageLimit.Food, town.StorageLimit.Food)
+}
+
+func TestServer_UpdateResources(t *testing.T) {
+	server, gameLogic := newTestServer(t)
+
+	require.Equal(t, http.StatusMethodNotAllowed,
+		doRequest(server, http.MethodGet, "/api/resources/update", "secret", "").Code)
+	require.Equal(t, http.StatusNoContent,
+		doRequest(server, http.MethodPost, "/api/resources/update", "secret", "").Code)
+	require.True(t, gameLogic.resourcesUpdate)
+}
+
+func TestServer_LogDebug(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	response := doRequest(server, http.MethodPost, "/api/logs/debug", "secret",
+		`{"field": "sessionID", "value": "session", "enabled": true}`)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var targets []logging.DebugTarget
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &targets))
+	require.Equal(t, []logging.DebugTarget{{Field: "sessionID", Value: "session"}}, targets)
+
+	response = doRequest(server, http.MethodPost, "/api/logs/debug", "secret",
+		`{"field": "sessionID", "value": "session", "enabled": false}`)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Empty(t, logging.DebugTargets())
+
+	response = doRequest(server, http.MethodPost, "/api/logs/debug", "secret",
+		`{"field": "password", "value": "secret", "enabled": true}`)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestLogBuffer_Recent(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	for _, message := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, buffer.Fire(&log.Entry{Message: message, Data: log.Fields{"module": "test"}}))
+	}
+
+	lines := buffer.Recent(0)
+	require.Len(t, lines, 3)
+	require.Equal(t, "2", lines[0].Message)
+	require.Equal(t, "4", lines[2].Message)
+	require.Equal(t, "test", lines[2].Fields["module"])
+
+	lines = buffer.Recent(1)
+	require.Len(t, lines, 1)
+	require.Equal(t, "4", lines[0].Message)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"abbysoft/gardarike-online/admin"
+	"abbysoft/gardarike-online/logging"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const adminUsage = `Usage: gardarike admin [-endpoint address] [-token token] <command> [arguments]
+
+Commands:
+  status                              show server state
+  sessions                            list online sessions
+  character <id>                      show character
+  edit-character <id> <key=value>...  change character (taxRate, moderator, admin)
+  town <id>                           show town
+  edit-town <id> <key=value>...       change town (name, owner, population, wood, food, stone, leather, gold)
+  update-resources                    run map resources update
+  moderation-log [-n count]           show recent actions of the chat moderators
+  logs [-f] [-n count]                show recent server logs
+`
+
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+// parseAssignments - parses key=value arguments
+func parseAssignments(args []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("invalid argument %q, key=value expected", arg)
+		}
+		result[parts[0]] = parts[1]
+	}
+
+	return result, nil
+}
+
+func parseID(args []string) (int64, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("id argument is required")
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", args[0])
+	}
+
+	return id, nil
+}
+
+func editCharacter(client *admin.Client, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	assignments, err := parseAssignments(args[1:])
+	if err != nil {
+		return err
+	}
+
+	var patch admin.CharacterPatch
+	for key, value := range assignments {
+		switch key {
+		case "taxRate":
+			number, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %q", key, value)
+			}
+			patch.TaxRate = &number
+		case "moderator", "admin":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %q", key, value)
+			}
+
+			if key == "moderator" {
+				patch.Moderator = &enabled
+			} else {
+				patch.Admin = &enabled
+			}
+		default:
+			return fmt.Errorf("unknown character field %q", key)
+		}
+	}
+
+	result, err := client.EditCharacter(id, patch)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(result)
+}
+
+func editTown(client *admin.Client, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	assignments, err := parseAssignments(args[1:])
+	if err != nil {
+		return err
+	}
+
+	town, err := client.Town(id)
+	if err != nil {
+		return err
+	}
+
+	patch := admin.TownPatch{Resources: &town.Resources}
+	for key, value := range assignments {
+		value := value
+
+		switch key {
+		case "name":
+			patch.Name = &value
+		case "owner":
+			patch.OwnerName = &value
+		default:
+			number, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %q", key, value)
+			}
+
+			switch key {
+			case "population":
+				patch.Population = &number
+			case "wood":
+				patch.Resources.Wood = number
+			case "food":
+				patch.Resources.Food = number
+			case "stone":
+				patch.Resources.Stone = number
+			case "leather":
+				patch.Resources.Leather = number
+			case "gold":
+				patch.Resources.Gold = number
+			default:
+				return fmt.Errorf("unknown town field %q", key)
+			}
+		}
+	}
+
+	result, err := client.EditTown(id, patch)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(result)
+}
+
+func showModerationLog(client *admin.Client, args []string) error {
+	flags := flag.NewFlagSet("moderation-log", flag.ContinueOnError)
+	count := flags.Int("n", 100, "number of recent actions to show")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := client.ModerationLog(*count)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(records)
+}
+
+func showLogs(client *admin.Client, args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := flags.Bool("f", false, "follow new log lines")
+	count := flags.Int("n", 100, "number of recent lines to show")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	printLine := func(line admin.LogLine) {
+		var fields []string
+		for key, value := range line.Fields {
+			fields = append(fields, fmt.Sprintf("%s=%s", key, value))
+		}
+
+		fmt.Printf("%s [%s] %s %s\n",
+			line.Time.Format("2006-01-02 15:04:05"), line.Level, line.Message, strings.Join(fields, " "))
+	}
+
+	if *follow {
+		return client.FollowLogs(*count, printLine)
+	}
+
+	lines, err := client.Logs(*count)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		printLine(line)
+	}
+
+	return nil
+}
+
+func toggleDebug(client *admin.Client, args []string) error {
+	if len(args) == 0 {
+		targets, err := client.DebugTargets()
+		if err != nil {
+			return err
+		}
+		return printJSON(targets)
+	}
+
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return fmt.Errorf("usage: debug <field>=<value> on|off")
+	}
+
+	assignments, err := parseAssignments(args[:1])
+	if err != nil {
+		return err
+	}
+
+	var target logging.DebugTarget
+	for field, value := range assignments {
+		target = logging.DebugTarget{Field: field, Value: value}
+	}
+
+	targets, err := client.SetDebugTarget(target, args[1] == "on")
+	if err != nil {
+		return err
+	}
+
+	return printJSON(targets)
+}
+
+func runAdminCommand(args []string) error {
+	// Config is optional here, the endpoint and the token could be passed with flags
+	_ = setupConfig()
+
+	flags := flag.NewFlagSet("admin", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, adminUsage) }
+
+	endpoint := flags.String("endpoint", viper.GetString("admin.Endpoint"), "admin API address")
+	token := flags.String("token", viper.GetString("admin.Token"), "admin API token")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("command is required")
+	}
+
+	if len(*endpoint) == 0 {
+		return fmt.Errorf("admin endpoint isn't set, use -endpoint flag or [admin] config section")
+	}
+
+	client := admin.NewClient(*endpoint, *token)
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+
+	switch command {
+	case "status":
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		return printJSON(status)
+	case "sessions":
+		sessions, err := client.Sessions()
+		if err != nil {
+			return err
+		}
+		return printJSON(sessions)
+	case "character":
+		id, err := parseID(commandArgs)
+		if err != nil {
+			return err
+		}
+		character, err := client.Character(id)
+		if err != nil {
+			return err
+		}
+		return printJSON(character)
+	case "edit-character":
+		return editCharacter(client, commandArgs)
+	case "town":
+		id, err := parseID(commandArgs)
+		if err != nil {
+			return err
+		}
+		town, err := client.Town(id)
+		if err != nil {
+			return err
+		}
+		return printJSON(town)
+	case "edit-town":
+		return editTown(client, commandArgs)
+	case "update-resources":
+		return client.UpdateResources()
+	case "moderation-log":
+		return showModerationLog(client, commandArgs)
+	case "logs":
+		return showLogs(client, commandArgs)
+	case "debug":
+		return toggleDebug(client, commandArgs)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
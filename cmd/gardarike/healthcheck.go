@@ -0,0 +1,42 @@
+package main
+
+import (
+	"abbysoft/gardarike-online/health"
+	"flag"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"time"
+)
+
+// runHealthcheck - probes the running server, returns an error if it isn't healthy.
+// Suitable for the container HEALTHCHECK, the exit code is non-zero on errors
+func runHealthcheck(args []string) error {
+	// Config is optional here, the endpoint could be passed with the flag
+	_ = setupConfig()
+
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	endpoint := flags.String("endpoint", viper.GetString("admin.Endpoint"), "admin API address")
+	live := flags.Bool("live", false, "check liveness only (readiness is checked by default)")
+	timeout := flags.Duration("timeout", 5*time.Second, "probe timeout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(*endpoint) == 0 {
+		return fmt.Errorf("admin endpoint isn't set, use -endpoint flag or [admin] config section")
+	}
+
+	path := health.ReadinessPath
+	if *live {
+		path = health.LivenessPath
+	}
+
+	report, err := health.Probe(*endpoint, path, *timeout)
+	for _, check := range report.Checks {
+		fmt.Fprintf(os.Stderr, "%s: %s %s\n", check.Name, check.Status, check.Error)
+	}
+
+	return err
+}
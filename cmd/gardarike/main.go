@@ -1,11 +1,14 @@
 package main
 
 import (
+	"abbysoft/gardarike-online/admin"
 	"abbysoft/gardarike-online/db/postgres"
 	"abbysoft/gardarike-online/generation"
+	"abbysoft/gardarike-online/logging"
 	"abbysoft/gardarike-online/logic"
 	"abbysoft/gardarike-online/model/consts"
 	"abbysoft/gardarike-online/server"
+	"abbysoft/gardarike-online/tracing"
 	"flag"
 	"fmt"
 	log "github.com/sirupsen/logrus"
@@ -32,6 +35,7 @@ func setupDefaults() {
 	viper.SetDefault("logic.ChunkSize", consts.DefaultMapChunkSize)
 	viper.SetDefault("logic.AlwaysRegenerateMap", consts.DefaultAlwaysRegenerateMap)
 	viper.SetDefault("logic.DebugTerrain", consts.DefaultDebugTerrain)
+	viper.SetDefault("logic.ConstructionSlots", consts.DefaultConstructionSlots)
 }
 
 func setupConfig() error {
@@ -105,15 +109,92 @@ func parseLogicConfig(config *viper.Viper) (result logic.Config, err error) {
 
 	config.SetDefault("AFKTimeout", time.Minute*10)
 	config.SetDefault("ChatMessageMaxLength", 200)
+	config.SetDefault("ChatHistoryMaxCount", 50)
 
 	if err := config.Unmarshal(&result); err != nil {
 		return result, fmt.Errorf("failed to parse [logic] config section: %w", err)
 	}
 
+	if result.ChatHistoryMaxCount < 1 {
+		return result, fmt.Errorf("ChatHistoryMaxCount should be at least 1")
+	}
+
+	if result.ConstructionSlots < 1 {
+		return result, fmt.Errorf("ConstructionSlots should be at least 1")
+	}
+
+	return
+}
+
+// parseAdminConfig - admin section is optional, the admin API is disabled without it
+func parseAdminConfig(config *viper.Viper) (result admin.Config, err error) {
+	if config == nil {
+		return result, nil
+	}
+
+	if err := config.Unmarshal(&result); err != nil {
+		return result, fmt.Errorf("failed to parse [admin] config section: %w", err)
+	}
+
+	if len(result.Endpoint) != 0 && len(result.Token) == 0 {
+		return result, fmt.Errorf("you should set Token variable to enable admin API")
+	}
+
+	return
+}
+
+// parseTracingConfig - tracing section is optional, spans aren't exported without it
+func parseTracingConfig(config *viper.Viper) (result tracing.Config, err error) {
+	if config == nil {
+		return result, nil
+	}
+
+	if err := config.Unmarshal(&result); err != nil {
+		return result, fmt.Errorf("failed to parse [tracing] config section: %w", err)
+	}
+
+	return
+}
+
+// parseLogConfig - log section is optional, the info level is used for all modules without it
+func parseLogConfig(config *viper.Viper) (result logging.Config, err error) {
+	if config == nil {
+		config = viper.New()
+	}
+
+	config.SetDefault("Level", "info")
+	config.SetDefault("Format", logging.FormatText)
+	config.SetDefault("MaxSize", 100)
+	config.SetDefault("MaxAge", 30)
+	config.SetDefault("Sampling.Messages", []string{"Sending *rpc.Response_"})
+	config.SetDefault("Sampling.Interval", time.Second)
+	config.SetDefault("Sampling.First", 10)
+	config.SetDefault("Sampling.Thereafter", 100)
+
+	if err := config.Unmarshal(&result); err != nil {
+		return result, fmt.Errorf("failed to parse [log] config section: %w", err)
+	}
+
 	return
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdminCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := runHealthcheck(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	setupFlags()
 
 	if flagVersion {
@@ -128,6 +209,15 @@ func main() {
 		log.WithError(err).Fatal("Failed to init configuration")
 	}
 
+	logConfig, err := parseLogConfig(viper.Sub("log"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to parse log config")
+	}
+
+	if err := logging.Setup(logConfig); err != nil {
+		log.WithError(err).Fatal("Failed to setup logging")
+	}
+
 	serverConfig, err := parseServerConfig(viper.Sub("server"))
 	if err != nil {
 		log.WithError(err).Fatal("Failed to parse server config")
@@ -148,10 +238,29 @@ func main() {
 		log.WithError(err).Fatal("Failed to parse logic config")
 	}
 
-	s, err := server.NewServer(serverConfig, logicConfig, dbConfig, generatorConfig)
+	adminConfig, err := parseAdminConfig(viper.Sub("admin"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to parse admin config")
+	}
+
+	tracingConfig, err := parseTracingConfig(viper.Sub("tracing"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to parse tracing config")
+	}
+
+	shutdownTracing, err := tracing.Setup(tracingConfig, func(err error) {
+		log.WithField("module", "tracing").WithError(err).Warn("Failed to export spans")
+	})
+	if err != nil {
+		log.WithError(err).Fatal("Failed to setup tracing")
+	}
+
+	s, err := server.NewServer(serverConfig, logicConfig, adminConfig, dbConfig, generatorConfig)
 	if err != nil {
 		log.WithError(err).Fatalf("Failed to start server")
 	}
 
-	log.Fatal(s.Serve())
+	err = s.Serve()
+	shutdownTracing()
+	log.Fatal(err)
 }
@@ -3,17 +3,24 @@ package db
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"time"
 )
 
 type CharacterDatabaseTransaction interface {
 	GetCharacter(id int64) (model.Character, error)
+	GetCharacterID(name string) (int64, error)
 	AddCharacter(name string) (id int, err error)
 	AddAccountCharacter(characterID, accountID int) error
 	DeleteCharacter(id int64) error
 	GetCharacters(accountID int64) ([]model.Character, error)
 	UpdateCharacter(character model.Character) error
-	GetResources(characterID int64) (model.Resources, error)
-	GetProductionRates(characterID int64) (model.Resources, error)
+	GetWorkDistribution(characterID int64) (model.WorkDistribution, error)
+	UpdateWorkDistribution(characterID int64, distribution model.WorkDistribution) error
+	GetCharacterBuildings(characterID int64) (model.CharacterBuildings, error)
+	AddTechnology(characterID int64, id rpc.TechnologyType) error
+	SetResearch(characterID int64, research model.Research) error
+	DeleteResearch(characterID int64) error
 	GetEmpiresByCriteria(
 		characterName string, offset, limit uint32, criteria rpc.EmpiresRatingCriteria) ([]*rpc.RatingEntry, *rpc.RatingEntry, error)
 }
@@ -24,27 +31,107 @@ type AccountDatabaseTransaction interface {
 }
 
 type WorldDatabaseTransaction interface {
-	AddChatMessage(message model.ChatMessage) (int64, error)
-	GetChatMessages(offset int, count int) ([]model.ChatMessage, error)
 	GetMapChunk(x, y, number int64) (model.WorldMapChunk, error)
 	GetChunkRange() (model.ChunkRange, error)
+	GetChunksCount() (model.ChunksCount, error)
 	IncrementMapResources(resources model.ChunkResources, limit model.ChunkResources) error
+	TakeChunkResources(x, y int64, requested model.ChunkResources) (model.ChunkResources, error)
 	SaveMapChunkOrUpdate(chunk model.WorldMapChunk) error
+	GetTown(townID int64) (model.Town, error)
 	GetTowns(ownerName string) ([]model.Town, error)
 	GetAllTowns() ([]model.Town, error)
 	GetTownsForRect(xStart, xEnd, yStart, yEnd int) ([]model.Town, error)
-	AddOrUpdateResources(resources model.Resources) error
-	AddOrUpdateProductionRates(rates model.Resources) error
-	AddTown(town model.Town) error
-	AddTownBuilding(townID int64, building model.Building) error
+	AddTown(town model.Town) (int64, error)
+	AddTownBuilding(townID int64, building model.Building) (int64, error)
+	UpdateTownBuilding(building model.Building) error
+	DeleteTownBuilding(id int64) error
+	AddConstruction(construction model.Construction) (int64, error)
+	UpdateConstruction(construction model.Construction) error
+	DeleteConstruction(id int64) error
 	GetAllBuildings() (map[int64]model.CharacterBuildings, error)
 	RenameTown(townID int64, newName string) error
+	UpdateTown(town model.Town) error
+	SetTownOwner(townID int64, ownerName string) error
+	GetLostTowns(ownerName string, townIDs []int64) ([]int64, error)
+	AddDelivery(delivery model.Delivery) error
+	TakeDeliveries(townIDs []int64) ([]model.Delivery, error)
+	MoveTownGoods(fromTownID, toTownID int64) error
+	DeleteTownGoods(townID int64) error
+	AddCaravan(caravan model.Caravan) (int64, error)
+	UpdateCaravan(caravan model.Caravan) error
+	DeleteCaravan(id int64) (int64, error)
+	GetCaravansForRect(xStart, xEnd, yStart, yEnd int) ([]model.Caravan, error)
+}
+
+type MarketDatabaseTransaction interface {
+	AddMarketOrder(order model.MarketOrder) (int64, error)
+	GetMarketOrder(id int64) (model.MarketOrder, error)
+	GetMatchingOrders(order model.MarketOrder, limit int) ([]model.MarketOrder, error)
+	GetMarketOrders(resource rpc.ResourceType, side rpc.OrderSide, offset, limit int) ([]model.MarketOrder, error)
+	GetCharacterMarketOrders(characterID int64) ([]model.MarketOrder, error)
+	UpdateMarketOrder(order model.MarketOrder) error
+	DeleteMarketOrder(id int64) error
+	AddTrade(trade model.Trade) (int64, error)
+	GetTrades(resource rpc.ResourceType, offset, limit int) ([]model.Trade, error)
+	GetCharacterTrades(characterID int64, offset, limit int) ([]model.Trade, error)
+}
+
+type MilitaryDatabaseTransaction interface {
+	AddTraining(training model.UnitTraining) (int64, error)
+	UpdateTraining(training model.UnitTraining) error
+	DeleteTraining(id int64) error
+	GetGarrisons(townIDs []int64) (map[int64]model.Units, error)
+	AddGarrison(townID int64, units model.Units) error
+	TakeGarrison(townID int64, units model.Units) (bool, error)
+	AddArmy(army model.Army) (int64, error)
+	GetArmy(id int64) (model.Army, error)
+	GetCharacterArmies(characterID int64) ([]model.Army, error)
+	GetActiveArmies(characterID int64) ([]model.Army, error)
+	GetArmiesNear(location model.Vector2D, radius float64, excludedCharacterID int64) ([]model.Army, error)
+	UpdateArmy(army model.Army) error
+	DeleteArmy(id int64) error
+}
+
+type ChatDatabaseTransaction interface {
+	AddChatMessage(message model.ChatMessage) (int64, error)
+	GetChatMessages(filter model.ChatFilter, count int) ([]model.ChatMessage, error)
+	JoinChatChannel(membership model.ChatMembership) error
+	LeaveChatChannel(characterID int64, channel rpc.ChatMessage_Channel) error
+	GetChatChannels(characterID int64) ([]model.ChatMembership, error)
+	GetChatMessage(id int64) (model.ChatMessage, error)
+	DeleteChatMessages(before time.Time) (int64, error)
+}
+
+type ModerationDatabaseTransaction interface {
+	GetSanctions(characterID int64) (model.Sanctions, error)
+	SetMutedUntil(characterID int64, until time.Time) error
+	SetBannedUntil(characterID int64, until time.Time) error
+	AddChatReport(report model.ChatReport) error
+	GetChatReports(count int) ([]model.ChatReport, error)
+	AddModerationRecord(record model.ModerationRecord) error
+	GetModerationLog(count int) ([]model.ModerationRecord, error)
+}
+
+type DiplomacyDatabaseTransaction interface {
+	GetDiplomaticState(characterID, otherID int64) (rpc.DiplomaticState, error)
+	GetDiplomaticRelations(characterID int64) ([]model.DiplomaticRelation, error)
+	SetDiplomaticState(characterID, otherID int64, state rpc.DiplomaticState) error
+	AddDiplomacyProposal(proposal model.DiplomacyProposal) (int64, error)
+	GetDiplomacyProposal(id int64) (model.DiplomacyProposal, error)
+	GetDiplomacyProposals(characterID int64) ([]model.DiplomacyProposal, error)
+	DeleteDiplomacyProposal(id int64) error
+	DeleteDiplomacyProposals(characterID, otherID int64) error
 }
 
 type DatabaseTransaction interface {
 	CharacterDatabaseTransaction
 	AccountDatabaseTransaction
 	WorldDatabaseTransaction
+	MarketDatabaseTransaction
+	MilitaryDatabaseTransaction
+	DiplomacyDatabaseTransaction
+	ChatDatabaseTransaction
+	ModerationDatabaseTransaction
 
 	EndTransaction() error
 	IsCompleted() bool
@@ -55,5 +142,6 @@ type DatabaseTransaction interface {
 }
 
 type Database interface {
-	BeginTransaction(autoCommit bool, autoRollBack bool) (DatabaseTransaction, error)
+	BeginTransaction(ctx context.Context, autoCommit bool, autoRollBack bool) (DatabaseTransaction, error)
+	Ping(ctx context.Context) error
 }
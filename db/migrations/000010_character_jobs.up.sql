@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS character_jobs
+(
+    character_id int NOT NULL,
+    job_id       int NOT NULL,
+    workers      int NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (character_id, job_id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE characters
+DROP COLUMN IF EXISTS happiness,
+DROP COLUMN IF EXISTS tax_rate,
+DROP COLUMN IF EXISTS starving_ticks;
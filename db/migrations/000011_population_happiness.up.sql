@@ -0,0 +1,4 @@
+ALTER TABLE characters
+ADD COLUMN happiness int NOT NULL DEFAULT 50,
+ADD COLUMN tax_rate int NOT NULL DEFAULT 10,
+ADD COLUMN starving_ticks int NOT NULL DEFAULT 0;
@@ -0,0 +1,34 @@
+ALTER TABLE characters
+ADD COLUMN IF NOT EXISTS max_population int NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS current_population int NOT NULL DEFAULT 0;
+
+UPDATE characters c SET current_population = COALESCE(
+    (SELECT SUM(population) FROM towns WHERE owner_name = c.name), 0);
+
+CREATE TABLE IF NOT EXISTS resources (
+    character_id int PRIMARY KEY,
+    wood int NOT NULL DEFAULT 30,
+    stone int NOT NULL DEFAULT 30,
+    food int NOT NULL DEFAULT 50,
+    leather int NOT NULL DEFAULT 10
+);
+
+CREATE TABLE IF NOT EXISTS production_rates
+(
+    character_id int PRIMARY KEY,
+    wood         int NOT NULL DEFAULT 0,
+    stone        int NOT NULL DEFAULT 0,
+    food         int NOT NULL DEFAULT 0,
+    leather      int NOT NULL DEFAULT 0
+);
+
+INSERT INTO resources
+SELECT c.id, COALESCE(SUM(r.wood), 0), COALESCE(SUM(r.stone), 0), COALESCE(SUM(r.food), 0), COALESCE(SUM(r.leather), 0)
+FROM characters c
+LEFT JOIN towns t ON t.owner_name = c.name
+LEFT JOIN town_resources r ON r.town_id = t.id
+GROUP BY c.id;
+
+INSERT INTO production_rates (character_id) SELECT id FROM characters;
+
+DROP TABLE IF EXISTS town_resources;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS town_resources
+(
+    town_id int PRIMARY KEY,
+    wood    int NOT NULL DEFAULT 0,
+    stone   int NOT NULL DEFAULT 0,
+    food    int NOT NULL DEFAULT 0,
+    leather int NOT NULL DEFAULT 0
+);
+
+-- Resources and population of the empire are moved to its capital (the first town)
+WITH capitals AS (
+    SELECT DISTINCT ON (owner_name) id, owner_name FROM towns ORDER BY owner_name, id
+)
+INSERT INTO town_resources
+SELECT capitals.id, r.wood, r.stone, r.food, r.leather FROM capitals
+JOIN characters c ON c.name = capitals.owner_name
+JOIN resources r ON r.character_id = c.id;
+
+INSERT INTO town_resources (town_id)
+SELECT id FROM towns
+ON CONFLICT (town_id) DO NOTHING;
+
+UPDATE towns t SET population = c.current_population
+FROM characters c
+WHERE c.name = t.owner_name AND t.id = (SELECT MIN(id) FROM towns WHERE owner_name = c.name);
+
+DROP TABLE IF EXISTS resources;
+DROP TABLE IF EXISTS production_rates;
+
+ALTER TABLE characters
+DROP COLUMN IF EXISTS max_population,
+DROP COLUMN IF EXISTS current_population;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS construction_queue
+(
+    id          serial PRIMARY KEY,
+    town_id     int    NOT NULL,
+    building_id int    NOT NULL,
+    location_x  int    NOT NULL,
+    location_y  int    NOT NULL,
+    rotation    float4 NOT NULL DEFAULT 0,
+    ticks_left  int    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS construction_queue_town_id_idx ON construction_queue (town_id);
@@ -0,0 +1,9 @@
+DELETE FROM construction_queue WHERE town_building_id <> 0;
+
+ALTER TABLE construction_queue
+DROP COLUMN IF EXISTS town_building_id,
+DROP COLUMN IF EXISTS level;
+
+ALTER TABLE town_buildings
+DROP COLUMN IF EXISTS id,
+DROP COLUMN IF EXISTS level;
@@ -0,0 +1,8 @@
+ALTER TABLE town_buildings
+ADD COLUMN IF NOT EXISTS id serial PRIMARY KEY,
+ADD COLUMN IF NOT EXISTS level int NOT NULL DEFAULT 1;
+
+-- Upgrades of the placed buildings share the queue with the new buildings
+ALTER TABLE construction_queue
+ADD COLUMN IF NOT EXISTS town_building_id int NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS level int NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS character_research;
+DROP TABLE IF EXISTS character_technologies;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS character_technologies
+(
+    character_id  int NOT NULL,
+    technology_id int NOT NULL,
+
+    PRIMARY KEY (character_id, technology_id)
+);
+
+CREATE TABLE IF NOT EXISTS character_research
+(
+    character_id  int PRIMARY KEY,
+    technology_id int NOT NULL,
+    ticks_left    int NOT NULL
+);
@@ -0,0 +1,5 @@
+ALTER TABLE towns
+DROP COLUMN IF EXISTS debt;
+
+ALTER TABLE town_resources
+DROP COLUMN IF EXISTS gold;
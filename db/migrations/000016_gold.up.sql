@@ -0,0 +1,5 @@
+ALTER TABLE town_resources
+ADD COLUMN gold bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE towns
+ADD COLUMN debt bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS market_deliveries;
+DROP TABLE IF EXISTS market_trades;
+DROP TABLE IF EXISTS market_orders;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS market_orders
+(
+    id           serial PRIMARY KEY,
+    character_id int    NOT NULL,
+    town_id      int    NOT NULL,
+    resource     int    NOT NULL,
+    side         int    NOT NULL,
+    price        bigint NOT NULL,
+    amount       bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS market_orders_book_idx ON market_orders (resource, side, price, id);
+CREATE INDEX IF NOT EXISTS market_orders_character_id_idx ON market_orders (character_id);
+
+CREATE TABLE IF NOT EXISTS market_trades
+(
+    id            serial    PRIMARY KEY,
+    resource      int       NOT NULL,
+    price         bigint    NOT NULL,
+    amount        bigint    NOT NULL,
+    buy_order_id  int       NOT NULL,
+    sell_order_id int       NOT NULL,
+    buyer_id      int       NOT NULL,
+    seller_id     int       NOT NULL,
+    created_at    timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS market_trades_resource_idx ON market_trades (resource, id);
+CREATE INDEX IF NOT EXISTS market_trades_buyer_id_idx ON market_trades (buyer_id);
+CREATE INDEX IF NOT EXISTS market_trades_seller_id_idx ON market_trades (seller_id);
+
+-- Goods of the filled orders waiting for the game loop of the owner
+CREATE TABLE IF NOT EXISTS market_deliveries
+(
+    id      serial PRIMARY KEY,
+    town_id int    NOT NULL,
+    wood    int    NOT NULL DEFAULT 0,
+    stone   int    NOT NULL DEFAULT 0,
+    food    int    NOT NULL DEFAULT 0,
+    leather int    NOT NULL DEFAULT 0,
+    gold    bigint NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS market_deliveries_town_id_idx ON market_deliveries (town_id);
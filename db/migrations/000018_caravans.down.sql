@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS caravans;
+
+ALTER INDEX town_deliveries_town_id_idx RENAME TO market_deliveries_town_id_idx;
+ALTER TABLE town_deliveries RENAME TO market_deliveries;
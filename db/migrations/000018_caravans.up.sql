@@ -0,0 +1,24 @@
+ALTER TABLE market_deliveries RENAME TO town_deliveries;
+ALTER INDEX market_deliveries_town_id_idx RENAME TO town_deliveries_town_id_idx;
+
+CREATE TABLE IF NOT EXISTS caravans
+(
+    id              serial PRIMARY KEY,
+    character_id    int    NOT NULL,
+    to_character_id int    NOT NULL,
+    from_town_id    int    NOT NULL,
+    to_town_id      int    NOT NULL,
+    from_x          float4 NOT NULL,
+    from_y          float4 NOT NULL,
+    to_x            float4 NOT NULL,
+    to_y            float4 NOT NULL,
+    wood            int    NOT NULL DEFAULT 0,
+    stone           int    NOT NULL DEFAULT 0,
+    food            int    NOT NULL DEFAULT 0,
+    leather         int    NOT NULL DEFAULT 0,
+    gold            bigint NOT NULL DEFAULT 0,
+    travel_time     int    NOT NULL,
+    ticks_left      int    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS caravans_character_id_idx ON caravans (character_id);
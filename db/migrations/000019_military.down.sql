@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS armies;
+DROP TABLE IF EXISTS town_garrisons;
+DROP TABLE IF EXISTS unit_training;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS unit_training
+(
+    id         serial PRIMARY KEY,
+    town_id    int    NOT NULL,
+    unit_type  int    NOT NULL,
+    count      bigint NOT NULL,
+    ticks_left bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS unit_training_town_id_idx ON unit_training (town_id);
+
+CREATE TABLE IF NOT EXISTS town_garrisons
+(
+    town_id  int PRIMARY KEY,
+    spearmen bigint NOT NULL DEFAULT 0,
+    archers  bigint NOT NULL DEFAULT 0,
+    cavalry  bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS armies
+(
+    id            serial  PRIMARY KEY,
+    character_id  int     NOT NULL,
+    town_id       int     NOT NULL,
+    spearmen      bigint  NOT NULL DEFAULT 0,
+    archers       bigint  NOT NULL DEFAULT 0,
+    cavalry       bigint  NOT NULL DEFAULT 0,
+    x             float4  NOT NULL,
+    y             float4  NOT NULL,
+    destination_x float4  NOT NULL DEFAULT 0,
+    destination_y float4  NOT NULL DEFAULT 0,
+    is_moving     boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS armies_character_id_idx ON armies (character_id);
+CREATE INDEX IF NOT EXISTS armies_location_idx ON armies (x, y);
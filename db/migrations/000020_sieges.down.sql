@@ -0,0 +1,9 @@
+ALTER TABLE towns
+    DROP COLUMN IF EXISTS captured_at;
+
+ALTER TABLE characters
+    DROP COLUMN IF EXISTS protected_until;
+
+ALTER TABLE armies
+    DROP COLUMN IF EXISTS siege_ticks,
+    DROP COLUMN IF EXISTS target_town_id;
@@ -0,0 +1,11 @@
+ALTER TABLE armies
+    ADD COLUMN IF NOT EXISTS target_town_id int    NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS siege_ticks    bigint NOT NULL DEFAULT 0;
+
+-- The towns founded by the owner have no capture time and go before the captured ones
+ALTER TABLE towns
+    ADD COLUMN IF NOT EXISTS captured_at timestamp;
+
+-- The existing characters are not protected
+ALTER TABLE characters
+    ADD COLUMN IF NOT EXISTS protected_until timestamp NOT NULL DEFAULT now();
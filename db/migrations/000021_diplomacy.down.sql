@@ -0,0 +1,8 @@
+ALTER TABLE chat_messages
+    DROP COLUMN IF EXISTS channel;
+
+ALTER TABLE market_trades
+    DROP COLUMN IF EXISTS fee;
+
+DROP TABLE IF EXISTS diplomacy_proposals;
+DROP TABLE IF EXISTS diplomatic_relations;
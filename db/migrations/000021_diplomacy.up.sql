@@ -0,0 +1,26 @@
+-- Both directions of the relations are stored, the neutral relations aren't stored
+CREATE TABLE IF NOT EXISTS diplomatic_relations
+(
+    character_id int NOT NULL,
+    other_id     int NOT NULL,
+    state        int NOT NULL,
+    PRIMARY KEY (character_id, other_id)
+);
+
+CREATE TABLE IF NOT EXISTS diplomacy_proposals
+(
+    id         serial    PRIMARY KEY,
+    from_id    int       NOT NULL,
+    to_id      int       NOT NULL,
+    state      int       NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    UNIQUE (from_id, to_id)
+);
+
+CREATE INDEX IF NOT EXISTS diplomacy_proposals_to_id_idx ON diplomacy_proposals (to_id);
+
+ALTER TABLE market_trades
+    ADD COLUMN IF NOT EXISTS fee bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS channel int NOT NULL DEFAULT 0;
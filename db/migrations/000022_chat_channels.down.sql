@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS chat_channel_members;
+
+DROP INDEX IF EXISTS chat_messages_audience_idx;
+DROP INDEX IF EXISTS chat_messages_channel_idx;
+
+ALTER TABLE chat_messages
+    DROP COLUMN IF EXISTS audience,
+    DROP COLUMN IF EXISTS region,
+    DROP COLUMN IF EXISTS recipient_name;
@@ -0,0 +1,24 @@
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS recipient_name varchar(25) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS region         varchar(25) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS audience       bigint[];
+
+CREATE INDEX IF NOT EXISTS chat_messages_channel_idx ON chat_messages (channel, region, message_id);
+
+-- IDs of the characters the alliance message was delivered to
+CREATE INDEX IF NOT EXISTS chat_messages_audience_idx ON chat_messages USING GIN (audience);
+
+CREATE TABLE IF NOT EXISTS chat_channel_members
+(
+    character_id int         NOT NULL,
+    channel      int         NOT NULL,
+    region       varchar(25) NOT NULL DEFAULT '',
+    PRIMARY KEY (character_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS chat_channel_members_channel_idx ON chat_channel_members (channel, region);
+
+-- The existing characters stay in the global chat
+INSERT INTO chat_channel_members (character_id, channel)
+SELECT id, 0 FROM characters
+ON CONFLICT DO NOTHING;
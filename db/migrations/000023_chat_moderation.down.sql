@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS moderation_log;
+
+DROP TABLE IF EXISTS chat_reports;
+
+ALTER TABLE characters
+    DROP COLUMN IF EXISTS banned_until,
+    DROP COLUMN IF EXISTS muted_until,
+    DROP COLUMN IF EXISTS is_moderator;
@@ -0,0 +1,29 @@
+-- The existing characters are not muted or banned
+ALTER TABLE characters
+    ADD COLUMN IF NOT EXISTS is_moderator boolean   NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS muted_until  timestamp NOT NULL DEFAULT 'epoch',
+    ADD COLUMN IF NOT EXISTS banned_until timestamp NOT NULL DEFAULT 'epoch';
+
+CREATE TABLE IF NOT EXISTS chat_reports
+(
+    id          serial    PRIMARY KEY,
+    message_id  int       NOT NULL,
+    reporter_id int       NOT NULL,
+    reason      text      NOT NULL DEFAULT '',
+    created_at  timestamp NOT NULL DEFAULT now(),
+    UNIQUE (message_id, reporter_id)
+);
+
+-- Audit log of the moderator actions
+CREATE TABLE IF NOT EXISTS moderation_log
+(
+    id           serial    PRIMARY KEY,
+    moderator_id int       NOT NULL,
+    target_id    int       NOT NULL,
+    action       int       NOT NULL,
+    until        timestamp NOT NULL,
+    reason       text      NOT NULL DEFAULT '',
+    created_at   timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS moderation_log_target_id_idx ON moderation_log (target_id);
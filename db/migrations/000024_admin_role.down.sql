@@ -0,0 +1,2 @@
+ALTER TABLE characters
+    DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,2 @@
+ALTER TABLE characters
+    ADD COLUMN IF NOT EXISTS is_admin boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat_messages_created_at_idx;
+
+ALTER TABLE chat_messages
+    DROP COLUMN IF EXISTS created_at;
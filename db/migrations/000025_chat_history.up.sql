@@ -0,0 +1,5 @@
+-- The existing messages are considered sent at the time of the migration
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS created_at timestamp NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS chat_messages_created_at_idx ON chat_messages (created_at);
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+)
+
+const selectCaravans = `SELECT cr.id, cr.character_id, c.name owner, cr.to_character_id, cr.from_town_id, cr.to_town_id, 
+    cr.from_x "from.x", cr.from_y "from.y", cr.to_x "to.x", cr.to_y "to.y", 
+    cr.wood "resources.wood", cr.stone "resources.stone", cr.food "resources.food", 
+    cr.leather "resources.leather", cr.gold "resources.gold", cr.travel_time, cr.ticks_left 
+FROM caravans cr JOIN characters c ON c.id = cr.character_id`
+
+func (d *DatabaseTransaction) AddCaravan(caravan model.Caravan) (id int64, err error) {
+	resources := caravan.Resources
+	err = d.tx.Get(&id, `INSERT INTO caravans (character_id, to_character_id, from_town_id, to_town_id, 
+    from_x, from_y, to_x, to_y, wood, stone, food, leather, gold, travel_time, ticks_left) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`,
+		caravan.CharacterID, caravan.ToCharacterID, caravan.FromTownID, caravan.ToTownID,
+		caravan.From.X, caravan.From.Y, caravan.To.X, caravan.To.Y,
+		resources.Wood, resources.Stone, resources.Food, resources.Leather, resources.Gold,
+		caravan.TravelTime, caravan.TicksLeft)
+	return id, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateCaravan(caravan model.Caravan) error {
+	_, err := d.tx.Exec("UPDATE caravans SET ticks_left=$2 WHERE id=$1", caravan.ID, caravan.TicksLeft)
+	return d.handleError(err)
+}
+
+// DeleteCaravan - removes the caravan and returns ID of its destination town,
+// the destination is changed if the town is captured on the way
+func (d *DatabaseTransaction) DeleteCaravan(id int64) (toTownID int64, err error) {
+	err = d.tx.Get(&toTownID, "DELETE FROM caravans WHERE id=$1 RETURNING to_town_id", id)
+	return toTownID, d.handleError(err)
+}
+
+// GetCaravansForRect - returns the caravans which way crosses the rect,
+// the caravans themselves may be outside of it
+func (d *DatabaseTransaction) GetCaravansForRect(xStart, xEnd, yStart, yEnd int) (result []model.Caravan, err error) {
+	err = d.tx.Select(&result, selectCaravans+` 
+    WHERE LEAST(cr.from_x, cr.to_x) <= $2 AND GREATEST(cr.from_x, cr.to_x) >= $1 
+    AND LEAST(cr.from_y, cr.to_y) <= $4 AND GREATEST(cr.from_y, cr.to_y) >= $3 ORDER BY cr.id`,
+		xStart, xEnd, yStart, yEnd)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) loadCaravans(characterID int64) (result []model.Caravan, err error) {
+	err = d.tx.Select(&result, selectCaravans+" WHERE cr.character_id=$1 ORDER BY cr.id", characterID)
+	return result, err
+}
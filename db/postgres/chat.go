@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"fmt"
+	"time"
+
+	pq "github.com/lib/pq"
+)
+
+const selectChatMessages = `SELECT message_id id, sender_name sender, text, channel, 
+       recipient_name recipient, region, created_at 
+    FROM chat_messages `
+
+// GetChatMessages - returns the messages of the channel selected by the filter from newest to oldest.
+// The messages right after the AfterID cursor are returned if it's set, the newest ones otherwise
+func (d *DatabaseTransaction) GetChatMessages(filter model.ChatFilter, count int) (result []model.ChatMessage, err error) {
+	query := selectChatMessages + "WHERE channel=$1 AND region=$2 "
+	args := []interface{}{filter.Channel, filter.Region, count}
+
+	if filter.BeforeID != 0 {
+		args = append(args, filter.BeforeID)
+		query += fmt.Sprintf("AND message_id < $%d ", len(args))
+	}
+
+	if filter.AfterID != 0 {
+		args = append(args, filter.AfterID)
+		query += fmt.Sprintf("AND message_id > $%d ", len(args))
+	}
+
+	if len(filter.Senders) > 0 {
+		args = append(args, pq.Array(filter.Senders))
+		query += fmt.Sprintf("AND sender_name = ANY($%d) ", len(args))
+	}
+
+	if len(filter.Recipients) > 0 {
+		args = append(args, pq.Array(filter.Recipients))
+		query += fmt.Sprintf("AND recipient_name = ANY($%d) AND recipient_name <> sender_name ", len(args))
+	}
+
+	if filter.AudienceID != 0 {
+		args = append(args, filter.AudienceID)
+		query += fmt.Sprintf("AND audience @> ARRAY[$%d]::bigint[] ", len(args))
+	}
+
+	if filter.AfterID == 0 {
+		err = d.tx.Select(&result, query+"ORDER BY message_id DESC LIMIT $3", args...)
+		return result, d.handleError(err)
+	}
+
+	err = d.tx.Select(&result, query+"ORDER BY message_id LIMIT $3", args...)
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddChatMessage(message model.ChatMessage) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO chat_messages (sender_name, text, channel, recipient_name, region, created_at, audience) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING message_id`,
+		message.Sender, message.Text, message.Channel, message.Recipient, message.Region, message.Time,
+		pq.Array(message.Audience))
+	return id, d.handleError(err)
+}
+
+// JoinChatChannel - adds the character to the channel, the region of the membership is updated on rejoining
+func (d *DatabaseTransaction) JoinChatChannel(membership model.ChatMembership) error {
+	_, err := d.tx.Exec(`INSERT INTO chat_channel_members (character_id, channel, region) VALUES ($1, $2, $3) 
+    ON CONFLICT (character_id, channel) DO UPDATE SET region=EXCLUDED.region`,
+		membership.CharacterID, membership.Channel, membership.Region)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) LeaveChatChannel(characterID int64, channel rpc.ChatMessage_Channel) error {
+	_, err := d.tx.Exec("DELETE FROM chat_channel_members WHERE character_id=$1 AND channel=$2", characterID, channel)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) GetChatChannels(characterID int64) (result []model.ChatMembership, err error) {
+	err = d.tx.Select(&result,
+		"SELECT * FROM chat_channel_members WHERE character_id=$1 ORDER BY channel", characterID)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) GetChatMessage(id int64) (result model.ChatMessage, err error) {
+	err = d.tx.Get(&result, selectChatMessages+"WHERE message_id=$1", id)
+	return result, d.handleError(err)
+}
+
+// DeleteChatMessages - deletes the messages sent before the time, the reported messages are kept for the moderators
+func (d *DatabaseTransaction) DeleteChatMessages(before time.Time) (int64, error) {
+	result, err := d.tx.Exec(`DELETE FROM chat_messages m WHERE m.created_at < $1 
+    AND NOT EXISTS (SELECT 1 FROM chat_reports r WHERE r.message_id = m.message_id)`, before)
+	if err != nil {
+		return 0, d.handleError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted, d.handleError(err)
+}
@@ -2,9 +2,15 @@ package postgres
 
 import (
 	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"abbysoft/gardarike-online/tracing"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	pq "github.com/lib/pq"
@@ -16,6 +22,35 @@ type DatabaseTransaction struct {
 	autoRollBack bool
 	isRolledBack bool
 	isCommitted  bool
+	startTime    time.Time
+	span         *tracing.Span
+}
+
+type characterJobRow struct {
+	JobID   int64  `db:"job_id"`
+	Workers uint64 `db:"workers"`
+}
+
+type townBuildingRow struct {
+	ID         int64   `db:"id"`
+	TownID     int64   `db:"town_id"`
+	BuildingID int64   `db:"building_id"`
+	LocationX  int64   `db:"location_x"`
+	LocationY  int64   `db:"location_y"`
+	Rotation   float32 `db:"rotation"`
+	Level      uint64  `db:"level"`
+}
+
+type constructionRow struct {
+	ID             int64   `db:"id"`
+	TownID         int64   `db:"town_id"`
+	BuildingID     int64   `db:"building_id"`
+	LocationX      int64   `db:"location_x"`
+	LocationY      int64   `db:"location_y"`
+	Rotation       float32 `db:"rotation"`
+	TicksLeft      uint64  `db:"ticks_left"`
+	TownBuildingID int64   `db:"town_building_id"`
+	Level          uint64  `db:"level"`
 }
 
 type allBuildingsRow struct {
@@ -26,9 +61,9 @@ type allBuildingsRow struct {
 
 func (d *DatabaseTransaction) GetEmpiresByCriteria(characterName string, offset, limit uint32, criteria rpc.EmpiresRatingCriteria) (
 	entries []*rpc.RatingEntry, playerEntry *rpc.RatingEntry, err error) {
-	var columnName string
+	var value string
 	if criteria == rpc.EmpiresRatingCriteria_POPULATION {
-		columnName = "current_population"
+		value = "COALESCE(SUM(t.population), 0)"
 	} else {
 		return nil, nil, fmt.Errorf("invalid criteria")
 	}
@@ -37,10 +72,17 @@ func (d *DatabaseTransaction) GetEmpiresByCriteria(characterName string, offset,
 		limit = 10
 	}
 
-	preparedQuery := fmt.Sprintf(`(SELECT name as empireName, %s as value, row_number() OVER (ORDER BY %s DESC) as position FROM characters ORDER BY value DESC OFFSET %d LIMIT %d)
+	preparedQuery := fmt.Sprintf(`WITH empires AS (
+    SELECT c.name as empireName, %s as value FROM characters c
+    LEFT JOIN towns t ON t.owner_name = c.name
+    GROUP BY c.name
+), rating AS (
+    SELECT empireName, value, row_number() OVER (ORDER BY value DESC) as position FROM empires
+)
+(SELECT * FROM rating ORDER BY position OFFSET %d LIMIT %d)
 UNION
-(SELECT empireName, value, position FROM (SELECT name as empireName, %s as value, row_number() OVER (ORDER BY %s DESC) as position FROM characters) as ir WHERE empireName=$1)
-ORDER BY position`, columnName, columnName, offset, limit, columnName, columnName)
+(SELECT * FROM rating WHERE empireName=$1)
+ORDER BY position`, value, offset, limit)
 
 	if err = d.tx.Select(&entries, preparedQuery, characterName); err != nil {
 		return nil, nil, d.handleError(err)
@@ -61,19 +103,89 @@ func (d *DatabaseTransaction) RenameTown(townID int64, newName string) error {
 	return d.handleError(err)
 }
 
-func (d *DatabaseTransaction) AddOrUpdateProductionRates(rates model.Resources) error {
-	_, err := d.tx.NamedExec(
-		`INSERT INTO production_rates VALUES (:character_id, :wood, :leather, :stone, :food) 
-    ON CONFLICT (character_id) DO 
-    UPDATE SET wood=:wood, leather=:leather, food=:food, stone=:stone`, rates)
+// UpdateTown - updates the town and its storage. Nothing is updated if the town has another owner,
+// so the stale state of the captured town can't overwrite it
+func (d *DatabaseTransaction) UpdateTown(town model.Town) error {
+	result, err := d.tx.NamedExec(`UPDATE towns SET 
+                 name=:name, 
+                 population=:population, 
+                 rotation=:rotation, 
+                 debt=:debt 
+        WHERE id=:id AND owner_name=:owner_name`, town)
+	if err != nil {
+		return d.handleError(err)
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return d.handleError(err)
+	}
+
+	_, err = d.tx.Exec(`INSERT INTO town_resources VALUES ($1, $2, $3, $4, $5, $6) 
+    ON CONFLICT (town_id) DO 
+    UPDATE SET wood=$2, stone=$3, food=$4, leather=$5, gold=$6`,
+		town.ID, town.Resources.Wood, town.Resources.Stone, town.Resources.Food, town.Resources.Leather,
+		town.Resources.Gold)
 	return d.handleError(err)
 }
 
-func (d *DatabaseTransaction) GetProductionRates(characterID int64) (result model.Resources, err error) {
-	err = d.tx.Get(&result, "SELECT * FROM production_rates WHERE character_id=$1", characterID)
+func (d *DatabaseTransaction) SetTownOwner(townID int64, ownerName string) error {
+	_, err := d.tx.Exec("UPDATE towns SET owner_name=$2, captured_at=now() WHERE id=$1", townID, ownerName)
+	return d.handleError(err)
+}
+
+// GetLostTowns - returns IDs of the towns which are not owned by the character anymore
+func (d *DatabaseTransaction) GetLostTowns(ownerName string, townIDs []int64) (result []int64, err error) {
+	err = d.tx.Select(&result, "SELECT id FROM towns WHERE id = ANY($1) AND owner_name<>$2 ORDER BY id",
+		pq.Array(townIDs), ownerName)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddDelivery(delivery model.Delivery) error {
+	resources := delivery.Resources
+	_, err := d.tx.Exec(`INSERT INTO town_deliveries (town_id, wood, stone, food, leather, gold) 
+    VALUES ($1, $2, $3, $4, $5, $6)`,
+		delivery.TownID, resources.Wood, resources.Stone, resources.Food, resources.Leather, resources.Gold)
+	return d.handleError(err)
+}
+
+// TakeDeliveries - removes and returns the deliveries to the towns
+func (d *DatabaseTransaction) TakeDeliveries(townIDs []int64) (result []model.Delivery, err error) {
+	err = d.tx.Select(&result, `DELETE FROM town_deliveries WHERE town_id = ANY($1) RETURNING id, town_id, 
+    wood "resources.wood", stone "resources.stone", food "resources.food", leather "resources.leather", 
+    gold "resources.gold"`, pq.Array(townIDs))
 	return result, d.handleError(err)
 }
 
+// MoveTownGoods - moves the market orders, the deliveries and the inbound caravans of one town to another
+func (d *DatabaseTransaction) MoveTownGoods(fromTownID, toTownID int64) error {
+	for _, query := range []string{
+		"UPDATE market_orders SET town_id=$2 WHERE town_id=$1",
+		"UPDATE town_deliveries SET town_id=$2 WHERE town_id=$1",
+		"UPDATE caravans SET to_town_id=$2 WHERE to_town_id=$1",
+	} {
+		if _, err := d.tx.Exec(query, fromTownID, toTownID); err != nil {
+			return d.handleError(err)
+		}
+	}
+
+	return d.handleError(nil)
+}
+
+// DeleteTownGoods - removes the market orders, the deliveries and the inbound caravans of the town
+func (d *DatabaseTransaction) DeleteTownGoods(townID int64) error {
+	for _, query := range []string{
+		"DELETE FROM market_orders WHERE town_id=$1",
+		"DELETE FROM town_deliveries WHERE town_id=$1",
+		"DELETE FROM caravans WHERE to_town_id=$1",
+	} {
+		if _, err := d.tx.Exec(query, townID); err != nil {
+			return d.handleError(err)
+		}
+	}
+
+	return d.handleError(nil)
+}
+
 func (d *DatabaseTransaction) GetAllBuildings() (result map[int64]model.CharacterBuildings, err error) {
 	var rows []allBuildingsRow
 	err = d.tx.Select(&rows, `select c.id character_id, tb.building_id, COUNT(tb.building_id) from town_buildings tb 
@@ -99,6 +211,64 @@ GROUP BY c.id, tb.building_id`)
 	return
 }
 
+func (d *DatabaseTransaction) GetCharacterBuildings(characterID int64) (result model.CharacterBuildings, err error) {
+	var rows []allBuildingsRow
+	err = d.tx.Select(&rows, `select c.id character_id, tb.building_id, COUNT(tb.building_id) from town_buildings tb 
+join towns t on tb.town_id = t.id 
+join characters c on t.owner_name = c.name
+WHERE c.id = $1
+GROUP BY c.id, tb.building_id`, characterID)
+
+	if err != nil {
+		return nil, d.handleError(err)
+	}
+
+	result = make(model.CharacterBuildings)
+	for _, row := range rows {
+		if model.IsValidBuildingType(int32(row.BuildingID)) {
+			result[rpc.BuildingType(row.BuildingID)] = row.Count
+		}
+	}
+
+	return
+}
+
+func (d *DatabaseTransaction) GetWorkDistribution(characterID int64) (result model.WorkDistribution, err error) {
+	var rows []characterJobRow
+	err = d.tx.Select(&rows, "SELECT job_id, workers FROM character_jobs WHERE character_id=$1", characterID)
+	if err != nil {
+		return nil, d.handleError(err)
+	}
+
+	result = make(model.WorkDistribution)
+	for _, row := range rows {
+		if model.IsValidJobType(int32(row.JobID)) {
+			result[rpc.JobType(row.JobID)] = row.Workers
+		}
+	}
+
+	return
+}
+
+func (d *DatabaseTransaction) UpdateWorkDistribution(characterID int64, distribution model.WorkDistribution) error {
+	if _, err := d.tx.Exec("DELETE FROM character_jobs WHERE character_id=$1", characterID); err != nil {
+		return d.handleError(err)
+	}
+
+	for job, workers := range distribution {
+		if workers == 0 {
+			continue
+		}
+
+		_, err := d.tx.Exec("INSERT INTO character_jobs VALUES ($1, $2, $3)", characterID, int64(job), workers)
+		if err != nil {
+			return d.handleError(err)
+		}
+	}
+
+	return d.handleError(nil)
+}
+
 func (d *DatabaseTransaction) SetAutoCommit(value bool) {
 	d.autoCommit = value
 }
@@ -123,19 +293,68 @@ type Database struct {
 	db *sqlx.DB
 }
 
-func (d *DatabaseTransaction) AddTownBuilding(townID int64, building model.Building) error {
-	_, err := d.tx.Exec("INSERT INTO town_buildings VALUES ($1, $2, $3, $4, $5)",
-		townID, building.ID, int64(building.Location.X), int64(building.Location.Y), building.Rotation)
+// AddTownBuilding - adds the building to the town, returns ID of the town building
+func (d *DatabaseTransaction) AddTownBuilding(townID int64, building model.Building) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO town_buildings (town_id, building_id, location_x, location_y, rotation, level) 
+    VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		townID, building.ID, int64(building.Location.X), int64(building.Location.Y), building.Rotation, building.Level)
+	return id, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateTownBuilding(building model.Building) error {
+	_, err := d.tx.Exec("UPDATE town_buildings SET location_x=$1, location_y=$2, rotation=$3, level=$4 WHERE id=$5",
+		int64(building.Location.X), int64(building.Location.Y), building.Rotation, building.Level, building.TownBuildingID)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteTownBuilding(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM town_buildings WHERE id=$1", id)
+	return d.handleError(err)
+}
+
+// AddConstruction - puts the building to the end of the town construction queue, returns ID of the construction
+func (d *DatabaseTransaction) AddConstruction(construction model.Construction) (id int64, err error) {
+	building := construction.Building
+	err = d.tx.Get(&id, `INSERT INTO construction_queue 
+    (town_id, building_id, location_x, location_y, rotation, ticks_left, town_building_id, level) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		construction.TownID, building.ID, int64(building.Location.X), int64(building.Location.Y), building.Rotation,
+		construction.TicksLeft, building.TownBuildingID, building.Level)
+	return id, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateConstruction(construction model.Construction) error {
+	_, err := d.tx.Exec("UPDATE construction_queue SET ticks_left=$1 WHERE id=$2", construction.TicksLeft, construction.ID)
 	return d.handleError(err)
 }
 
-func (d *Database) BeginTransaction(autoCommit, autoRollBack bool) (db.DatabaseTransaction, error) {
-	tx, err := d.db.Beginx()
+func (d *DatabaseTransaction) DeleteConstruction(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM construction_queue WHERE id=$1", id)
+	return d.handleError(err)
+}
+
+func (d *Database) BeginTransaction(ctx context.Context, autoCommit, autoRollBack bool) (db.DatabaseTransaction, error) {
+	ctx, span := tracing.StartSpan(ctx, "DatabaseTransaction")
+
+	tx, err := d.db.BeginTxx(ctx, nil)
 	if err != nil {
+		span.SetError(err)
+		span.End()
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	return &DatabaseTransaction{tx: tx, autoCommit: autoCommit, autoRollBack: autoRollBack}, nil
+	return &DatabaseTransaction{
+		tx:           tx,
+		autoCommit:   autoCommit,
+		autoRollBack: autoRollBack,
+		startTime:    time.Now(),
+		span:         span,
+	}, nil
+}
+
+// Ping - checks the database connection is alive
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
 }
 
 func (d *DatabaseTransaction) EndTransaction() error {
@@ -149,6 +368,7 @@ func (d *DatabaseTransaction) EndTransaction() error {
 		}
 		d.tx = nil
 		d.isCommitted = true
+		d.observe("commit")
 		return nil
 	} else {
 		return fmt.Errorf("transaction is not started")
@@ -157,6 +377,14 @@ func (d *DatabaseTransaction) EndTransaction() error {
 
 type transactionFunc func(t *sqlx.Tx) error
 
+// observe - records the transaction latency with the specified result and ends the transaction span
+func (d *DatabaseTransaction) observe(result string) {
+	metrics.ObserveSince(metrics.DBTransactionDuration.WithLabelValues(result), d.startTime)
+
+	d.span.SetAttribute("result", result)
+	d.span.End()
+}
+
 func (d *DatabaseTransaction) handleError(err error) error {
 	if err == nil {
 		if d.autoCommit {
@@ -165,6 +393,7 @@ func (d *DatabaseTransaction) handleError(err error) error {
 			} else {
 				d.tx = nil
 				d.isCommitted = true
+				d.observe("commit")
 			}
 		}
 
@@ -177,6 +406,8 @@ func (d *DatabaseTransaction) handleError(err error) error {
 		} else {
 			d.tx = nil
 			d.isRolledBack = true
+			d.observe("rollback")
+			metrics.DBRollbacks.Inc()
 		}
 	}
 
@@ -194,33 +425,46 @@ type incrementMapResources struct {
 	Limit model.ChunkResources
 }
 
+// IncrementMapResources - regrows resources of the global chunks, a chunk never gets more than the limit
 func (d *DatabaseTransaction) IncrementMapResources(resources model.ChunkResources, limit model.ChunkResources) error {
-
 	_, err := d.tx.NamedExec(`
-UPDATE chunks SET stones = stones + :inc.stones 
-WHERE stones < :limit.stones`, incrementMapResources{Limit: limit, Inc: resources})
-	if err != nil {
-		return d.handleError(err)
-	}
-
-	_, err = d.tx.NamedExec(`
-UPDATE chunks SET trees = chunks.trees + :inc.trees 
-WHERE trees < :limit.trees`, incrementMapResources{Limit: limit, Inc: resources})
-	if err != nil {
-		return d.handleError(err)
-	}
+UPDATE chunks SET 
+    trees = LEAST(trees + :inc.trees, GREATEST(trees, :limit.trees)),
+    stones = LEAST(stones + :inc.stones, GREATEST(stones, :limit.stones)),
+    animals = LEAST(animals + :inc.animals, GREATEST(animals, :limit.animals)),
+    plants = LEAST(plants + :inc.plants, GREATEST(plants, :limit.plants))
+WHERE number = 0`, incrementMapResources{Limit: limit, Inc: resources})
+	return d.handleError(err)
+}
 
-	_, err = d.tx.NamedExec(`
-UPDATE chunks SET animals = animals + :inc.animals 
-WHERE animals < :limit.animals`, incrementMapResources{Limit: limit, Inc: resources})
-	if err != nil {
-		return d.handleError(err)
+// TakeChunkResources - decrements resources of the global chunk by the requested amount or
+// by the available amount if there is not enough. Returns the taken resources
+func (d *DatabaseTransaction) TakeChunkResources(x, y int64, requested model.ChunkResources) (result model.ChunkResources, err error) {
+	err = d.tx.Get(&result, `
+WITH old AS (
+    SELECT x, y, number, trees, stones, animals, plants FROM chunks 
+    WHERE x=$1 AND y=$2 AND number=0 
+    FOR UPDATE
+)
+UPDATE chunks c SET 
+    trees = c.trees - LEAST(old.trees, $3),
+    stones = c.stones - LEAST(old.stones, $4),
+    animals = c.animals - LEAST(old.animals, $5),
+    plants = c.plants - LEAST(old.plants, $6)
+FROM old WHERE c.x = old.x AND c.y = old.y AND c.number = old.number
+RETURNING 
+    LEAST(old.trees, $3) trees, 
+    LEAST(old.stones, $4) stones, 
+    LEAST(old.animals, $5) animals, 
+    LEAST(old.plants, $6) plants`,
+		x, y, requested.Trees, requested.Stones, requested.Animals, requested.Plants)
+
+	// Chunk isn't generated yet, so there is nothing to take
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ChunkResources{}, d.handleError(nil)
 	}
 
-	_, err = d.tx.NamedExec(`
-UPDATE chunks SET plants = chunks.plants + :inc.plants 
-WHERE plants < :limit.plants`, incrementMapResources{Limit: limit, Inc: resources})
-	return d.handleError(err)
+	return result, d.handleError(err)
 }
 
 func (d *DatabaseTransaction) GetChunkRange() (result model.ChunkRange, err error) {
@@ -229,6 +473,12 @@ func (d *DatabaseTransaction) GetChunkRange() (result model.ChunkRange, err erro
 	return result, d.handleError(err)
 }
 
+func (d *DatabaseTransaction) GetChunksCount() (result model.ChunksCount, err error) {
+	err = d.tx.Get(&result,
+		"SELECT COUNT(*) FILTER (WHERE number = 0) as global, COUNT(*) FILTER (WHERE number <> 0) as local FROM chunks")
+	return result, d.handleError(err)
+}
+
 func (d *DatabaseTransaction) GetTownsForRect(xStart, xEnd, yStart, yEnd int) (results []model.Town, err error) {
 	err = d.tx.Select(&results,
 		"SELECT * FROM towns WHERE (x BETWEEN $1 AND $2) AND (y BETWEEN $3 AND $4)",
@@ -236,23 +486,22 @@ func (d *DatabaseTransaction) GetTownsForRect(xStart, xEnd, yStart, yEnd int) (r
 	return results, d.handleError(err)
 }
 
-func (d *DatabaseTransaction) AddTown(town model.Town) error {
-	_, err := d.tx.NamedExec(
-		`INSERT INTO towns VALUES (DEFAULT, :x, :y, :name, :owner_name, :population, :rotation)`, town)
-	return d.handleError(err)
-}
-
-func (d *DatabaseTransaction) AddOrUpdateResources(resources model.Resources) error {
-	_, err := d.tx.NamedExec(
-		`INSERT INTO resources VALUES (:character_id, :wood, :stone, :food, :leather) ON CONFLICT (character_id) DO 
-    UPDATE SET stone = :stone, food = :food, leather = :leather, wood = :wood`, resources)
-	return d.handleError(err)
-}
+// AddTown - adds the town with its storage, returns ID of the town
+func (d *DatabaseTransaction) AddTown(town model.Town) (id int64, err error) {
+	statement, err := d.tx.PrepareNamed(
+		`INSERT INTO towns VALUES (DEFAULT, :x, :y, :name, :owner_name, :population, :rotation) RETURNING id`)
+	if err != nil {
+		return 0, d.handleError(err)
+	}
+	defer statement.Close()
 
-func (d *DatabaseTransaction) GetResources(characterID int64) (result model.Resources, err error) {
-	err = d.tx.Get(&result, "SELECT * FROM resources WHERE character_id=$1", characterID)
+	if err = statement.Get(&id, town); err != nil {
+		return 0, d.handleError(err)
+	}
 
-	return result, d.handleError(err)
+	_, err = d.tx.Exec("INSERT INTO town_resources VALUES ($1, $2, $3, $4, $5, $6)",
+		id, town.Resources.Wood, town.Resources.Stone, town.Resources.Food, town.Resources.Leather, town.Resources.Gold)
+	return id, d.handleError(err)
 }
 
 func (d *DatabaseTransaction) AddAccountCharacter(characterID, accountID int) error {
@@ -272,16 +521,130 @@ func (d *DatabaseTransaction) GetAllTowns() (result []model.Town, err error) {
 	return result, d.handleError(err)
 }
 
+const selectTownsWithResources = `SELECT t.*, 
+    COALESCE(r.wood, 0) "resources.wood", 
+    COALESCE(r.stone, 0) "resources.stone", 
+    COALESCE(r.food, 0) "resources.food", 
+    COALESCE(r.leather, 0) "resources.leather", 
+    COALESCE(r.gold, 0) "resources.gold" 
+FROM towns t LEFT JOIN town_resources r ON r.town_id = t.id`
+
+// GetTown - returns the town with its storage, buildings and construction queue
+func (d *DatabaseTransaction) GetTown(townID int64) (result model.Town, err error) {
+	err = d.tx.Get(&result, selectTownsWithResources+" WHERE t.id=$1", townID)
+	if err != nil {
+		return result, d.handleError(err)
+	}
+
+	towns := []model.Town{result}
+	if err = d.loadTownDetails(towns); err != nil {
+		return result, err
+	}
+
+	return towns[0], nil
+}
+
+// GetTowns - returns towns of the character with their storages, buildings and construction queues.
+// The towns founded by the character are sorted by ID and go before the captured ones sorted by the capture time
 func (d *DatabaseTransaction) GetTowns(ownerName string) (result []model.Town, err error) {
-	err = d.tx.Select(&result, "SELECT * FROM towns WHERE owner_name=$1", ownerName)
-	return result, d.handleError(err)
+	err = d.tx.Select(&result, selectTownsWithResources+
+		" WHERE t.owner_name=$1 ORDER BY t.captured_at NULLS FIRST, t.id", ownerName)
+	if err != nil {
+		return nil, d.handleError(err)
+	}
+
+	return result, d.loadTownDetails(result)
+}
+
+func (d *DatabaseTransaction) loadTownDetails(towns []model.Town) error {
+	if len(towns) == 0 {
+		return d.handleError(nil)
+	}
+
+	ids := make([]int64, 0, len(towns))
+	for _, town := range towns {
+		ids = append(ids, town.ID)
+	}
+
+	if err := d.loadTownBuildings(towns, ids); err != nil {
+		return d.handleError(err)
+	}
+
+	if err := d.loadConstructionQueues(towns, ids); err != nil {
+		return d.handleError(err)
+	}
+
+	return d.handleError(d.loadTrainingQueues(towns, ids))
+}
+
+func (d *DatabaseTransaction) loadTownBuildings(towns []model.Town, ids []int64) error {
+	var rows []townBuildingRow
+	err := d.tx.Select(&rows,
+		"SELECT * FROM town_buildings WHERE town_id = ANY($1) ORDER BY id",
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byTown := make(map[int64][]model.Building)
+	for _, row := range rows {
+		building, found := model.Buildings[rpc.BuildingType(row.BuildingID)]
+		if !found {
+			continue
+		}
+
+		building = building.AtLevel(row.Level)
+		building.TownBuildingID = row.ID
+		building.Location = model.Vector2D{X: float32(row.LocationX), Y: float32(row.LocationY)}
+		building.Rotation = row.Rotation
+		byTown[row.TownID] = append(byTown[row.TownID], building)
+	}
+
+	for i := range towns {
+		towns[i].Buildings = byTown[towns[i].ID]
+	}
+
+	return nil
+}
+
+func (d *DatabaseTransaction) loadConstructionQueues(towns []model.Town, ids []int64) error {
+	var rows []constructionRow
+	err := d.tx.Select(&rows, "SELECT * FROM construction_queue WHERE town_id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byTown := make(map[int64][]model.Construction)
+	for _, row := range rows {
+		building, found := model.Buildings[rpc.BuildingType(row.BuildingID)]
+		if !found {
+			continue
+		}
+
+		building = building.AtLevel(row.Level)
+		building.TownBuildingID = row.TownBuildingID
+		building.Location = model.Vector2D{X: float32(row.LocationX), Y: float32(row.LocationY)}
+		building.Rotation = row.Rotation
+		byTown[row.TownID] = append(byTown[row.TownID], model.Construction{
+			ID:        row.ID,
+			TownID:    row.TownID,
+			Building:  building,
+			TicksLeft: row.TicksLeft,
+		})
+	}
+
+	for i := range towns {
+		towns[i].Construction = byTown[towns[i].ID]
+	}
+
+	return nil
 }
 
 func (d *DatabaseTransaction) SaveMapChunkOrUpdate(chunk model.WorldMapChunk) error {
-	_, err := d.tx.NamedQuery(
-		`INSERT INTO chunks (x, y, data, trees, stones, animals, plants) VALUES 
-                                      (:x, :y, :data, :trees, :stones, :animals, :plants)
-			   ON CONFLICT (x, y) DO UPDATE 
+	_, err := d.tx.NamedExec(
+		`INSERT INTO chunks (x, y, number, data, trees, stones, animals, plants) VALUES 
+                                      (:x, :y, :number, :data, :trees, :stones, :animals, :plants)
+			   ON CONFLICT (x, y, number) DO UPDATE 
 			   SET trees = :trees,
 			   stones = :stones,
 			   animals = :animals,
@@ -295,37 +658,26 @@ func (d *DatabaseTransaction) GetMapChunk(x, y, number int64) (result model.Worl
 	return result, d.handleError(err)
 }
 
-func (d *DatabaseTransaction) GetChatMessages(offset int, count int) (result []model.ChatMessage, err error) {
-	err = d.tx.Select(&result,
-		"SELECT * FROM chat_messages ORDER BY message_id DESC OFFSET $1 LIMIT $2",
-		offset, count)
-	return result, d.handleError(err)
-}
-
-func (d *DatabaseTransaction) AddChatMessage(message model.ChatMessage) (id int64, err error) {
-	err = d.tx.Get(&id,
-		"INSERT INTO chat_messages (sender_name, text) VALUES ($1, $2) RETURNING message_id",
-		message.Sender, message.Text)
-	return id, d.handleError(err)
-}
-
+// UpdateCharacter - updates the character and all its towns
 func (d *DatabaseTransaction) UpdateCharacter(character model.Character) error {
 	_, err := d.tx.NamedExec(
 		`UPDATE characters SET 
 			  name=:name, 
-			  max_population=:max_population, 
-			  current_population=:current_population
+			  happiness=:happiness,
+			  tax_rate=:tax_rate,
+			  starving_ticks=:starving_ticks,
+			  protected_until=:protected_until,
+			  is_moderator=:is_moderator,
+			  is_admin=:is_admin
          WHERE id=:id`, &character)
 	if err != nil {
 		return d.handleError(err)
 	}
 
-	if err = d.AddOrUpdateResources(character.Resources); err != nil {
-		return fmt.Errorf("failed to update character resources: %w", err)
-	}
-
-	if err = d.AddOrUpdateProductionRates(character.ProductionRate); err != nil {
-		return fmt.Errorf("failed to update character production rate: %w", err)
+	for _, town := range character.Towns {
+		if err = d.UpdateTown(town); err != nil {
+			return fmt.Errorf("failed to update town %d: %w", town.ID, err)
+		}
 	}
 
 	return nil
@@ -336,8 +688,17 @@ func (d *DatabaseTransaction) GetCharacters(accountID int64) (result []model.Cha
     INNER JOIN characters as c
         ON c.id = a.character_id
 WHERE account_id = $1`, accountID)
+	if err != nil {
+		return nil, d.handleError(err)
+	}
 
-	return result, d.handleError(err)
+	for i := range result {
+		if result[i].Towns, err = d.GetTowns(result[i].Name); err != nil {
+			return nil, fmt.Errorf("failed to get character towns: %w", err)
+		}
+	}
+
+	return result, nil
 }
 
 func (d *DatabaseTransaction) GetAccount(login string) (result model.Account, err error) {
@@ -354,39 +715,87 @@ func (d *DatabaseTransaction) GetCharacter(id int64) (result model.Character, er
 		return result, d.handleError(err)
 	}
 
-	resources, err := d.GetResources(id)
+	workers, err := d.GetWorkDistribution(id)
 	if err != nil {
-		return result, fmt.Errorf("failed to get character resources: %w", err)
+		return result, fmt.Errorf("failed to get character work distribution: %w", err)
 	}
 
-	result.Resources = resources
+	result.Workers = workers
 
-	productionRates, err := d.GetProductionRates(id)
-	if err != nil {
-		return result, fmt.Errorf("failed to get character production rates: %w", err)
+	if result.Technologies, err = d.loadTechnologies(id); err != nil {
+		return result, fmt.Errorf("failed to get character technologies: %w", err)
+	}
+
+	if result.Research, err = d.loadResearch(id); err != nil {
+		return result, fmt.Errorf("failed to get character research: %w", err)
+	}
+
+	if result.Caravans, err = d.loadCaravans(id); err != nil {
+		return result, fmt.Errorf("failed to get character caravans: %w", err)
 	}
 
-	result.ProductionRate = productionRates
 	return result, d.handleError(err)
 }
 
-func (d *DatabaseTransaction) AddCharacter(name string) (id int, err error) {
-	err = d.tx.Get(&id, "INSERT INTO characters VALUES (DEFAULT, $1, DEFAULT, DEFAULT) RETURNING id", name)
-	if err != nil {
-		return 0, d.handleError(err)
+// GetCharacterID - returns ID of the character with the name
+func (d *DatabaseTransaction) GetCharacterID(name string) (id int64, err error) {
+	err = d.tx.Get(&id, "SELECT id FROM characters WHERE name=$1", name)
+	return id, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) loadTechnologies(characterID int64) (model.Technologies, error) {
+	var ids []int32
+	if err := d.tx.Select(&ids,
+		"SELECT technology_id FROM character_technologies WHERE character_id=$1", characterID); err != nil {
+		return nil, err
 	}
 
-	_, err = d.tx.Exec("INSERT INTO resources VALUES ($1, 0, 0, 0, 0)", id)
-	if err != nil {
-		return id, fmt.Errorf("failed to insert character resources: %w", err)
+	result := make(model.Technologies)
+	for _, id := range ids {
+		if model.IsValidTechnologyType(id) {
+			result[rpc.TechnologyType(id)] = true
+		}
 	}
 
-	_, err = d.tx.Exec("INSERT INTO production_rates VALUES ($1, 0, 0, 0, 0)", id)
-	if err != nil {
-		err = fmt.Errorf("failed to insert character production rates: %w", err)
+	return result, nil
+}
+
+// loadResearch - returns the current research of the character or nil if nothing is being researched
+func (d *DatabaseTransaction) loadResearch(characterID int64) (*model.Research, error) {
+	var result model.Research
+	err := d.tx.Get(&result,
+		"SELECT technology_id, ticks_left FROM character_research WHERE character_id=$1", characterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	return id, err
+	return &result, nil
+}
+
+func (d *DatabaseTransaction) AddTechnology(characterID int64, id rpc.TechnologyType) error {
+	_, err := d.tx.Exec(`INSERT INTO character_technologies (character_id, technology_id) VALUES ($1, $2)
+    ON CONFLICT DO NOTHING`, characterID, id)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) SetResearch(characterID int64, research model.Research) error {
+	_, err := d.tx.Exec(`INSERT INTO character_research (character_id, technology_id, ticks_left) VALUES ($1, $2, $3)
+    ON CONFLICT (character_id) DO UPDATE SET technology_id=excluded.technology_id, ticks_left=excluded.ticks_left`,
+		characterID, research.TechnologyID, research.TicksLeft)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteResearch(characterID int64) error {
+	_, err := d.tx.Exec("DELETE FROM character_research WHERE character_id=$1", characterID)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddCharacter(name string) (id int, err error) {
+	err = d.tx.Get(&id, "INSERT INTO characters (name, protected_until) VALUES ($1, $2) RETURNING id",
+		name, time.Now().Add(model.NewPlayerProtection))
+	return id, d.handleError(err)
 }
 
 func (d *DatabaseTransaction) DeleteCharacter(id int64) error {
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+const selectProposals = `SELECT p.*, f.name "from", t.name "to" FROM diplomacy_proposals p 
+    JOIN characters f ON f.id = p.from_id 
+    JOIN characters t ON t.id = p.to_id`
+
+// GetDiplomaticState - returns the state of the relations between the characters, neutral if there are none
+func (d *DatabaseTransaction) GetDiplomaticState(characterID, otherID int64) (state rpc.DiplomaticState, err error) {
+	err = d.tx.Get(&state, `SELECT COALESCE(
+    (SELECT state FROM diplomatic_relations WHERE character_id=$1 AND other_id=$2), 0)`, characterID, otherID)
+	return state, d.handleError(err)
+}
+
+// GetDiplomaticRelations - returns the non-neutral relations of the character
+func (d *DatabaseTransaction) GetDiplomaticRelations(characterID int64) (result []model.DiplomaticRelation, err error) {
+	err = d.tx.Select(&result, `SELECT r.character_id, r.other_id, c.name other, r.state FROM diplomatic_relations r 
+    JOIN characters c ON c.id = r.other_id WHERE r.character_id=$1 ORDER BY c.name`, characterID)
+	return result, d.handleError(err)
+}
+
+// SetDiplomaticState - sets the state of the relations for both characters, the neutral relations aren't stored
+func (d *DatabaseTransaction) SetDiplomaticState(characterID, otherID int64, state rpc.DiplomaticState) error {
+	_, err := d.tx.Exec(`DELETE FROM diplomatic_relations 
+    WHERE (character_id=$1 AND other_id=$2) OR (character_id=$2 AND other_id=$1)`, characterID, otherID)
+	if err != nil || state == rpc.DiplomaticState_NEUTRAL {
+		return d.handleError(err)
+	}
+
+	_, err = d.tx.Exec(`INSERT INTO diplomatic_relations (character_id, other_id, state) 
+    VALUES ($1, $2, $3), ($2, $1, $3)`, characterID, otherID, state)
+	return d.handleError(err)
+}
+
+// AddDiplomacyProposal - adds the proposal, the previous proposal of the character to the same empire is replaced
+func (d *DatabaseTransaction) AddDiplomacyProposal(proposal model.DiplomacyProposal) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO diplomacy_proposals (from_id, to_id, state, created_at) 
+    VALUES ($1, $2, $3, $4) 
+    ON CONFLICT (from_id, to_id) DO UPDATE SET state=EXCLUDED.state, created_at=EXCLUDED.created_at 
+    RETURNING id`,
+		proposal.FromID, proposal.ToID, proposal.State, proposal.Time)
+	return id, d.handleError(err)
+}
+
+// GetDiplomacyProposal - returns the proposal locked until the end of the transaction
+func (d *DatabaseTransaction) GetDiplomacyProposal(id int64) (result model.DiplomacyProposal, err error) {
+	err = d.tx.Get(&result, selectProposals+" WHERE p.id=$1 FOR UPDATE OF p", id)
+	return result, d.handleError(err)
+}
+
+// GetDiplomacyProposals - returns the proposals sent by the character and to the character
+func (d *DatabaseTransaction) GetDiplomacyProposals(characterID int64) (result []model.DiplomacyProposal, err error) {
+	err = d.tx.Select(&result, selectProposals+" WHERE p.from_id=$1 OR p.to_id=$1 ORDER BY p.id", characterID)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteDiplomacyProposal(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM diplomacy_proposals WHERE id=$1", id)
+	return d.handleError(err)
+}
+
+// DeleteDiplomacyProposals - deletes the proposals between the characters in both directions
+func (d *DatabaseTransaction) DeleteDiplomacyProposals(characterID, otherID int64) error {
+	_, err := d.tx.Exec(`DELETE FROM diplomacy_proposals 
+    WHERE (from_id=$1 AND to_id=$2) OR (from_id=$2 AND to_id=$1)`, characterID, otherID)
+	return d.handleError(err)
+}
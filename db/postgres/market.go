@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+const selectMarketOrders = `SELECT o.id, o.character_id, c.name owner, o.town_id, o.resource, o.side, o.price, o.amount 
+FROM market_orders o JOIN characters c ON c.id = o.character_id`
+
+const selectTrades = `SELECT t.*, b.name buyer, s.name seller FROM market_trades t 
+    JOIN characters b ON b.id = t.buyer_id 
+    JOIN characters s ON s.id = t.seller_id`
+
+func (d *DatabaseTransaction) AddMarketOrder(order model.MarketOrder) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO market_orders (character_id, town_id, resource, side, price, amount) 
+    VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		order.CharacterID, order.TownID, order.Resource, order.Side, order.Price, order.Amount)
+	return id, d.handleError(err)
+}
+
+// GetMarketOrder - returns the order and locks it until the end of the transaction
+func (d *DatabaseTransaction) GetMarketOrder(id int64) (result model.MarketOrder, err error) {
+	err = d.tx.Get(&result, selectMarketOrders+" WHERE o.id=$1 FOR UPDATE OF o", id)
+	return result, d.handleError(err)
+}
+
+// GetMatchingOrders - returns the orders of other characters the order can be filled with, the best prices
+// and the oldest orders go first. The orders are locked until the end of the transaction
+func (d *DatabaseTransaction) GetMatchingOrders(order model.MarketOrder, limit int) (result []model.MarketOrder, err error) {
+	query := selectMarketOrders + " WHERE o.resource=$1 AND o.side=$2 AND o.character_id<>$3 "
+	if order.Side == rpc.OrderSide_BUY {
+		query += "AND o.price<=$4 ORDER BY o.price, o.id"
+	} else {
+		query += "AND o.price>=$4 ORDER BY o.price DESC, o.id"
+	}
+
+	err = d.tx.Select(&result, query+" LIMIT $5 FOR UPDATE OF o",
+		order.Resource, oppositeSide(order.Side), order.CharacterID, order.Price, limit)
+	return result, d.handleError(err)
+}
+
+// GetMarketOrders - returns the order book of the resource side, the best prices go first
+func (d *DatabaseTransaction) GetMarketOrders(
+	resource rpc.ResourceType, side rpc.OrderSide, offset, limit int) (result []model.MarketOrder, err error) {
+	order := "o.price, o.id"
+	if side == rpc.OrderSide_BUY {
+		order = "o.price DESC, o.id"
+	}
+
+	err = d.tx.Select(&result, selectMarketOrders+" WHERE o.resource=$1 AND o.side=$2 ORDER BY "+order+
+		" OFFSET $3 LIMIT $4", resource, side, offset, limit)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) GetCharacterMarketOrders(characterID int64) (result []model.MarketOrder, err error) {
+	err = d.tx.Select(&result, selectMarketOrders+" WHERE o.character_id=$1 ORDER BY o.id", characterID)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateMarketOrder(order model.MarketOrder) error {
+	_, err := d.tx.Exec("UPDATE market_orders SET amount=$1 WHERE id=$2", order.Amount, order.ID)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteMarketOrder(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM market_orders WHERE id=$1", id)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddTrade(trade model.Trade) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO market_trades 
+    (resource, price, amount, buy_order_id, sell_order_id, buyer_id, seller_id, created_at, fee) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		trade.Resource, trade.Price, trade.Amount, trade.BuyOrderID, trade.SellOrderID, trade.BuyerID, trade.SellerID,
+		trade.Time, trade.Fee)
+	return id, d.handleError(err)
+}
+
+// GetTrades - returns the trades of the resource from newest to oldest
+func (d *DatabaseTransaction) GetTrades(resource rpc.ResourceType, offset, limit int) (result []model.Trade, err error) {
+	err = d.tx.Select(&result, selectTrades+" WHERE t.resource=$1 ORDER BY t.id DESC OFFSET $2 LIMIT $3",
+		resource, offset, limit)
+	return result, d.handleError(err)
+}
+
+// GetCharacterTrades - returns the trades the character bought or sold in from newest to oldest
+func (d *DatabaseTransaction) GetCharacterTrades(characterID int64, offset, limit int) (result []model.Trade, err error) {
+	err = d.tx.Select(&result, selectTrades+" WHERE t.buyer_id=$1 OR t.seller_id=$1 ORDER BY t.id DESC OFFSET $2 LIMIT $3",
+		characterID, offset, limit)
+	return result, d.handleError(err)
+}
+
+func oppositeSide(side rpc.OrderSide) rpc.OrderSide {
+	if side == rpc.OrderSide_BUY {
+		return rpc.OrderSide_SELL
+	}
+
+	return rpc.OrderSide_BUY
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+
+	pq "github.com/lib/pq"
+)
+
+const selectArmies = `SELECT a.id, a.character_id, c.name owner, a.town_id, 
+    a.spearmen "units.spearmen", a.archers "units.archers", a.cavalry "units.cavalry", 
+    a.x "location.x", a.y "location.y", a.destination_x "destination.x", a.destination_y "destination.y", a.is_moving, 
+    a.target_town_id, a.siege_ticks 
+FROM armies a JOIN characters c ON c.id = a.character_id`
+
+func (d *DatabaseTransaction) AddTraining(training model.UnitTraining) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO unit_training (town_id, unit_type, count, ticks_left) 
+    VALUES ($1, $2, $3, $4) RETURNING id`,
+		training.TownID, training.UnitType, training.Count, training.TicksLeft)
+	return id, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateTraining(training model.UnitTraining) error {
+	_, err := d.tx.Exec("UPDATE unit_training SET ticks_left=$2 WHERE id=$1", training.ID, training.TicksLeft)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteTraining(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM unit_training WHERE id=$1", id)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) loadTrainingQueues(towns []model.Town, ids []int64) error {
+	var rows []model.UnitTraining
+	err := d.tx.Select(&rows, "SELECT * FROM unit_training WHERE town_id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byTown := make(map[int64][]model.UnitTraining)
+	for _, row := range rows {
+		byTown[row.TownID] = append(byTown[row.TownID], row)
+	}
+
+	for i := range towns {
+		towns[i].Training = byTown[towns[i].ID]
+	}
+
+	return nil
+}
+
+// GetGarrisons - returns the garrisons of the towns, the towns without units are omitted
+func (d *DatabaseTransaction) GetGarrisons(townIDs []int64) (map[int64]model.Units, error) {
+	var rows []struct {
+		TownID int64 `db:"town_id"`
+		model.Units
+	}
+
+	err := d.tx.Select(&rows, "SELECT * FROM town_garrisons WHERE town_id = ANY($1)", pq.Array(townIDs))
+	if err != nil {
+		return nil, d.handleError(err)
+	}
+
+	result := make(map[int64]model.Units, len(rows))
+	for _, row := range rows {
+		if !row.Units.IsEmpty() {
+			result[row.TownID] = row.Units
+		}
+	}
+
+	return result, d.handleError(nil)
+}
+
+// AddGarrison - adds the units to the town garrison
+func (d *DatabaseTransaction) AddGarrison(townID int64, units model.Units) error {
+	_, err := d.tx.Exec(`INSERT INTO town_garrisons (town_id, spearmen, archers, cavalry) VALUES ($1, $2, $3, $4)
+    ON CONFLICT (town_id) DO UPDATE SET spearmen = town_garrisons.spearmen + $2, 
+    archers = town_garrisons.archers + $3, cavalry = town_garrisons.cavalry + $4`,
+		townID, units.Spearmen, units.Archers, units.Cavalry)
+	return d.handleError(err)
+}
+
+// TakeGarrison - removes the units from the town garrison, returns false if there are not enough units
+func (d *DatabaseTransaction) TakeGarrison(townID int64, units model.Units) (bool, error) {
+	result, err := d.tx.Exec(`UPDATE town_garrisons SET spearmen = spearmen - $2, archers = archers - $3, 
+    cavalry = cavalry - $4 WHERE town_id=$1 AND spearmen >= $2 AND archers >= $3 AND cavalry >= $4`,
+		townID, units.Spearmen, units.Archers, units.Cavalry)
+	if err != nil {
+		return false, d.handleError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddArmy(army model.Army) (id int64, err error) {
+	err = d.tx.Get(&id, `INSERT INTO armies 
+    (character_id, town_id, spearmen, archers, cavalry, x, y, destination_x, destination_y, is_moving, 
+    target_town_id, siege_ticks) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		army.CharacterID, army.TownID, army.Units.Spearmen, army.Units.Archers, army.Units.Cavalry,
+		army.Location.X, army.Location.Y, army.Destination.X, army.Destination.Y, army.IsMoving,
+		army.TargetTownID, army.SiegeTicks)
+	return id, d.handleError(err)
+}
+
+// GetArmy - returns the army and locks it until the end of the transaction
+func (d *DatabaseTransaction) GetArmy(id int64) (result model.Army, err error) {
+	err = d.tx.Get(&result, selectArmies+" WHERE a.id=$1 FOR UPDATE OF a", id)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) GetCharacterArmies(characterID int64) (result []model.Army, err error) {
+	err = d.tx.Select(&result, selectArmies+" WHERE a.character_id=$1 ORDER BY a.id", characterID)
+	return result, d.handleError(err)
+}
+
+// GetActiveArmies - returns the moving and besieging armies of the character
+// and locks them until the end of the transaction
+func (d *DatabaseTransaction) GetActiveArmies(characterID int64) (result []model.Army, err error) {
+	err = d.tx.Select(&result, selectArmies+
+		" WHERE a.character_id=$1 AND (a.is_moving OR a.target_town_id<>0) ORDER BY a.id FOR UPDATE OF a", characterID)
+	return result, d.handleError(err)
+}
+
+// GetArmiesNear - returns the armies of other characters within the radius from the location, the closest first.
+// The armies are locked until the end of the transaction
+func (d *DatabaseTransaction) GetArmiesNear(
+	location model.Vector2D, radius float64, excludedCharacterID int64) (result []model.Army, err error) {
+	err = d.tx.Select(&result, selectArmies+` 
+    WHERE a.character_id<>$4 AND a.x BETWEEN $1 - $3 AND $1 + $3 AND a.y BETWEEN $2 - $3 AND $2 + $3 
+    AND (a.x - $1) * (a.x - $1) + (a.y - $2) * (a.y - $2) <= $3 * $3 
+    ORDER BY (a.x - $1) * (a.x - $1) + (a.y - $2) * (a.y - $2), a.id FOR UPDATE OF a`,
+		location.X, location.Y, radius, excludedCharacterID)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) UpdateArmy(army model.Army) error {
+	_, err := d.tx.Exec(`UPDATE armies SET spearmen=$2, archers=$3, cavalry=$4, x=$5, y=$6, 
+    destination_x=$7, destination_y=$8, is_moving=$9, target_town_id=$10, siege_ticks=$11 WHERE id=$1`,
+		army.ID, army.Units.Spearmen, army.Units.Archers, army.Units.Cavalry,
+		army.Location.X, army.Location.Y, army.Destination.X, army.Destination.Y, army.IsMoving,
+		army.TargetTownID, army.SiegeTicks)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) DeleteArmy(id int64) error {
+	_, err := d.tx.Exec("DELETE FROM armies WHERE id=$1", id)
+	return d.handleError(err)
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"abbysoft/gardarike-online/model"
+	"time"
+)
+
+func (d *DatabaseTransaction) GetSanctions(characterID int64) (result model.Sanctions, err error) {
+	err = d.tx.Get(&result, "SELECT muted_until, banned_until FROM characters WHERE id=$1", characterID)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) SetMutedUntil(characterID int64, until time.Time) error {
+	_, err := d.tx.Exec("UPDATE characters SET muted_until=$2 WHERE id=$1", characterID, until)
+	return d.handleError(err)
+}
+
+func (d *DatabaseTransaction) SetBannedUntil(characterID int64, until time.Time) error {
+	_, err := d.tx.Exec("UPDATE characters SET banned_until=$2 WHERE id=$1", characterID, until)
+	return d.handleError(err)
+}
+
+// AddChatReport - adds the report, the message reported by the same character again is ignored
+func (d *DatabaseTransaction) AddChatReport(report model.ChatReport) error {
+	_, err := d.tx.Exec(`INSERT INTO chat_reports (message_id, reporter_id, reason, created_at) 
+    VALUES ($1, $2, $3, $4) ON CONFLICT (message_id, reporter_id) DO NOTHING`,
+		report.MessageID, report.ReporterID, report.Reason, report.Time)
+	return d.handleError(err)
+}
+
+// GetChatReports - returns the newest reports with the reported messages
+func (d *DatabaseTransaction) GetChatReports(count int) (result []model.ChatReport, err error) {
+	err = d.tx.Select(&result, `SELECT r.*, c.name reporter, 
+       m.message_id "message.id", m.sender_name "message.sender", m.text "message.text", 
+       m.channel "message.channel", m.recipient_name "message.recipient", m.region "message.region", 
+       m.created_at "message.created_at" 
+    FROM chat_reports r 
+    JOIN chat_messages m ON m.message_id = r.message_id 
+    JOIN characters c ON c.id = r.reporter_id 
+    ORDER BY r.id DESC LIMIT $1`, count)
+	return result, d.handleError(err)
+}
+
+func (d *DatabaseTransaction) AddModerationRecord(record model.ModerationRecord) error {
+	_, err := d.tx.Exec(`INSERT INTO moderation_log (moderator_id, target_id, action, until, reason, created_at) 
+    VALUES ($1, $2, $3, $4, $5, $6)`,
+		record.ModeratorID, record.TargetID, record.Action, record.Until, record.Reason, record.Time)
+	return d.handleError(err)
+}
+
+// GetModerationLog - returns the newest actions of the moderators
+func (d *DatabaseTransaction) GetModerationLog(count int) (result []model.ModerationRecord, err error) {
+	err = d.tx.Select(&result, `SELECT l.*, m.name moderator, t.name target FROM moderation_log l 
+    JOIN characters m ON m.id = l.moderator_id 
+    JOIN characters t ON t.id = l.target_id 
+    ORDER BY l.id DESC LIMIT $1`, count)
+	return result, d.handleError(err)
+}
@@ -81,20 +81,6 @@ func (s SimplexTerrainGenerator) GenerateTerrain(width, height int, offsetX, off
 					ny := (float64(y) + offsetY) / float64(height)
 
 					noiseVal := s.generator.Eval2(freq*nx, freq*ny)
-					if s.config.Debug {
-						log.WithFields(log.Fields{
-							"module":    "terrain_generator",
-							"noise":     noiseVal,
-							"x":         x,
-							"y":         y,
-							"amplitude": amplitude,
-							"freq":      freq,
-							"nx":        nx,
-							"ny":        ny,
-							"octave":    octave,
-						}).Debugf("Nose generated")
-					}
-
 					// Map noise from [-1;1] to [0;1)
 					noiseVal = (noiseVal + 1.0) / 2.0
 					noise += amplitude * noiseVal
@@ -137,5 +123,15 @@ func (s SimplexTerrainGenerator) GenerateTerrain(width, height int, offsetX, off
 		}
 	}
 
+	if s.config.Debug {
+		log.WithFields(log.Fields{
+			"module":  "terrain_generator",
+			"width":   width,
+			"height":  height,
+			"offsetX": offsetX,
+			"offsetY": offsetY,
+		}).Debug("Terrain generated")
+	}
+
 	return result
 }
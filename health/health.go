@@ -0,0 +1,184 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	defaultCheckTimeout = 3 * time.Second
+)
+
+// CheckFunc - returns non-nil error if the checked component isn't healthy
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	liveness bool
+	check    CheckFunc
+}
+
+// CheckResult - result of the single check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report - results of all checks of the probe, Status is ok only if all checks passed
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Checker - runs the registered checks for the liveness and readiness probes.
+// Liveness checks fail only if the server should be restarted (e.g. the game loop is stuck),
+// readiness checks fail if the server can't serve the players right now
+type Checker struct {
+	mutex   sync.RWMutex
+	checks  []check
+	timeout time.Duration
+}
+
+func NewChecker() *Checker {
+	return &Checker{timeout: defaultCheckTimeout}
+}
+
+// AddLivenessCheck - registers the check used by both liveness and readiness probes
+func (c *Checker) AddLivenessCheck(name string, checkFunc CheckFunc) {
+	c.add(check{name: name, liveness: true, check: checkFunc})
+}
+
+// AddReadinessCheck - registers the check used by the readiness probe only
+func (c *Checker) AddReadinessCheck(name string, checkFunc CheckFunc) {
+	c.add(check{name: name, liveness: false, check: checkFunc})
+}
+
+func (c *Checker) add(check check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.checks = append(c.checks, check)
+}
+
+// Liveness - runs the liveness checks
+func (c *Checker) Liveness(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+// Readiness - runs all checks
+func (c *Checker) Readiness(ctx context.Context) Report {
+	return c.run(ctx, false)
+}
+
+func (c *Checker) run(ctx context.Context, livenessOnly bool) Report {
+	c.mutex.RLock()
+	var checks []check
+	for _, check := range c.checks {
+		if check.liveness || !livenessOnly {
+			checks = append(checks, check)
+		}
+	}
+	c.mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// Checks run in parallel, so the slow database doesn't delay the other checks
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		i, check := i, check
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.check(ctx)
+
+			result := CheckResult{Name: check.name, Status: StatusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// LivenessHandler - responds with 200 if the liveness checks passed, otherwise with 503
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Liveness(r.Context()))
+	})
+}
+
+// ReadinessHandler - responds with 200 if all checks passed, otherwise with 503
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Readiness(r.Context()))
+	})
+}
+
+// Probe - requests the probe at the endpoint (e.g. 127.0.0.1:8502), returns an error if the server is unhealthy
+func Probe(endpoint, path string, timeout time.Duration) (Report, error) {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+
+	client := http.Client{Timeout: timeout}
+	response, err := client.Get(strings.TrimSuffix(endpoint, "/") + path)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to request health probe: %w", err)
+	}
+	defer response.Body.Close()
+
+	var report Report
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		return report, fmt.Errorf("failed to decode health report (status %d): %w", response.StatusCode, err)
+	}
+
+	if response.StatusCode != http.StatusOK || !report.Healthy() {
+		return report, fmt.Errorf("server is unhealthy (status %d)", response.StatusCode)
+	}
+
+	return report, nil
+}
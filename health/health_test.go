@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestChecker(databaseErr error) *Checker {
+	checker := NewChecker()
+	checker.AddLivenessCheck("gameLoop", func(ctx context.Context) error {
+		return nil
+	})
+	checker.AddReadinessCheck("database", func(ctx context.Context) error {
+		return databaseErr
+	})
+
+	return checker
+}
+
+func TestChecker_Liveness(t *testing.T) {
+	report := newTestChecker(fmt.Errorf("connection refused")).Liveness(context.Background())
+
+	require.True(t, report.Healthy())
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, "gameLoop", report.Checks[0].Name)
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	report := newTestChecker(fmt.Errorf("connection refused")).Readiness(context.Background())
+
+	require.False(t, report.Healthy())
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, StatusOK, report.Checks[0].Status)
+	require.Equal(t, StatusFail, report.Checks[1].Status)
+	require.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, newTestChecker(fmt.Errorf("connection refused")).LivenessHandler())
+	mux.Handle(ReadinessPath, newTestChecker(fmt.Errorf("connection refused")).ReadinessHandler())
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	report, err := Probe(server.URL, LivenessPath, time.Second)
+	require.NoError(t, err)
+	require.True(t, report.Healthy())
+
+	report, err = Probe(server.URL, ReadinessPath, time.Second)
+	require.Error(t, err)
+	require.Equal(t, StatusFail, report.Status)
+}
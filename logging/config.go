@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Config struct {
+	Level      string            // Default level: trace, debug, info, warning, error
+	Format     string            // "text" or "json"
+	File       string            // Writes logs to the file instead of stdout if set
+	MaxSize    int               // Megabytes the log file can grow before it's rotated
+	MaxAge     int               // Days to keep the rotated files, 0 keeps them forever
+	MaxBackups int               // Number of the rotated files to keep, 0 keeps all of them
+	Modules    map[string]string // Levels by the 'module' field overriding the default level
+	Sampling   SamplingConfig
+}
+
+// SamplingConfig - limits the messages starting with one of the prefixes to the first 'First'
+// messages in every 'Interval', after that only every 'Thereafter' message is logged
+type SamplingConfig struct {
+	Messages   []string
+	Interval   time.Duration
+	First      int
+	Thereafter int
+}
+
+func parseLevel(level string) (log.Level, error) {
+	if level == "" {
+		return log.InfoLevel, nil
+	}
+
+	return log.ParseLevel(level)
+}
+
+func newFormatter(format string) (log.Formatter, error) {
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		return &log.TextFormatter{FullTimestamp: true}, nil
+	case FormatJSON:
+		return &log.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+func newOutput(config Config) io.Writer {
+	if config.File == "" {
+		return os.Stdout
+	}
+
+	return &lumberjack.Logger{
+		Filename:   config.File,
+		MaxSize:    config.MaxSize,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+	}
+}
+
+// Setup - configures the standard logrus logger, debug targets enabled before are kept
+func Setup(config Config) error {
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+
+	modules := make(map[string]log.Level, len(config.Modules))
+	for module, value := range config.Modules {
+		moduleLevel, err := parseLevel(value)
+		if err != nil {
+			return fmt.Errorf("invalid log level of module %s: %w", module, err)
+		}
+
+		modules[strings.ToLower(module)] = moduleLevel
+	}
+
+	formatter, err := newFormatter(config.Format)
+	if err != nil {
+		return err
+	}
+
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+
+	filter.level = level
+	filter.modules = modules
+	filter.sampler = newSampler(config.Sampling)
+	filter.configured = true
+
+	log.SetFormatter(&filteringFormatter{formatter: formatter})
+	log.SetOutput(newOutput(config))
+	updateLoggerLevel()
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package logging
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	moduleField = "module"
+)
+
+// DebugTargetFields - log fields which can be used to enable debug logging at runtime
+var DebugTargetFields = []string{"requestName", "sessionID", "accountID", "character"}
+
+// DebugTarget - entries having the field with the value are logged at debug level
+// regardless of the configured levels, e.g. all requests of the single session
+type DebugTarget struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (t DebugTarget) matches(entry *log.Entry) bool {
+	value, found := entry.Data[t.Field]
+	return found && fmt.Sprint(value) == t.Value
+}
+
+type levelFilter struct {
+	configured   bool
+	level        log.Level
+	modules      map[string]log.Level
+	debugTargets map[DebugTarget]struct{}
+	sampler      *sampler
+}
+
+var (
+	filterMutex sync.RWMutex
+	filter      = levelFilter{
+		level:        log.InfoLevel,
+		debugTargets: make(map[DebugTarget]struct{}),
+	}
+)
+
+// updateLoggerLevel - sets the logrus level to the most verbose configured level,
+// so the messages aren't formatted if no one needs them. Should be called with the filter mutex locked
+func updateLoggerLevel() {
+	if !filter.configured {
+		return
+	}
+
+	level := filter.level
+	for _, moduleLevel := range filter.modules {
+		if moduleLevel > level {
+			level = moduleLevel
+		}
+	}
+
+	if len(filter.debugTargets) > 0 && level < log.DebugLevel {
+		level = log.DebugLevel
+	}
+
+	log.SetLevel(level)
+}
+
+// Enabled - checks the entry level against the module level and the debug targets.
+// All entries are enabled until Setup is called
+func Enabled(entry *log.Entry) bool {
+	filterMutex.RLock()
+	defer filterMutex.RUnlock()
+
+	return filter.enabled(entry)
+}
+
+func (f *levelFilter) enabled(entry *log.Entry) bool {
+	if !f.configured {
+		return true
+	}
+
+	level := f.level
+	if module, ok := entry.Data[moduleField].(string); ok {
+		if moduleLevel, found := f.modules[strings.ToLower(module)]; found {
+			level = moduleLevel
+		}
+	}
+
+	if entry.Level <= level {
+		return true
+	}
+
+	if entry.Level <= log.DebugLevel {
+		for target := range f.debugTargets {
+			if target.matches(entry) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SetDebugTarget - enables or disables debug logging for the entries matching the target
+func SetDebugTarget(target DebugTarget, enabled bool) error {
+	valid := false
+	for _, field := range DebugTargetFields {
+		valid = valid || field == target.Field
+	}
+
+	if !valid {
+		return fmt.Errorf("unsupported debug field %q, expected one of %s",
+			target.Field, strings.Join(DebugTargetFields, ", "))
+	}
+
+	if target.Value == "" {
+		return fmt.Errorf("debug target value is required")
+	}
+
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+
+	if enabled {
+		filter.debugTargets[target] = struct{}{}
+	} else {
+		delete(filter.debugTargets, target)
+	}
+
+	updateLoggerLevel()
+	return nil
+}
+
+// DebugTargets - returns targets with debug logging enabled
+func DebugTargets() []DebugTarget {
+	filterMutex.RLock()
+	defer filterMutex.RUnlock()
+
+	result := make([]DebugTarget, 0, len(filter.debugTargets))
+	for target := range filter.debugTargets {
+		result = append(result, target)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Field != result[j].Field {
+			return result[i].Field < result[j].Field
+		}
+		return result[i].Value < result[j].Value
+	})
+
+	return result
+}
+
+// filteringFormatter - drops the filtered entries, logrus doesn't write empty output
+type filteringFormatter struct {
+	formatter log.Formatter
+}
+
+func (f *filteringFormatter) Format(entry *log.Entry) ([]byte, error) {
+	filterMutex.RLock()
+	enabled := filter.enabled(entry) && filter.sampler.allow(entry)
+	filterMutex.RUnlock()
+
+	if !enabled {
+		return nil, nil
+	}
+
+	return f.formatter.Format(entry)
+}
+
+type samplerCounter struct {
+	start time.Time
+	count int
+}
+
+type sampler struct {
+	config   SamplingConfig
+	mutex    sync.Mutex
+	counters map[string]*samplerCounter
+}
+
+func newSampler(config SamplingConfig) *sampler {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+
+	return &sampler{
+		config:   config,
+		counters: make(map[string]*samplerCounter),
+	}
+}
+
+// allow - returns false for the sampled out messages, warnings and errors are never sampled
+func (s *sampler) allow(entry *log.Entry) bool {
+	if s == nil || entry.Level <= log.WarnLevel {
+		return true
+	}
+
+	prefix := ""
+	for _, message := range s.config.Messages {
+		if strings.HasPrefix(entry.Message, message) {
+			prefix = message
+			break
+		}
+	}
+
+	if prefix == "" {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter, found := s.counters[prefix]
+	if !found || entry.Time.Sub(counter.start) >= s.config.Interval {
+		counter = &samplerCounter{start: entry.Time}
+		s.counters[prefix] = counter
+	}
+
+	counter.count++
+	if counter.count <= s.config.First {
+		return true
+	}
+
+	return s.config.Thereafter > 0 && (counter.count-s.config.First)%s.config.Thereafter == 0
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newEntry(level log.Level, message string, fields log.Fields) *log.Entry {
+	entry := log.WithFields(fields)
+	entry.Level = level
+	entry.Message = message
+	entry.Time = time.Now()
+	return entry
+}
+
+func TestLevelFilter_ModuleLevels(t *testing.T) {
+	filter := levelFilter{
+		configured:   true,
+		level:        log.InfoLevel,
+		modules:      map[string]log.Level{"terrain_generator": log.DebugLevel, "packet_handler": log.WarnLevel},
+		debugTargets: make(map[DebugTarget]struct{}),
+	}
+
+	assert.True(t, filter.enabled(newEntry(log.InfoLevel, "Login request", log.Fields{"module": "logic"})))
+	assert.False(t, filter.enabled(newEntry(log.DebugLevel, "Login request", log.Fields{"module": "logic"})))
+	assert.True(t, filter.enabled(newEntry(log.DebugLevel, "Terrain generated", log.Fields{"module": "terrain_generator"})))
+	assert.False(t, filter.enabled(newEntry(log.InfoLevel, "Sending error response", log.Fields{"module": "packet_handler"})))
+	assert.True(t, filter.enabled(newEntry(log.ErrorLevel, "Failed to commit transaction", log.Fields{"module": "packet_handler"})))
+}
+
+func TestLevelFilter_DebugTargets(t *testing.T) {
+	filter := levelFilter{
+		configured: true,
+		level:      log.InfoLevel,
+		debugTargets: map[DebugTarget]struct{}{
+			{Field: "sessionID", Value: "session"}: {},
+			{Field: "accountID", Value: "10"}:      {},
+		},
+	}
+
+	assert.True(t, filter.enabled(newEntry(log.DebugLevel, "Chunk generated", log.Fields{"sessionID": "session"})))
+	assert.True(t, filter.enabled(newEntry(log.DebugLevel, "Chunk generated", log.Fields{"accountID": int64(10)})))
+	assert.False(t, filter.enabled(newEntry(log.DebugLevel, "Chunk generated", log.Fields{"sessionID": "other"})))
+	assert.False(t, filter.enabled(newEntry(log.TraceLevel, "Chunk generated", log.Fields{"sessionID": "session"})))
+}
+
+func TestSampler(t *testing.T) {
+	sampler := newSampler(SamplingConfig{
+		Messages:   []string{"Sending *rpc."},
+		Interval:   time.Minute,
+		First:      2,
+		Thereafter: 3,
+	})
+
+	var allowed []bool
+	for i := 0; i < 8; i++ {
+		allowed = append(allowed, sampler.allow(newEntry(log.InfoLevel, "Sending *rpc.Response_LoginResponse response", nil)))
+	}
+
+	assert.Equal(t, []bool{true, true, false, false, true, false, false, true}, allowed)
+	assert.True(t, sampler.allow(newEntry(log.InfoLevel, "Login request", nil)))
+	assert.True(t, sampler.allow(newEntry(log.WarnLevel, "Sending *rpc.Response_ErrorResponse response", nil)))
+
+	later := newEntry(log.InfoLevel, "Sending *rpc.Response_LoginResponse response", nil)
+	later.Time = later.Time.Add(time.Minute)
+	assert.True(t, sampler.allow(later))
+}
+
+func TestSetDebugTarget(t *testing.T) {
+	target := DebugTarget{Field: "character", Value: "Rurik"}
+
+	assert.Error(t, SetDebugTarget(DebugTarget{Field: "password", Value: "secret"}, true))
+	assert.Error(t, SetDebugTarget(DebugTarget{Field: "character"}, true))
+
+	assert.NoError(t, SetDebugTarget(target, true))
+	assert.Equal(t, []DebugTarget{target}, DebugTargets())
+
+	assert.NoError(t, SetDebugTarget(target, false))
+	assert.Empty(t, DebugTargets())
+}
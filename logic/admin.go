@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/model"
+	"context"
+	"fmt"
+)
+
+// Database - returns the database used by the logic
+func (s *SimpleLogic) Database() db.Database {
+	return s.db
+}
+
+// UpdateMapResources - runs the map resources update immediately
+func (s *SimpleLogic) UpdateMapResources(ctx context.Context) {
+	s.resourceManager.Update(ctx)
+}
+
+// EditCharacter - applies the update to the character and saves it.
+// If the character is selected in some session, the session state is updated as well,
+// so the game loop won't overwrite the changes.
+func (s *SimpleLogic) EditCharacter(ctx context.Context, characterID int64, update func(character *model.Character)) (model.Character, error) {
+	session := s.findCharacterSession(func(character *model.Character) bool {
+		return character.ID == characterID
+	})
+
+	if session != nil {
+		session.Mutex.Lock()
+		defer session.Mutex.Unlock()
+
+		if session.SelectedCharacter != nil && session.SelectedCharacter.ID == characterID {
+			tx, err := s.db.BeginTransaction(ctx, false, true)
+			if err != nil {
+				return model.Character{}, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+
+			update(session.SelectedCharacter)
+
+			if err := tx.UpdateCharacter(*session.SelectedCharacter); err != nil {
+				return model.Character{}, fmt.Errorf("failed to update character: %w", err)
+			}
+
+			return *session.SelectedCharacter, tx.EndTransaction()
+		}
+	}
+
+	tx, err := s.db.BeginTransaction(ctx, false, true)
+	if err != nil {
+		return model.Character{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	character, err := tx.GetCharacter(characterID)
+	if err != nil {
+		return model.Character{}, fmt.Errorf("failed to get character: %w", err)
+	}
+
+	update(&character)
+
+	if err := tx.UpdateCharacter(character); err != nil {
+		return model.Character{}, fmt.Errorf("failed to update character: %w", err)
+	}
+
+	return character, tx.EndTransaction()
+}
+
+// EditTown - applies the update to the town and saves it.
+// Towns of the character selected in some session are reloaded after the update.
+func (s *SimpleLogic) EditTown(ctx context.Context, townID int64, update func(town *model.Town)) (model.Town, error) {
+	tx, err := s.db.BeginTransaction(ctx, false, true)
+	if err != nil {
+		return model.Town{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	town, err := tx.GetTown(townID)
+	if err != nil {
+		return model.Town{}, fmt.Errorf("failed to get town: %w", err)
+	}
+
+	previousOwner := town.OwnerName
+	update(&town)
+	town.ID = townID
+
+	if town.OwnerName != previousOwner {
+		if err := tx.SetTownOwner(townID, town.OwnerName); err != nil {
+			return model.Town{}, fmt.Errorf("failed to change town owner: %w", err)
+		}
+	}
+
+	if err := tx.UpdateTown(town); err != nil {
+		return model.Town{}, fmt.Errorf("failed to update town: %w", err)
+	}
+
+	if err := tx.EndTransaction(); err != nil {
+		return model.Town{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, owner := range []string{previousOwner, town.OwnerName} {
+		if err := s.reloadCharacterTowns(ctx, owner); err != nil {
+			return town, err
+		}
+	}
+
+	return town, nil
+}
+
+func (s *SimpleLogic) reloadCharacterTowns(ctx context.Context, characterName string) error {
+	session := s.findCharacterSession(func(character *model.Character) bool {
+		return character.Name == characterName
+	})
+
+	if session == nil {
+		return nil
+	}
+
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	if session.SelectedCharacter == nil || session.SelectedCharacter.Name != characterName {
+		return nil
+	}
+
+	tx, err := s.db.BeginTransaction(ctx, false, true)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	towns, err := tx.GetTowns(characterName)
+	if err != nil {
+		return fmt.Errorf("failed to reload character towns: %w", err)
+	}
+
+	session.SelectedCharacter.Towns = towns
+
+	return tx.EndTransaction()
+}
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) AnswerDiplomacyProposal(ctx context.Context, session *PlayerSession, request *rpc.AnswerDiplomacyProposalRequest) (*rpc.AnswerDiplomacyProposalResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":  session.SessionID,
+		"proposalID": request.ProposalID,
+		"accept":     request.Accept,
+	}).Info("AnswerDiplomacyProposal")
+
+	character := session.SelectedCharacter
+	tx := session.Tx
+
+	tx.SetAutoRollBack(false)
+	proposal, err := tx.GetDiplomacyProposal(request.ProposalID)
+	tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrProposalNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomacy proposal")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Only the proposals sent to the player can be answered
+	if proposal.ToID != character.ID {
+		return nil, model.ErrProposalNotFound
+	}
+
+	if request.Accept {
+		if err := s.changeDiplomaticState(ctx, session, proposal.FromID, proposal.From, proposal.State); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to change diplomatic state")
+			return nil, model.ErrInternalServerError
+		}
+
+		return &rpc.AnswerDiplomacyProposalResponse{State: proposal.State}, nil
+	}
+
+	if err := tx.DeleteDiplomacyProposal(proposal.ID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to delete diplomacy proposal")
+		return nil, model.ErrInternalServerError
+	}
+
+	current, err := tx.GetDiplomaticState(character.ID, proposal.FromID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomatic state")
+		return nil, model.ErrInternalServerError
+	}
+
+	s.publishEvent(ctx, model.NewDiplomacyEvent(
+		proposal.FromID, rpc.DiplomacyEvent_REJECTED, character.Name, current, &proposal))
+
+	return &rpc.AnswerDiplomacyProposalResponse{State: current}, nil
+}
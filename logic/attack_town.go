@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+func (s *SimpleLogic) AttackTown(ctx context.Context, session *PlayerSession, request *rpc.AttackTownRequest) (*rpc.AttackTownResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"armyID":    request.ArmyID,
+		"townID":    request.TownID,
+	}).Info("AttackTown")
+
+	character := session.SelectedCharacter
+	tx := session.Tx
+
+	army, modelErr := s.characterArmy(ctx, session, request.ArmyID)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+
+	tx.SetAutoRollBack(false)
+	town, err := tx.GetTown(request.TownID)
+	tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrTownNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get town")
+		return nil, model.ErrInternalServerError
+	}
+
+	if town.OwnerName == character.Name {
+		return nil, model.ErrBadRequest
+	}
+
+	defenderID, err := tx.GetCharacterID(town.OwnerName)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get town owner")
+		return nil, model.ErrInternalServerError
+	}
+
+	if war, err := atWar(tx, character.ID, defenderID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to check relations with town owner")
+		return nil, model.ErrInternalServerError
+	} else if !war {
+		return nil, model.ErrNotAtWar
+	}
+
+	defender, err := tx.GetCharacter(defenderID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get town owner")
+		return nil, model.ErrInternalServerError
+	}
+
+	now := time.Now()
+	if defender.IsProtected(now) {
+		return nil, model.ErrTownProtected
+	}
+
+	army.AttackTown(town)
+
+	if err := tx.UpdateArmy(army); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update army")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Attacking ends the protection of the new player, it's saved with the character by the game loop
+	if character.IsProtected(now) {
+		character.ProtectedUntil = now
+	}
+
+	return &rpc.AttackTownResponse{Army: army.ToRPC()}, nil
+}
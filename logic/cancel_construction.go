@@ -0,0 +1,49 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) CancelConstruction(ctx context.Context, session *PlayerSession, request *rpc.CancelConstructionRequest) (*rpc.CancelConstructionResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":      session.SessionID,
+		"townID":         request.TownID,
+		"constructionID": request.ConstructionID,
+	}).Info("CancelConstruction")
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	index := town.FindConstruction(request.ConstructionID)
+	if index < 0 {
+		return nil, model.ErrConstructionNotFound
+	}
+
+	construction := town.Construction[index]
+	refund := construction.Refund()
+
+	updated := *town
+	updated.Construction = append(append([]model.Construction{}, town.Construction[:index]...), town.Construction[index+1:]...)
+	updated.Store(refund)
+
+	if err := session.Tx.DeleteConstruction(construction.ID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to delete construction")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	*town = updated
+
+	return &rpc.CancelConstructionResponse{
+		Refund: refund.ToRPC(),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) CancelMarketOrder(ctx context.Context, session *PlayerSession, request *rpc.CancelMarketOrderRequest) (*rpc.CancelMarketOrderResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"orderID":   request.OrderID,
+	}).Info("CancelMarketOrder")
+
+	character := session.SelectedCharacter
+
+	order, err := session.Tx.GetMarketOrder(request.OrderID)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrOrderNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get market order")
+		return nil, model.ErrInternalServerError
+	}
+
+	if order.CharacterID != character.ID {
+		return nil, model.ErrOrderNotFound
+	}
+
+	// The escrow of the order placed in a lost town is returned to the capital
+	town := character.Town(order.TownID)
+	if town == nil {
+		town = character.Capital()
+	}
+
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	refund := order.Escrow()
+
+	updated := *town
+	overflow := updated.Store(refund)
+
+	if err := session.Tx.DeleteMarketOrder(order.ID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to delete market order")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	// The refund exceeding the storage limit waits for the free space
+	if !overflow.IsEmpty() {
+		if err := session.Tx.AddDelivery(model.Delivery{TownID: town.ID, Resources: overflow}); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to add refund delivery")
+			return nil, model.ErrInternalServerError
+		}
+	}
+
+	*town = updated
+
+	return &rpc.CancelMarketOrderResponse{
+		Refund: refund.ToRPC(),
+	}, nil
+}
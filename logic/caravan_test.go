@@ -0,0 +1,275 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_SendCaravan(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 10
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, X: 0, Y: 0, OwnerName: "sender", Resources: model.Resources{Wood: 100, Gold: 50}},
+		model.Town{ID: 2, X: 10, Y: 0, OwnerName: "sender"},
+	)
+
+	// No generated chunks on the way, the terrain is flat
+	db.On("GetMapChunk", mock.Anything, mock.Anything, mock.Anything).Return(model.WorldMapChunk{}, sql.ErrNoRows)
+
+	caravan := model.Caravan{
+		CharacterID:   1,
+		Owner:         "sender",
+		ToCharacterID: 1,
+		FromTownID:    1,
+		ToTownID:      2,
+		From:          model.Vector2D{},
+		To:            model.Vector2D{X: 10},
+		Resources:     model.Resources{Wood: 60},
+		TravelTime:    5,
+		TicksLeft:     5,
+	}
+	db.On("AddCaravan", caravan).Return(int64(7), nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1 && town.Resources.Wood == 40
+	})).Return(nil)
+
+	resp, err := logic.SendCaravan(context.Background(), session, &rpc.SendCaravanRequest{
+		ToTownID:  2,
+		Resources: &rpc.Resources{Wood: 60},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), resp.Caravan.Id)
+	require.Equal(t, uint64(5), resp.Caravan.TicksLeft)
+	require.Equal(t, float32(0), resp.Caravan.Location.X)
+
+	require.Equal(t, uint64(40), session.SelectedCharacter.Towns[0].Resources.Wood)
+	require.Len(t, session.SelectedCharacter.Caravans, 1)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_SendCaravan_Errors(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, X: 0, Y: 0, OwnerName: "sender", Resources: model.Resources{Wood: 100, Gold: 50}},
+		model.Town{ID: 2, X: 10, Y: 0, OwnerName: "sender"},
+	)
+
+	send := func(request *rpc.SendCaravanRequest) model.Error {
+		_, err := logic.SendCaravan(context.Background(), session, request)
+		return err
+	}
+
+	db.On("GetTown", int64(3)).Return(model.Town{}, sql.ErrNoRows)
+	db.On("GetTowns", "nobody").Return([]model.Town{}, nil)
+
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToTownID: 2}), model.ErrBadRequest.Error())
+	require.EqualError(t, send(&rpc.SendCaravanRequest{Resources: &rpc.Resources{Wood: 1}}),
+		model.ErrBadRequest.Error())
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToTownID: 1, Resources: &rpc.Resources{Wood: 1}}),
+		model.ErrBadRequest.Error())
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToTownID: 3, Resources: &rpc.Resources{Wood: 1}}),
+		model.ErrTownNotFound.Error())
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToCharacterName: "nobody", Resources: &rpc.Resources{Wood: 1}}),
+		model.ErrTownNotFound.Error())
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToTownID: 2, Resources: &rpc.Resources{Stone: 1}}),
+		model.ErrNotEnoughResources.Error())
+
+	session.SelectedCharacter.Caravans = make([]model.Caravan, model.MaxCaravans)
+	require.EqualError(t, send(&rpc.SendCaravanRequest{ToTownID: 2, Resources: &rpc.Resources{Wood: 1}}),
+		model.ErrCaravanLimitReached.Error())
+}
+
+func TestSimpleLogic_SendCaravan_ToPlayer(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 10
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, X: 0, Y: 0, OwnerName: "sender", Resources: model.Resources{Wood: 100, Gold: 50}},
+		model.Town{ID: 2, X: 10, Y: 0, OwnerName: "sender"},
+	)
+
+	db.On("GetTowns", "recipient").Return([]model.Town{
+		{ID: 20, X: 0, Y: 20, OwnerName: "recipient"},
+		{ID: 21, X: 50, Y: 50, OwnerName: "recipient"},
+	}, nil)
+	db.On("GetCharacterID", "recipient").Return(int64(2), nil)
+	db.On("GetMapChunk", mock.Anything, mock.Anything, mock.Anything).Return(model.WorldMapChunk{}, sql.ErrNoRows)
+	db.On("AddCaravan", mock.MatchedBy(func(caravan model.Caravan) bool {
+		return caravan.ToTownID == 20 && caravan.ToCharacterID == 2 && caravan.TravelTime == 10
+	})).Return(int64(1), nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	// The caravan goes to the capital of the player
+	resp, err := logic.SendCaravan(context.Background(), session, &rpc.SendCaravanRequest{
+		ToCharacterName: "recipient",
+		Resources:       &rpc.Resources{Gold: 50},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), resp.Caravan.ToTownID)
+	require.Equal(t, uint64(0), session.SelectedCharacter.Towns[0].Resources.Gold)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CaravanTravelTime(t *testing.T) {
+	from, to := model.Vector2D{}, model.Vector2D{X: 20}
+	flat := func(x, y int) (float32, bool) {
+		return 1, true
+	}
+	require.Equal(t, uint64(10), model.CaravanTravelTime(from, to, flat, 0.5))
+
+	// Half of the way is under the water
+	lake := func(x, y int) (float32, bool) {
+		if x >= 10 {
+			return 0, true
+		}
+		return 1, true
+	}
+	require.Greater(t, model.CaravanTravelTime(from, to, lake, 0.5), uint64(20))
+
+	// The way up and down the hill costs more than the flat way
+	hill := func(x, y int) (float32, bool) {
+		return float32(10-abs(x-10)) * 0.1, true
+	}
+	require.Equal(t, uint64(30), model.CaravanTravelTime(from, to, hill, 0))
+
+	// The caravan is at the middle of the way after a half of the travel time
+	caravan := model.Caravan{From: from, To: to, TravelTime: 10, TicksLeft: 10}
+	for i := 0; i < 5; i++ {
+		require.False(t, caravan.Advance())
+	}
+	require.Equal(t, model.Vector2D{X: 10}, caravan.Location())
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestSimpleLogic_UpdateCaravans(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, X: 0, Y: 0, OwnerName: "sender", Resources: model.Resources{Wood: 100, Gold: 50}},
+		model.Town{ID: 2, X: 10, Y: 0, OwnerName: "sender"},
+	)
+	session.SelectedCharacter.Caravans = []model.Caravan{
+		{ID: 1, CharacterID: 1, ToCharacterID: 1, FromTownID: 1, ToTownID: 2,
+			Resources: model.Resources{Wood: 30}, TravelTime: 5, TicksLeft: 1},
+		{ID: 2, CharacterID: 1, ToCharacterID: 2, FromTownID: 1, ToTownID: 20,
+			Resources: model.Resources{Gold: 40}, TravelTime: 5, TicksLeft: 1},
+		{ID: 3, CharacterID: 1, ToCharacterID: 1, FromTownID: 2, ToTownID: 1, TravelTime: 5, TicksLeft: 3},
+	}
+
+	db.On("DeleteCaravan", int64(1)).Return(int64(2), nil)
+	db.On("AddDelivery", model.Delivery{TownID: 20, Resources: model.Resources{Gold: 40}}).Return(nil)
+	db.On("DeleteCaravan", int64(2)).Return(int64(20), nil)
+	db.On("UpdateCaravan", mock.MatchedBy(func(caravan model.Caravan) bool {
+		return caravan.ID == 3 && caravan.TicksLeft == 2
+	})).Return(nil)
+
+	logic.updateCaravans(context.Background(), session)
+
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(30), character.Towns[1].Resources.Wood)
+	require.Len(t, character.Caravans, 1)
+	require.Equal(t, uint64(2), character.Caravans[0].TicksLeft)
+
+	// The owner gets the events about both caravans, the other player about its own one
+	require.Len(t, logic.EventsChan, 3)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.Equal(t, int64(1), event.Event.GetCaravanArrivedEvent().Caravan.Id)
+
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, int64(2), event.Event.GetCaravanArrivedEvent().Caravan.Id)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateCaravans_Failed(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, X: 0, Y: 0, OwnerName: "sender", Resources: model.Resources{Wood: 100, Gold: 50}},
+		model.Town{ID: 2, X: 10, Y: 0, OwnerName: "sender"},
+	)
+	caravans := []model.Caravan{
+		{ID: 1, CharacterID: 1, ToCharacterID: 1, FromTownID: 1, ToTownID: 2,
+			Resources: model.Resources{Wood: 30}, TravelTime: 5, TicksLeft: 1},
+		{ID: 2, CharacterID: 1, ToCharacterID: 1, FromTownID: 2, ToTownID: 1, TravelTime: 5, TicksLeft: 3},
+	}
+	session.SelectedCharacter.Caravans = caravans
+
+	db.On("DeleteCaravan", int64(1)).Return(int64(2), nil)
+	db.On("UpdateCaravan", mock.Anything).Return(sql.ErrConnDone)
+
+	logic.updateCaravans(context.Background(), session)
+
+	// The arrived caravan isn't stored to the town until all caravans are saved
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(0), character.Towns[1].Resources.Wood)
+	require.Equal(t, caravans, character.Caravans)
+	require.Empty(t, logic.EventsChan)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateCaravans_StorageFull(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("sender",
+		model.Town{ID: 1, OwnerName: "sender"},
+		model.Town{ID: 2, X: 10, OwnerName: "sender", Resources: model.Resources{Wood: 1990}},
+	)
+	session.SelectedCharacter.Caravans = []model.Caravan{
+		{ID: 1, CharacterID: 1, ToCharacterID: 1, FromTownID: 1, ToTownID: 2,
+			Resources: model.Resources{Wood: 30, Gold: 40}, TravelTime: 5, TicksLeft: 1},
+	}
+
+	db.On("DeleteCaravan", int64(1)).Return(int64(2), nil)
+	db.On("AddDelivery", model.Delivery{TownID: 2, Resources: model.Resources{Wood: 20}}).Return(nil)
+
+	logic.updateCaravans(context.Background(), session)
+
+	// The goods exceeding the storage limit are delivered when the town has free space
+	town := session.SelectedCharacter.Towns[1]
+	require.Equal(t, model.Resources{Wood: 2000, Gold: 40}, town.Resources)
+	require.Len(t, logic.EventsChan, 1)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateCaravans_TownCaptured(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("sender", model.Town{ID: 1, OwnerName: "sender"})
+	session.SelectedCharacter.Caravans = []model.Caravan{
+		{ID: 1, CharacterID: 1, ToCharacterID: 2, FromTownID: 1, ToTownID: 5,
+			Resources: model.Resources{Wood: 30}, TravelTime: 5, TicksLeft: 1},
+		{ID: 2, CharacterID: 1, ToCharacterID: 3, FromTownID: 1, ToTownID: 6,
+			Resources: model.Resources{Stone: 30}, TravelTime: 5, TicksLeft: 1},
+	}
+
+	// The town is captured on the way, so the goods go to the capital of the recipient
+	db.On("DeleteCaravan", int64(1)).Return(int64(9), nil)
+	db.On("AddDelivery", model.Delivery{TownID: 9, Resources: model.Resources{Wood: 30}}).Return(nil)
+
+	// The recipient lost its last town, so the goods are lost
+	db.On("DeleteCaravan", int64(2)).Return(int64(0), sql.ErrNoRows)
+
+	logic.updateCaravans(context.Background(), session)
+
+	require.Empty(t, session.SelectedCharacter.Caravans)
+	require.Len(t, logic.EventsChan, 2)
+	event := <-logic.EventsChan
+	require.Equal(t, int64(9), event.Event.GetCaravanArrivedEvent().Caravan.ToTownID)
+
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,138 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/model/consts"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// floorDiv - returns the quotient rounded towards negative infinity
+func floorDiv(a, b int) int {
+	result := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		result--
+	}
+
+	return result
+}
+
+// caravanTravelTime - returns ticks the caravan needs to go between the points over the terrain
+// of the global map chunks, the chunks not generated yet are considered flat
+func (s *SimpleLogic) caravanTravelTime(tx db.DatabaseTransaction, from, to model.Vector2D) (uint64, error) {
+	size := s.MapChunkSize()
+	chunks := make(map[[2]int]*rpc.WorldMapChunk)
+
+	var loadErr error
+	height := func(x, y int) (float32, bool) {
+		key := [2]int{floorDiv(x, size), floorDiv(y, size)}
+
+		chunk, found := chunks[key]
+		if !found {
+			tx.SetAutoRollBack(false)
+			modelChunk, err := tx.GetMapChunk(int64(key[0]), int64(key[1]), consts.GlobalChunkNumber)
+			tx.SetAutoRollBack(true)
+
+			if err == nil && len(modelChunk.Data) > 0 {
+				chunk, err = modelChunk.ToRPC()
+			}
+
+			if err != nil && !errors.Is(err, sql.ErrNoRows) && loadErr == nil {
+				loadErr = fmt.Errorf("failed to get map chunk: %w", err)
+			}
+
+			chunks[key] = chunk
+		}
+
+		localX, localY := x-key[0]*size, y-key[1]*size
+		if chunk == nil || localY+localX*size >= len(chunk.Data) {
+			return 0, false
+		}
+
+		return s.getMapChunkHeightAt(chunk, localX, localY), true
+	}
+
+	travelTime := model.CaravanTravelTime(from, to, height, s.config.WaterLevel)
+	return travelTime, loadErr
+}
+
+// updateCaravans - moves the caravans of the character. The arrived caravans store the resources
+// to the towns of the character directly and to the towns of other players through the deliveries,
+// both the owner and the recipient get an event about the arrival. The character is changed only
+// after all caravans are saved, the goods exceeding the storage limit are left for delivery
+func (s *SimpleLogic) updateCaravans(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+	if len(character.Caravans) == 0 {
+		return
+	}
+
+	onTheWay := make([]model.Caravan, 0, len(character.Caravans))
+	var arrived []model.Caravan
+	for _, caravan := range character.Caravans {
+		logger := s.logger(ctx).WithField("caravanID", caravan.ID)
+
+		if !caravan.Advance() {
+			if err := session.Tx.UpdateCaravan(caravan); err != nil {
+				logger.WithError(err).Error("Failed to update caravan")
+				return
+			}
+
+			onTheWay = append(onTheWay, caravan)
+			continue
+		}
+
+		// The destination is changed if the town is captured on the way
+		session.Tx.SetAutoRollBack(false)
+		toTownID, err := session.Tx.DeleteCaravan(caravan.ID)
+		session.Tx.SetAutoRollBack(true)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Info("Caravan lost with the last town of the recipient")
+			continue
+		} else if err != nil {
+			logger.WithError(err).Error("Failed to delete caravan")
+			return
+		}
+		caravan.ToTownID = toTownID
+
+		if character.Town(caravan.ToTownID) == nil {
+			delivery := model.Delivery{TownID: caravan.ToTownID, Resources: caravan.Resources}
+			if err := session.Tx.AddDelivery(delivery); err != nil {
+				logger.WithError(err).Error("Failed to add caravan delivery")
+				return
+			}
+		}
+
+		arrived = append(arrived, caravan)
+	}
+
+	character.Caravans = onTheWay
+
+	for _, caravan := range arrived {
+		if town := character.Town(caravan.ToTownID); town != nil {
+			overflow := town.Store(caravan.Resources)
+			if !overflow.IsEmpty() {
+				if err := session.Tx.AddDelivery(model.Delivery{TownID: town.ID, Resources: overflow}); err != nil {
+					s.logger(ctx).WithError(err).Error("Failed to return caravan overflow")
+					return
+				}
+			}
+		}
+
+		s.logger(ctx).WithFields(log.Fields{
+			"caravanID": caravan.ID,
+			"toTownID":  caravan.ToTownID,
+			"resources": caravan.Resources,
+		}).Info("Caravan arrived")
+
+		s.publishEvent(ctx, model.NewCaravanArrivedEvent(character.ID, caravan))
+		if caravan.ToCharacterID != character.ID {
+			s.publishEvent(ctx, model.NewCaravanArrivedEvent(caravan.ToCharacterID, caravan))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"abbysoft/gardarike-online/model"
 	"math/rand"
 	"time"
 )
@@ -10,6 +11,12 @@ const (
 	ResourceIncrementChance    = 2.0
 )
 
+// PopulationGrowChance - returns percent chance of the population growth every tick,
+// it's doubled for the happiest population and unhappy population doesn't grow at all
+func PopulationGrowChance(happiness uint64) float32 {
+	return PopulationGrownEventChance * float32(happiness) / model.DefaultHappiness
+}
+
 // checkRandomEventHappened - check if the random event of 'chance' percent freq has happened
 // returns true if the event has happened and false otherwise
 func CheckRandomEventHappened(chance float32) bool {
@@ -0,0 +1,198 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// chatPruneInterval - period of deleting the chat messages older than the retention time
+const chatPruneInterval = time.Hour
+
+// chatRegion - returns the region of the character capital, false if the character has no towns
+func (s *SimpleLogic) chatRegion(character model.Character) (string, bool) {
+	capital := character.Capital()
+	if capital == nil {
+		return "", false
+	}
+
+	size := s.MapChunkSize()
+	return fmt.Sprintf("%d:%d", floorDiv(int(capital.X), size), floorDiv(int(capital.Y), size)), true
+}
+
+// chatMembership - returns the membership of the player in the joinable channel
+func (s *SimpleLogic) chatMembership(
+	ctx context.Context, session *PlayerSession, channel rpc.ChatMessage_Channel) (model.ChatMembership, model.Error) {
+	memberships, err := session.Tx.GetChatChannels(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chat channels")
+		return model.ChatMembership{}, model.ErrInternalServerError
+	}
+
+	membership, found := model.FindChatMembership(memberships, channel)
+	if !found {
+		return membership, model.ErrNotChannelMember
+	}
+
+	return membership, nil
+}
+
+// chatRecipient - returns ID of the character the direct message is sent to or the moderator action is applied to
+func (s *SimpleLogic) chatRecipient(ctx context.Context, session *PlayerSession, name string) (int64, model.Error) {
+	if name == "" || name == session.SelectedCharacter.Name {
+		return 0, model.ErrBadRequest
+	}
+
+	session.Tx.SetAutoRollBack(false)
+	id, err := session.Tx.GetCharacterID(name)
+	session.Tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, model.ErrCharacterNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get recipient")
+		return 0, model.ErrInternalServerError
+	}
+
+	return id, nil
+}
+
+// checkChatSanctions - checks if the player is allowed to chat. The sanctions are read from the database,
+// so the mute or the ban of the moderator affects the selected character immediately
+func (s *SimpleLogic) checkChatSanctions(ctx context.Context, session *PlayerSession) model.Error {
+	sanctions, err := session.Tx.GetSanctions(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chat sanctions")
+		return model.ErrInternalServerError
+	}
+
+	now := time.Now()
+	if sanctions.IsBanned(now) {
+		return model.ErrCharacterBanned
+	} else if sanctions.IsMuted(now) {
+		return model.ErrCharacterMuted
+	}
+
+	return nil
+}
+
+// allianceMembers - returns the relations of the player with the allies
+func (s *SimpleLogic) allianceMembers(ctx context.Context, session *PlayerSession) ([]model.DiplomaticRelation, model.Error) {
+	relations, err := session.Tx.GetDiplomaticRelations(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomatic relations")
+		return nil, model.ErrInternalServerError
+	}
+
+	return model.Allies(relations), nil
+}
+
+// chatFilter - returns the filter of the channel history available to the player
+func (s *SimpleLogic) chatFilter(ctx context.Context, session *PlayerSession,
+	channel rpc.ChatMessage_Channel, recipient string) (model.ChatFilter, model.Error) {
+	character := session.SelectedCharacter
+	filter := model.ChatFilter{Channel: channel}
+
+	switch channel {
+	case rpc.ChatMessage_ALLIANCE:
+		// Only the messages sent while the player was in the alliance
+		filter.AudienceID = character.ID
+	case rpc.ChatMessage_DIRECT:
+		if _, err := s.chatRecipient(ctx, session, recipient); err != nil {
+			return filter, err
+		}
+
+		filter.Senders = []string{character.Name, recipient}
+		filter.Recipients = filter.Senders
+	default:
+		membership, err := s.chatMembership(ctx, session, channel)
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Region = membership.Region
+	}
+
+	return filter, nil
+}
+
+// chatDelivery - prepares the message for the channel, returns the topics it's published to. The messages
+// of the joinable channels are published once to the channel topic, the alliance and the direct messages
+// are published to the topic of every recipient
+func (s *SimpleLogic) chatDelivery(ctx context.Context, session *PlayerSession, message *model.ChatMessage) ([]string, model.Error) {
+	character := session.SelectedCharacter
+
+	switch message.Channel {
+	case rpc.ChatMessage_ALLIANCE:
+		allies, err := s.allianceMembers(ctx, session)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Audience = []int64{character.ID}
+		for _, ally := range allies {
+			message.Audience = append(message.Audience, ally.OtherID)
+		}
+
+		var result []string
+		for _, id := range message.Audience {
+			result = append(result, model.CharacterTopic(id))
+		}
+
+		return result, nil
+	case rpc.ChatMessage_DIRECT:
+		recipientID, err := s.chatRecipient(ctx, session, message.Recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{model.CharacterTopic(character.ID), model.CharacterTopic(recipientID)}, nil
+	default:
+		membership, err := s.chatMembership(ctx, session, message.Channel)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Region = membership.Region
+		return []string{model.ChatChannelTopic(message.Channel, message.Region)}, nil
+	}
+}
+
+// chatChannelsToRPC - returns the channels the player is a member of
+func (s *SimpleLogic) chatChannelsToRPC(ctx context.Context, session *PlayerSession) ([]*rpc.ChatChannelMembership, model.Error) {
+	memberships, err := session.Tx.GetChatChannels(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chat channels")
+		return nil, model.ErrInternalServerError
+	}
+
+	var result []*rpc.ChatChannelMembership
+	for _, membership := range memberships {
+		result = append(result, membership.ToRPC())
+	}
+
+	return result, nil
+}
+
+// pruneChatHistory - deletes the chat messages older than the retention time
+func (s *SimpleLogic) pruneChatHistory(ctx context.Context) {
+	logger := s.logger(ctx)
+
+	tx, err := s.db.BeginTransaction(ctx, true, true)
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		return
+	}
+
+	deleted, err := tx.DeleteChatMessages(time.Now().Add(-s.config.ChatRetention))
+	if err != nil {
+		logger.WithError(err).Error("Failed to delete old chat messages")
+		return
+	}
+
+	logger.WithField("deleted", deleted).Info("Old chat messages deleted")
+}
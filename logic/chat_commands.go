@@ -0,0 +1,334 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chatRatingSize - number of the best empires shown by the rating command
+const chatRatingSize = 10
+
+// chatCommand - command typed in the chat. The handler returns the reply sent to the player only,
+// the model errors except the internal one are replied to the player instead of failing the request
+type chatCommand struct {
+	name        string
+	aliases     []string
+	usage       string // Arguments of the command
+	description string
+	role        model.Role // Minimal role allowed to run the command
+	minArgs     int
+	handle      func(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error)
+}
+
+func (s *SimpleLogic) chatCommands() []chatCommand {
+	return []chatCommand{
+		{
+			name:        "help",
+			usage:       "[command]",
+			description: "lists the commands or shows the command usage",
+			handle:      s.helpCommand,
+		},
+		{
+			name:        "whisper",
+			aliases:     []string{"w"},
+			usage:       "<player> <text>",
+			description: "sends the private message",
+			minArgs:     2,
+			handle:      s.whisperCommand,
+		},
+		{
+			name:        "who",
+			description: "lists the players online",
+			handle:      s.whoCommand,
+		},
+		{
+			name:        "town",
+			usage:       "[player]",
+			description: "lists the towns of the player, your towns by default",
+			handle:      s.townCommand,
+		},
+		{
+			name:        "rating",
+			description: "shows the most populated empires",
+			handle:      s.ratingCommand,
+		},
+		{
+			name:        "mute",
+			usage:       "<player> <minutes> [reason]",
+			description: "forbids the player to chat",
+			role:        model.RoleModerator,
+			minArgs:     2,
+			handle:      s.muteCommand,
+		},
+		{
+			name:        "unmute",
+			usage:       "<player>",
+			description: "allows the muted player to chat",
+			role:        model.RoleModerator,
+			minArgs:     1,
+			handle:      s.unmuteCommand,
+		},
+		{
+			name:        "announce",
+			usage:       "<text>",
+			description: "sends the system message to everyone",
+			role:        model.RoleAdmin,
+			minArgs:     1,
+			handle:      s.announceCommand,
+		},
+		{
+			name:        "give",
+			usage:       "<player> <resource> <amount>",
+			description: "delivers the resources to the capital of the player",
+			role:        model.RoleAdmin,
+			minArgs:     3,
+			handle:      s.giveCommand,
+		},
+	}
+}
+
+// findChatCommand - returns the command by the name or the alias
+func (s *SimpleLogic) findChatCommand(name string) (chatCommand, bool) {
+	for _, command := range s.chatCommands() {
+		if command.name == name {
+			return command, true
+		}
+
+		for _, alias := range command.aliases {
+			if alias == name {
+				return command, true
+			}
+		}
+	}
+
+	return chatCommand{}, false
+}
+
+func (c chatCommand) helpLine() string {
+	line := model.ChatCommandPrefix + c.name
+	if c.usage != "" {
+		line += " " + c.usage
+	}
+
+	return line + " - " + c.description
+}
+
+// executeChatCommand - runs the command typed by the player and sends the reply to the player
+func (s *SimpleLogic) executeChatCommand(ctx context.Context, session *PlayerSession, text string) model.Error {
+	reply, err := s.runChatCommand(ctx, session, text)
+	if err != nil {
+		return err
+	}
+
+	if reply != "" {
+		s.publishEvent(ctx, model.NewPrivateSystemChatMessageEvent(session.SelectedCharacter.ID, reply))
+	}
+
+	return nil
+}
+
+func (s *SimpleLogic) runChatCommand(ctx context.Context, session *PlayerSession, text string) (string, model.Error) {
+	parsed, parseErr := model.ParseChatCommand(text)
+	if parseErr != nil {
+		return fmt.Sprintf("Failed to parse the command: %s", parseErr), nil
+	}
+
+	command, found := s.findChatCommand(parsed.Name)
+	if !found {
+		return fmt.Sprintf("Unknown command %s%s, type %shelp for the list of commands",
+			model.ChatCommandPrefix, parsed.Name, model.ChatCommandPrefix), nil
+	}
+
+	if session.SelectedCharacter.Role() < command.role {
+		return fmt.Sprintf("%s%s is available to %ss only", model.ChatCommandPrefix, command.name, command.role), nil
+	}
+
+	if len(parsed.Args) < command.minArgs {
+		return "Usage: " + command.helpLine(), nil
+	}
+
+	s.logger(ctx).WithFields(log.Fields{
+		"command": command.name,
+		"args":    parsed.Args,
+	}).Info("Chat command")
+
+	reply, err := command.handle(ctx, session, parsed)
+	if err != nil && err != model.ErrInternalServerError {
+		return fmt.Sprintf("%s%s failed: %s", model.ChatCommandPrefix, command.name, err.GetMessage()), nil
+	}
+
+	return reply, err
+}
+
+func (s *SimpleLogic) helpCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	role := session.SelectedCharacter.Role()
+
+	if len(command.Args) > 0 {
+		found, ok := s.findChatCommand(strings.ToLower(strings.TrimPrefix(command.Args[0], model.ChatCommandPrefix)))
+		if !ok || role < found.role {
+			return fmt.Sprintf("Unknown command %s", command.Args[0]), nil
+		}
+
+		line := found.helpLine()
+		if len(found.aliases) > 0 {
+			line += fmt.Sprintf(" (also %s%s)", model.ChatCommandPrefix,
+				strings.Join(found.aliases, ", "+model.ChatCommandPrefix))
+		}
+
+		return line, nil
+	}
+
+	lines := []string{"Commands:"}
+	for _, available := range s.chatCommands() {
+		if role >= available.role {
+			lines = append(lines, available.helpLine())
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *SimpleLogic) whisperCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	_, err := s.sendChatMessage(ctx, session, model.ChatMessage{
+		Sender:    session.SelectedCharacter.Name,
+		Text:      command.Tail(1),
+		Channel:   rpc.ChatMessage_DIRECT,
+		Recipient: command.Args[0],
+	})
+
+	return "", err
+}
+
+func (s *SimpleLogic) whoCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	online := s.onlineCharacters()
+	return fmt.Sprintf("Online (%d): %s", len(online), strings.Join(online, ", ")), nil
+}
+
+func (s *SimpleLogic) townCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	owner := session.SelectedCharacter.Name
+	towns := session.SelectedCharacter.Towns
+
+	if len(command.Args) > 0 && command.Args[0] != owner {
+		owner = command.Args[0]
+
+		var err error
+		if towns, err = session.Tx.GetTowns(owner); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to get towns")
+			return "", model.ErrInternalServerError
+		}
+	}
+
+	if len(towns) == 0 {
+		return fmt.Sprintf("%s has no towns", owner), nil
+	}
+
+	lines := []string{fmt.Sprintf("Towns of %s:", owner)}
+	for _, town := range towns {
+		lines = append(lines, fmt.Sprintf("%s (%d, %d) - population %d/%d",
+			town.Name, town.X, town.Y, town.Population, town.MaxPopulation()))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *SimpleLogic) ratingCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	entries, playerEntry, err := session.Tx.GetEmpiresByCriteria(
+		session.SelectedCharacter.Name, 0, chatRatingSize, rpc.EmpiresRatingCriteria_POPULATION)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get empires rating")
+		return "", model.ErrInternalServerError
+	}
+
+	lines := []string{"Most populated empires:"}
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("%d. %s - %d", entry.Position, entry.EmpireName, entry.Value))
+	}
+
+	if playerEntry != nil {
+		lines = append(lines, fmt.Sprintf("Your position: %d", playerEntry.Position))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *SimpleLogic) muteCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	minutes, parseErr := strconv.ParseUint(command.Args[1], 10, 64)
+	if parseErr != nil || minutes == 0 || minutes > uint64(model.MaxSanctionDuration/time.Minute) {
+		return fmt.Sprintf("Invalid number of minutes %q", command.Args[1]), nil
+	}
+
+	response, err := s.ModerateCharacter(ctx, session, &rpc.ModerateCharacterRequest{
+		CharacterName: command.Args[0],
+		Action:        rpc.ModerateCharacterRequest_MUTE,
+		Duration:      minutes * uint64(time.Minute/time.Second),
+		Reason:        command.Tail(2),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s is muted until %s", command.Args[0],
+		time.Unix(response.Until, 0).UTC().Format(time.RFC1123)), nil
+}
+
+func (s *SimpleLogic) unmuteCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	_, err := s.ModerateCharacter(ctx, session, &rpc.ModerateCharacterRequest{
+		CharacterName: command.Args[0],
+		Action:        rpc.ModerateCharacterRequest_UNMUTE,
+		Reason:        command.Tail(1),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s is no longer muted", command.Args[0]), nil
+}
+
+func (s *SimpleLogic) announceCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	s.publishEvent(ctx, model.NewSystemChatMessageEvent(command.Tail(0)))
+	return "", nil
+}
+
+func (s *SimpleLogic) giveCommand(ctx context.Context, session *PlayerSession, command model.ChatCommand) (string, model.Error) {
+	owner, resourceName := command.Args[0], strings.ToUpper(command.Args[1])
+
+	amount, parseErr := strconv.ParseUint(command.Args[2], 10, 64)
+	if parseErr != nil || amount == 0 {
+		return fmt.Sprintf("Invalid amount %q", command.Args[2]), nil
+	}
+
+	var resources model.Resources
+	if resourceType, found := rpc.ResourceType_value[resourceName]; found {
+		resources = model.ResourceAmount(rpc.ResourceType(resourceType), amount)
+	} else if resourceName == "GOLD" {
+		resources.Gold = amount
+	} else {
+		return fmt.Sprintf("Unknown resource %q", command.Args[1]), nil
+	}
+
+	towns, err := session.Tx.GetTowns(owner)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get towns")
+		return "", model.ErrInternalServerError
+	}
+
+	character := model.Character{Towns: towns}
+	capital := character.Capital()
+	if capital == nil {
+		return fmt.Sprintf("%s has no towns", owner), nil
+	}
+
+	// The resources are delivered by the game loop, so the towns of the selected characters get them too
+	if err := session.Tx.AddDelivery(model.Delivery{TownID: capital.ID, Resources: resources}); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add delivery")
+		return "", model.ErrInternalServerError
+	}
+
+	return fmt.Sprintf("%d %s delivered to %s", amount, strings.ToLower(resourceName), capital.Name), nil
+}
@@ -0,0 +1,137 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// sendChatCommand - sends the command and returns the private reply to the player
+func sendChatCommand(t *testing.T, logic *SimpleLogic, session *PlayerSession, text string) string {
+	resp, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{Text: text})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	require.Len(t, logic.EventsChan, 1)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(session.SelectedCharacter.ID), event.Topic)
+
+	message := event.Event.GetChatMessageEvent().Message
+	require.Equal(t, rpc.ChatMessage_SYSTEM, message.Type)
+	return message.Text
+}
+
+func TestSimpleLogic_ChatCommands_Help(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+
+	help := sendChatCommand(t, logic, session, "/help")
+	require.Contains(t, help, "/whisper <player> <text>")
+	require.NotContains(t, help, "/mute")
+	require.NotContains(t, help, "/give")
+
+	require.Equal(t, "/whisper <player> <text> - sends the private message (also /w)",
+		sendChatCommand(t, logic, session, "/HELP whisper"))
+	require.Equal(t, "Usage: /whisper <player> <text> - sends the private message",
+		sendChatCommand(t, logic, session, "/w duke"))
+	require.Equal(t, "Unknown command /dance, type /help for the list of commands",
+		sendChatCommand(t, logic, session, "/dance"))
+	require.Equal(t, "/give is available to admins only",
+		sendChatCommand(t, logic, session, "/give king gold 100"))
+	require.Equal(t, "Failed to parse the command: unterminated quote",
+		sendChatCommand(t, logic, session, `/town "New town`))
+
+	session.SelectedCharacter.IsAdmin = true
+	require.Contains(t, sendChatCommand(t, logic, session, "/help"), "/mute <player> <minutes> [reason]")
+	require.Equal(t, `Invalid number of minutes "307445734561825860"`,
+		sendChatCommand(t, logic, session, "/mute duke 307445734561825860"))
+}
+
+func TestSimpleLogic_ChatCommands_Whisper(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{}, nil)
+	db.On("GetCharacterID", "Ivan the Great").Return(int64(2), nil)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: `meet me at "the river"`,
+		Channel: rpc.ChatMessage_DIRECT, Recipient: "Ivan the Great"})).Return(int64(7), nil)
+
+	_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    `/w "Ivan the Great"  meet me at "the river"`,
+		Channel: rpc.ChatMessage_GLOBAL,
+	})
+	require.NoError(t, err)
+
+	// The whisper is the direct message to both characters
+	require.Len(t, logic.EventsChan, 2)
+	require.Equal(t, model.CharacterTopic(1), (<-logic.EventsChan).Topic)
+	require.Equal(t, model.CharacterTopic(2), (<-logic.EventsChan).Topic)
+
+	// The whisper to yourself is replied with the error
+	require.Equal(t, "/whisper failed: bad request", sendChatCommand(t, logic, session, "/whisper king hi"))
+
+	// The escaped prefix is sent as the plain message
+	global := model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_GLOBAL}
+	db.On("GetChatChannels", int64(1)).Return([]model.ChatMembership{global}, nil)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: "/shrug",
+		Channel: rpc.ChatMessage_GLOBAL})).
+		Return(int64(8), nil)
+
+	resp, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    "//shrug",
+		Channel: rpc.ChatMessage_GLOBAL,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(8), resp.MessageID)
+	<-logic.EventsChan
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ChatCommands_Info(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+	logic.setSessionCharacter(session.SessionID, "king")
+	logic.setSessionCharacter("other", "duke")
+	logic.setSessionCharacter("another", "duke")
+
+	require.Equal(t, "Online (2): duke, king", sendChatCommand(t, logic, session, "/who"))
+
+	db.On("GetTowns", "duke").Return([]model.Town{{ID: 3, Name: "Kiev", X: 10, Y: -20, Population: 5}}, nil)
+	require.Equal(t, "Towns of duke:\nKiev (10, -20) - population 5/100", sendChatCommand(t, logic, session, "/town duke"))
+
+	db.On("GetTowns", "nobody").Return([]model.Town{}, nil)
+	require.Equal(t, "nobody has no towns", sendChatCommand(t, logic, session, "/town nobody"))
+
+	entries := []*rpc.RatingEntry{{Position: 1, EmpireName: "duke", Value: 500}, {Position: 2, EmpireName: "king", Value: 300}}
+	db.On("GetEmpiresByCriteria", "king", uint32(0), uint32(chatRatingSize), rpc.EmpiresRatingCriteria_POPULATION).
+		Return(entries, entries[1], nil)
+	require.Equal(t, "Most populated empires:\n1. duke - 500\n2. king - 300\nYour position: 2",
+		sendChatCommand(t, logic, session, "/rating"))
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ChatCommands_Admin(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+	session.SelectedCharacter.IsAdmin = true
+
+	db.On("GetTowns", "duke").Return([]model.Town{{ID: 3, Name: "Kiev"}, {ID: 5, Name: "Minsk"}}, nil)
+	db.On("AddDelivery", model.Delivery{TownID: 3, Resources: model.Resources{Stone: 250}}).Return(nil)
+	require.Equal(t, "250 stone delivered to Kiev", sendChatCommand(t, logic, session, "/give duke Stone 250"))
+	require.Equal(t, `Unknown resource "iron"`, sendChatCommand(t, logic, session, "/give duke iron 250"))
+
+	_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text: "/announce  Server restarts in 5 minutes",
+	})
+	require.NoError(t, err)
+	require.Equal(t, model.NewSystemChatMessageEvent("Server restarts in 5 minutes"), <-logic.EventsChan)
+
+	db.AssertExpectations(t)
+}
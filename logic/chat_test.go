@@ -0,0 +1,225 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sentChatMessage - matches the stored message, the sending time is set by the server
+func sentChatMessage(expected model.ChatMessage) interface{} {
+	return mock.MatchedBy(func(message model.ChatMessage) bool {
+		sentTime := message.Time
+		message.Time = time.Time{}
+		return !sentTime.IsZero() && reflect.DeepEqual(message, expected)
+	})
+}
+
+func TestSimpleLogic_SendChatMessage_Alliance(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	logic.config.ChatHistoryMaxCount = 50
+	session.SelectedCharacter = newTestCharacter("king")
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{}, nil)
+
+	relations := []model.DiplomaticRelation{
+		{CharacterID: 1, OtherID: 2, Other: "duke", State: rpc.DiplomaticState_ALLIANCE},
+		{CharacterID: 1, OtherID: 3, Other: "baron", State: rpc.DiplomaticState_WAR},
+	}
+	db.On("GetDiplomaticRelations", int64(1)).Return(relations, nil)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: "hello",
+		Channel: rpc.ChatMessage_ALLIANCE, Audience: []int64{1, 2}})).
+		Return(int64(9), nil)
+
+	_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    "hello",
+		Channel: rpc.ChatMessage_ALLIANCE,
+	})
+	require.NoError(t, err)
+
+	// The message is sent to the player and the allies only
+	require.Len(t, logic.EventsChan, 2)
+	require.Equal(t, model.CharacterTopic(1), (<-logic.EventsChan).Topic)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, rpc.ChatMessage_ALLIANCE, event.Event.GetChatMessageEvent().Message.Channel)
+
+	// The history has only the messages delivered to the player, so a new ally doesn't see the older ones
+	filter := model.ChatFilter{Channel: rpc.ChatMessage_ALLIANCE, AudienceID: 1}
+	db.On("GetChatMessages", filter, 10).
+		Return([]model.ChatMessage{{ID: 9, Sender: "king", Text: "hello", Channel: rpc.ChatMessage_ALLIANCE}}, nil)
+
+	history, err := logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel: rpc.ChatMessage_ALLIANCE,
+	})
+	require.NoError(t, err)
+	require.Len(t, history.Messages, 1)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_SendChatMessage_Direct(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	logic.config.ChatHistoryMaxCount = 50
+	session.SelectedCharacter = newTestCharacter("king")
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{}, nil)
+
+	send := func(recipient string) model.Error {
+		_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+			Text:      "psst",
+			Channel:   rpc.ChatMessage_DIRECT,
+			Recipient: recipient,
+		})
+		return err
+	}
+
+	require.EqualError(t, send(""), model.ErrBadRequest.Error())
+	require.EqualError(t, send("king"), model.ErrBadRequest.Error())
+
+	db.On("GetCharacterID", "nobody").Return(int64(0), sql.ErrNoRows)
+	require.EqualError(t, send("nobody"), model.ErrCharacterNotFound.Error())
+
+	db.On("GetCharacterID", "duke").Return(int64(2), nil)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: "psst", Channel: rpc.ChatMessage_DIRECT,
+		Recipient: "duke"})).Return(int64(3), nil)
+	require.NoError(t, send("duke"))
+
+	// Only the sender and the recipient get the message
+	require.Len(t, logic.EventsChan, 2)
+	require.Equal(t, model.CharacterTopic(1), (<-logic.EventsChan).Topic)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, "duke", event.Event.GetChatMessageEvent().Message.Recipient)
+
+	// The history has the messages of both characters to each other
+	filter := model.ChatFilter{Channel: rpc.ChatMessage_DIRECT, Senders: []string{"king", "duke"},
+		Recipients: []string{"king", "duke"}}
+	db.On("GetChatMessages", filter, 10).Return([]model.ChatMessage{}, nil)
+
+	_, err := logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel:   rpc.ChatMessage_DIRECT,
+		Recipient: "duke",
+	})
+	require.NoError(t, err)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ChatChannels(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	logic.config.ChunkSize = 500
+	session.SelectedCharacter = newTestCharacter("king", model.Town{ID: 1, X: 600, Y: 100, OwnerName: "king"})
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{}, nil)
+
+	_, err := logic.JoinChatChannel(context.Background(), session, &rpc.JoinChatChannelRequest{
+		Channel: rpc.ChatMessage_ALLIANCE,
+	})
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	global := model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_GLOBAL}
+	db.On("GetChatChannels", int64(1)).Return([]model.ChatMembership{global}, nil).Once()
+
+	// Only the members can write to the channel
+	_, err = logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    "hi",
+		Channel: rpc.ChatMessage_REGION,
+	})
+	require.EqualError(t, err, model.ErrNotChannelMember.Error())
+
+	// The region is the world map chunk of the capital
+	region := model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_REGION, Region: "1:0"}
+	db.On("JoinChatChannel", region).Return(nil)
+	db.On("GetChatChannels", int64(1)).Return([]model.ChatMembership{global, region}, nil)
+
+	resp, err := logic.JoinChatChannel(context.Background(), session, &rpc.JoinChatChannelRequest{
+		Channel: rpc.ChatMessage_REGION,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Channels, 2)
+	require.Equal(t, "1:0", resp.Channels[1].Region)
+
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: "hi", Channel: rpc.ChatMessage_REGION,
+		Region: "1:0"})).Return(int64(5), nil)
+
+	_, err = logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    "hi",
+		Channel: rpc.ChatMessage_REGION,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "CHAT.REGION.1:0.", resp.Channels[1].Topic)
+
+	// The message is published once to the members of the region
+	require.Len(t, logic.EventsChan, 1)
+	require.Equal(t, model.ChatChannelTopic(rpc.ChatMessage_REGION, "1:0"), (<-logic.EventsChan).Topic)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_GetChatHistory_Cursor(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatHistoryMaxCount = 50
+	session.SelectedCharacter = newTestCharacter("king")
+
+	global := model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_GLOBAL}
+	db.On("GetChatChannels", int64(1)).Return([]model.ChatMembership{global}, nil)
+
+	sent := time.Unix(1600000000, 0)
+	older := model.ChatFilter{Channel: rpc.ChatMessage_GLOBAL, BeforeID: 40}
+	db.On("GetChatMessages", older, 50).
+		Return([]model.ChatMessage{{ID: 39, Sender: "duke", Text: "hi", Time: sent}}, nil)
+
+	// The count is limited by the server
+	history, err := logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel:  rpc.ChatMessage_GLOBAL,
+		Count:    1000,
+		BeforeID: 40,
+	})
+	require.NoError(t, err)
+	require.Len(t, history.Messages, 1)
+
+	_, err = logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel:  rpc.ChatMessage_GLOBAL,
+		Count:    math.MaxUint64,
+		BeforeID: 40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, sent.Unix(), history.Messages[0].Time)
+
+	newer := model.ChatFilter{Channel: rpc.ChatMessage_GLOBAL, AfterID: 40}
+	db.On("GetChatMessages", newer, 10).Return([]model.ChatMessage{}, nil)
+
+	_, err = logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel: rpc.ChatMessage_GLOBAL,
+		AfterID: 40,
+	})
+	require.NoError(t, err)
+
+	_, err = logic.GetChatHistory(context.Background(), session, &rpc.GetChatHistoryRequest{
+		Channel:  rpc.ChatMessage_GLOBAL,
+		BeforeID: -1,
+	})
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_PruneChatHistory(t *testing.T) {
+	logic, db, _ := NewLogicMock()
+	logic.config.ChatRetention = 24 * time.Hour
+
+	db.On("DeleteChatMessages", mock.MatchedBy(func(before time.Time) bool {
+		return before.Before(time.Now().Add(-23*time.Hour)) && before.After(time.Now().Add(-25*time.Hour))
+	})).Return(int64(3), nil)
+
+	logic.pruneChatHistory(context.Background())
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// updateConstruction - advances the construction queues of all towns, the completed buildings
+// are added to the towns or replace the upgraded ones and their owner gets an event for each of them
+func (s *SimpleLogic) updateConstruction(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+	slots := s.config.ConstructionSlots
+
+	for i := range character.Towns {
+		town := &character.Towns[i]
+
+		for _, construction := range town.AdvanceConstruction(slots) {
+			logger := s.logger(ctx).WithFields(log.Fields{
+				"townID":         town.ID,
+				"constructionID": construction.ID,
+				"buildingID":     construction.Building.ID,
+			})
+
+			if construction.IsUpgrade() {
+				construction.Building = town.CompleteConstruction(construction)
+				if err := session.Tx.UpdateTownBuilding(construction.Building); err != nil {
+					logger.WithError(err).Error("Failed to upgrade town building")
+					return
+				}
+			} else {
+				id, err := session.Tx.AddTownBuilding(town.ID, construction.Building)
+				if err != nil {
+					logger.WithError(err).Error("Failed to add town building")
+					return
+				}
+
+				construction.Building.TownBuildingID = id
+				town.CompleteConstruction(construction)
+			}
+
+			if err := session.Tx.DeleteConstruction(construction.ID); err != nil {
+				logger.WithError(err).Error("Failed to delete construction")
+				return
+			}
+
+			logger.WithField("level", construction.Building.Level).Info("Building completed")
+			s.publishEvent(ctx, model.NewBuildingCompletedEvent(character.ID, construction))
+		}
+
+		for j := 0; j < len(town.Construction) && j < slots; j++ {
+			if !town.Construction[j].IsStarted() {
+				continue
+			}
+
+			if err := session.Tx.UpdateConstruction(town.Construction[j]); err != nil {
+				s.logger(ctx).WithError(err).Error("Failed to update construction")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSimpleLogic_UpdateConstruction(t *testing.T) {
+	logic, db, session := NewLogicMock()
+
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	quarry := model.Buildings[rpc.BuildingType_QUARRY].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 10, Construction: []model.Construction{
+		{ID: 1, TownID: 1, Building: house, TicksLeft: 1},
+		{ID: 2, TownID: 1, Building: quarry, TicksLeft: 5},
+		{ID: 3, TownID: 1, Building: quarry, TicksLeft: quarry.BuildTime},
+	}})
+
+	db.On("AddTownBuilding", int64(1), house).Return(int64(7), nil)
+	db.On("DeleteConstruction", int64(1)).Return(nil)
+	db.On("UpdateConstruction", mock.MatchedBy(func(construction model.Construction) bool {
+		return construction.ID == 2 && construction.TicksLeft == 4
+	})).Return(nil)
+
+	logic.updateConstruction(context.Background(), session)
+
+	// The house is finished, the second slot is taken by the next building in the queue
+	house.TownBuildingID = 7
+	town := session.SelectedCharacter.Towns[0]
+	require.Equal(t, []model.Building{house}, town.Buildings)
+	require.Len(t, town.Construction, 2)
+	require.Equal(t, uint64(4), town.Construction[0].TicksLeft)
+	require.False(t, town.Construction[1].IsStarted())
+
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.Equal(t, int64(1), event.Event.GetBuildingCompletedEvent().ConstructionID)
+	require.Equal(t, rpc.BuildingType_HOUSE, event.Event.GetBuildingCompletedEvent().Building.BuildingID)
+	require.Equal(t, int64(7), event.Event.GetBuildingCompletedEvent().Building.Id)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_TickSession_RolledBack(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Construction: []model.Construction{
+		{ID: 1, TownID: 1, Building: house, TicksLeft: 1},
+	}})
+	session.LastRequestTime = time.Now()
+	logic.config.AFKTimeout = time.Minute
+
+	saved := *newTestCharacter("test", model.Town{ID: 1, Construction: []model.Construction{
+		{ID: 1, TownID: 1, Building: house, TicksLeft: 1},
+	}})
+	db.On("GetLostTowns", "test", []int64{1}).Return([]int64{}, nil)
+	db.On("AddTownBuilding", int64(1), mock.Anything).Return(int64(0), errors.New("connection lost")).
+		Run(func(mock.Arguments) { db.isCompleted = true })
+	db.On("GetCharacter", int64(1)).Return(saved, nil)
+	db.On("GetTowns", "test").Return(saved.Towns, nil)
+
+	logic.tickSession(context.Background(), session)
+
+	// The rest of the stages are skipped and the advanced construction queue is dropped
+	require.Equal(t, saved.Towns, session.SelectedCharacter.Towns)
+	require.Equal(t, uint64(1), session.SelectedCharacter.Towns[0].Construction[0].TicksLeft)
+	require.Empty(t, logic.EventsChan)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CancelConstruction(t *testing.T) {
+	logic, db, session := NewLogicMock()
+
+	quarry := model.Buildings[rpc.BuildingType_QUARRY].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Construction: []model.Construction{
+		{ID: 2, TownID: 1, Building: quarry, TicksLeft: 5},
+		{ID: 3, TownID: 1, Building: quarry, TicksLeft: quarry.BuildTime},
+	}})
+
+	db.On("DeleteConstruction", int64(3)).Return(nil)
+	db.On("DeleteConstruction", int64(2)).Return(nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	// Not started construction is refunded in full
+	resp, err := logic.CancelConstruction(context.Background(), session, &rpc.CancelConstructionRequest{
+		TownID:         1,
+		ConstructionID: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, quarry.Cost.ToRPC(), resp.Refund)
+	require.Equal(t, quarry.Cost, session.SelectedCharacter.Towns[0].Resources)
+
+	// Only a half of the cost is returned if the construction has started
+	resp, err = logic.CancelConstruction(context.Background(), session, &rpc.CancelConstructionRequest{
+		TownID:         1,
+		ConstructionID: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, model.Resources{Wood: 50, Food: 25, Leather: 40}.ToRPC(), resp.Refund)
+	require.Empty(t, session.SelectedCharacter.Towns[0].Construction)
+
+	_, err = logic.CancelConstruction(context.Background(), session, &rpc.CancelConstructionRequest{
+		TownID:         1,
+		ConstructionID: 2,
+	})
+	require.EqualError(t, err, model.ErrConstructionNotFound.Error())
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_GetTownDetails(t *testing.T) {
+	logic, _, session := NewLogicMock()
+
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	quarry := model.Buildings[rpc.BuildingType_QUARRY].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Construction: []model.Construction{
+			{ID: 1, TownID: 1, Building: house, TicksLeft: 1},
+			{ID: 2, TownID: 1, Building: quarry, TicksLeft: 5},
+			{ID: 3, TownID: 1, Building: quarry, TicksLeft: quarry.BuildTime},
+		},
+	})
+
+	resp, err := logic.GetTownDetails(context.Background(), session, &rpc.GetTownDetailsRequest{TownID: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Town.Id)
+	require.Len(t, resp.Buildings, 1)
+	require.Equal(t, uint64(105), resp.MaxPopulation)
+	require.Equal(t, uint64(2), resp.ConstructionSlots)
+	require.Len(t, resp.ConstructionQueue, 3)
+	require.True(t, resp.ConstructionQueue[1].InProgress)
+	require.False(t, resp.ConstructionQueue[2].InProgress)
+
+	_, err = logic.GetTownDetails(context.Background(), session, &rpc.GetTownDetailsRequest{TownID: 2})
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+}
+
+func TestSimpleLogic_UpgradeBuilding(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+
+	db.On("AddConstruction", mock.MatchedBy(func(construction model.Construction) bool {
+		return construction.Building.TownBuildingID == 5 && construction.Building.Level == 2
+	})).Return(int64(1), nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	resp, err := logic.UpgradeBuilding(context.Background(), session, &rpc.UpgradeBuildingRequest{TownID: 1, BuildingID: 5})
+	require.NoError(t, err)
+
+	// The second level costs and takes twice as much
+	require.Equal(t, 2*house.BuildTime, resp.Construction.TicksLeft)
+	require.Equal(t, uint64(1000-2*house.Cost.Wood), session.SelectedCharacter.Towns[0].Resources.Wood)
+
+	_, err = logic.UpgradeBuilding(context.Background(), session, &rpc.UpgradeBuildingRequest{TownID: 1, BuildingID: 5})
+	require.EqualError(t, err, model.ErrBuildingUnderConstruction.Error())
+
+	_, err = logic.UpgradeBuilding(context.Background(), session, &rpc.UpgradeBuildingRequest{TownID: 1, BuildingID: 6})
+	require.EqualError(t, err, model.ErrBuildingNotFound.Error())
+
+	// The upgrade is applied to the building in place
+	session.SelectedCharacter.Towns[0].Construction[0].TicksLeft = 1
+	db.On("UpdateTownBuilding", mock.MatchedBy(func(building model.Building) bool {
+		return building.TownBuildingID == 5 && building.Level == 2 && building.Location == model.Vector2D{X: 10, Y: 20}
+	})).Return(nil)
+	db.On("DeleteConstruction", int64(1)).Return(nil)
+
+	logic.updateConstruction(context.Background(), session)
+
+	town := session.SelectedCharacter.Towns[0]
+	require.Len(t, town.Buildings, 1)
+	require.Equal(t, uint64(100+2*house.PopulationBonus), town.MaxPopulation())
+	require.Equal(t, house.Production.Multiply(2), town.ProductionRate())
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpgradeBuilding_MaxLevel(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+	session.SelectedCharacter.Towns[0].Buildings[0].Level = model.MaxBuildingLevel
+
+	_, err := logic.UpgradeBuilding(context.Background(), session, &rpc.UpgradeBuildingRequest{TownID: 1, BuildingID: 5})
+	require.EqualError(t, err, model.ErrMaxLevelReached.Error())
+}
+
+func TestSimpleLogic_DemolishBuilding(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+	session.SelectedCharacter.Towns[0].Buildings[0] = session.SelectedCharacter.Towns[0].Buildings[0].AtLevel(2)
+
+	db.On("DeleteTownBuilding", int64(5)).Return(nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	resp, err := logic.DemolishBuilding(context.Background(), session, &rpc.DemolishBuildingRequest{TownID: 1, BuildingID: 5})
+	require.NoError(t, err)
+
+	// A half of the first and the second levels cost
+	require.Equal(t, house.Cost.Multiply(3).Share(1, 2).ToRPC(), resp.Refund)
+
+	town := session.SelectedCharacter.Towns[0]
+	require.Empty(t, town.Buildings)
+	require.Equal(t, uint64(100), town.MaxPopulation())
+	require.Equal(t, model.Resources{}, town.ProductionRate())
+
+	_, err = logic.DemolishBuilding(context.Background(), session, &rpc.DemolishBuildingRequest{TownID: 1, BuildingID: 5})
+	require.EqualError(t, err, model.ErrBuildingNotFound.Error())
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_MoveBuilding(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+	session.SelectedCharacter.Towns[0].X, session.SelectedCharacter.Towns[0].Y = 20, 30
+	logic.config.ChunkSize = 10
+
+	db.On("GetMapChunk", mock.Anything, mock.Anything, mock.Anything).Return(newTerrainChunk(t, 10, flatTerrain), nil)
+
+	db.On("UpdateTownBuilding", mock.MatchedBy(func(building model.Building) bool {
+		return building.TownBuildingID == 5 && building.Location == model.Vector2D{X: 30, Y: 40} && building.Rotation == 90
+	})).Return(nil)
+
+	_, err := logic.MoveBuilding(context.Background(), session, &rpc.MoveBuildingRequest{
+		TownID:     1,
+		BuildingID: 5,
+		Location:   &rpc.Vector2D{X: 30, Y: 40},
+		Rotation:   90,
+	})
+	require.NoError(t, err)
+	require.Equal(t, model.Vector2D{X: 30, Y: 40}, session.SelectedCharacter.Towns[0].Buildings[0].Location)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_PlaceBuilding_Requirements(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+
+	place := func(id rpc.BuildingType) model.Error {
+		_, err := logic.PlaceBuilding(context.Background(), session, &rpc.PlaceBuildingRequest{TownID: 1, BuildingID: id})
+		return err
+	}
+
+	// The sawmill is locked until carpentry is researched
+	require.EqualError(t, place(rpc.BuildingType_SAWMILL), model.ErrTechnologyRequired.Error())
+	session.SelectedCharacter.Technologies = model.Technologies{
+		rpc.TechnologyType_CARPENTRY: true,
+		rpc.TechnologyType_TRAPPING:  true,
+		rpc.TechnologyType_TANNING:   true,
+	}
+	session.SelectedCharacter.ShareTechnologies()
+
+	// The town has a house, but not enough population for the sawmill and no hunter lodge for the tannery
+	require.EqualError(t, place(rpc.BuildingType_SAWMILL), model.ErrPrerequisitesNotMet.Error())
+	session.SelectedCharacter.Towns[0].Population = 30
+	require.EqualError(t, place(rpc.BuildingType_TANNERY), model.ErrPrerequisitesNotMet.Error())
+
+	// The queued sawmills count towards the limit
+	sawmill := model.Buildings[rpc.BuildingType_SAWMILL].AtLevel(1)
+	session.SelectedCharacter.Towns[0].Construction = []model.Construction{
+		model.NewConstruction(1, sawmill),
+		model.NewConstruction(1, sawmill),
+	}
+	require.EqualError(t, place(rpc.BuildingType_SAWMILL), model.ErrBuildingLimitReached.Error())
+}
+
+func TestSimpleLogic_GetBuildingCatalog(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 5
+	house.Location = model.Vector2D{X: 10, Y: 20}
+	session.SelectedCharacter = newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{house},
+		Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+	})
+
+	resp, err := logic.GetBuildingCatalog(context.Background(), session, &rpc.GetBuildingCatalogRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Buildings, len(model.Buildings))
+
+	info := resp.Buildings[rpc.BuildingType_HOUSE]
+	require.Equal(t, rpc.BuildingType_HOUSE, info.BuildingID)
+	require.True(t, info.Available)
+	require.True(t, info.Affordable)
+
+	temple := resp.Buildings[rpc.BuildingType_TEMPLE]
+	require.False(t, temple.Available)
+	require.Equal(t, rpc.Error_TECHNOLOGY_REQUIRED, temple.UnavailableReason)
+	require.Equal(t, []rpc.BuildingType{rpc.BuildingType_MARKET}, temple.RequiredBuildings)
+
+	session.SelectedCharacter.Technologies = model.Technologies{rpc.TechnologyType_RELIGION: true}
+	session.SelectedCharacter.ShareTechnologies()
+
+	resp, err = logic.GetBuildingCatalog(context.Background(), session, &rpc.GetBuildingCatalogRequest{})
+	require.NoError(t, err)
+	require.Equal(t, rpc.Error_PREREQUISITES_NOT_MET, resp.Buildings[rpc.BuildingType_TEMPLE].UnavailableReason)
+
+	_, err = logic.GetBuildingCatalog(context.Background(), session, &rpc.GetBuildingCatalogRequest{TownID: 2})
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+
+	// Nothing is available without towns
+	session.SelectedCharacter.Towns = nil
+	resp, err = logic.GetBuildingCatalog(context.Background(), session, &rpc.GetBuildingCatalogRequest{})
+	require.NoError(t, err)
+	require.False(t, resp.Buildings[rpc.BuildingType_HOUSE].Available)
+}
@@ -4,6 +4,7 @@ import (
 	"abbysoft/gardarike-online/db"
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"errors"
 	"math/rand"
 	"time"
@@ -21,15 +22,15 @@ func randStringBytes(n int) string {
 	return string(b)
 }
 
-func (s *SimpleLogic) CreateAccount(request *rpc.CreateAccountRequest) (*rpc.CreateAccountResponse, model.Error) {
-	s.log.WithField("login", request.Login).Info("CreateAccount")
+func (s *SimpleLogic) CreateAccount(ctx context.Context, request *rpc.CreateAccountRequest) (*rpc.CreateAccountResponse, model.Error) {
+	s.logger(ctx).WithField("login", request.Login).Info("CreateAccount")
 
 	salt := randStringBytes(10)
 	saltedPass := saltPassword(request.Password, salt)
 
-	tx, err := s.db.BeginTransaction(true, true)
+	tx, err := s.db.BeginTransaction(ctx, true, true)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to begin transaction")
+		s.logger(ctx).WithError(err).Error("Failed to begin transaction")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -37,7 +38,7 @@ func (s *SimpleLogic) CreateAccount(request *rpc.CreateAccountRequest) (*rpc.Cre
 	if err != nil && errors.Is(err, db.ErrDuplicatedUniqueKey) {
 		return nil, model.ErrUsernameIsTaken
 	} else if err != nil {
-		s.log.WithError(err).Error("Failed to add new account to the db")
+		s.logger(ctx).WithError(err).Error("Failed to add new account to the db")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -4,6 +4,7 @@ import (
 	db2 "abbysoft/gardarike-online/db"
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
@@ -26,7 +27,7 @@ func TestSimpleLogic_CreateAccount(t *testing.T) {
 		assert.Equal(t, saltPassword(expectedPass, salt), pass, "password not salted properly")
 	})
 
-	resp, err := logic.CreateAccount(request)
+	resp, err := logic.CreateAccount(context.Background(), request)
 	db.AssertExpectations(t)
 
 	if !assert.NoError(t, err, "request error is not nil") {
@@ -50,7 +51,7 @@ func TestSimpleLogic_CreateAccount_AlreadyExists(t *testing.T) {
 	db.On("AddAccount", "login", mock.Anything, mock.Anything).Once().
 		Return(0, db2.ErrDuplicatedUniqueKey)
 
-	_, err := logic.CreateAccount(request)
+	_, err := logic.CreateAccount(context.Background(), request)
 	db.AssertExpectations(t)
 
 	assert.EqualError(t, err, model.ErrUsernameIsTaken.Error())
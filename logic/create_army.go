@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) CreateArmy(ctx context.Context, session *PlayerSession, request *rpc.CreateArmyRequest) (*rpc.CreateArmyResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"townID":    request.TownID,
+		"units":     request.Units,
+	}).Info("CreateArmy")
+
+	units := model.ToModelUnits(request.Units)
+	if units.IsEmpty() {
+		return nil, model.ErrBadRequest
+	}
+
+	character := session.SelectedCharacter
+
+	town := character.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	armies, err := session.Tx.GetCharacterArmies(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character armies")
+		return nil, model.ErrInternalServerError
+	}
+
+	if len(armies) >= model.MaxArmies {
+		return nil, model.ErrArmyLimitReached
+	}
+
+	taken, err := session.Tx.TakeGarrison(town.ID, units)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to take units from the garrison")
+		return nil, model.ErrInternalServerError
+	}
+
+	if !taken {
+		return nil, model.ErrNotEnoughUnits
+	}
+
+	army := model.NewArmy(*character, *town, units)
+	if army.ID, err = session.Tx.AddArmy(army); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add army")
+		return nil, model.ErrInternalServerError
+	}
+
+	return &rpc.CreateArmyResponse{Army: army.ToRPC()}, nil
+}
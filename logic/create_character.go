@@ -3,11 +3,12 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
 )
 
-func (s *SimpleLogic) CreateCharacter(session *PlayerSession, request *rpc.CreateCharacterRequest) (*rpc.CreateCharacterResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) CreateCharacter(ctx context.Context, session *PlayerSession, request *rpc.CreateCharacterRequest) (*rpc.CreateCharacterResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 		"name":      request.Name,
 	}).Info("CreateCharacter")
@@ -16,12 +17,18 @@ func (s *SimpleLogic) CreateCharacter(session *PlayerSession, request *rpc.Creat
 
 	id, err := tx.AddCharacter(request.Name)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to create character")
+		s.logger(ctx).WithError(err).Error("Failed to create character")
 		return nil, model.ErrInternalServerError
 	}
 
 	if err = tx.AddAccountCharacter(id, int(session.AccountID)); err != nil {
-		s.log.WithError(err).Error("Failed to add account character")
+		s.logger(ctx).WithError(err).Error("Failed to add account character")
+		return nil, model.ErrInternalServerError
+	}
+
+	// New characters are in the global chat
+	if err = tx.JoinChatChannel(model.ChatMembership{CharacterID: int64(id), Channel: rpc.ChatMessage_GLOBAL}); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to join global chat")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -3,6 +3,7 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -18,8 +19,9 @@ func TestSimpleLogic_CreateCharacter(t *testing.T) {
 
 	db.On("AddCharacter", "test").Return(1, nil)
 	db.On("AddAccountCharacter", 1, 2).Return(nil)
+	db.On("JoinChatChannel", model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_GLOBAL}).Return(nil)
 
-	resp, err := logic.CreateCharacter(session, request)
+	resp, err := logic.CreateCharacter(context.Background(), session, request)
 
 	db.AssertExpectations(t)
 
@@ -40,7 +42,7 @@ func TestSimpleLogic_CreateCharacter_DuplicateLogin(t *testing.T) {
 	db.On("AddCharacter", "test").
 		Return(0, errors.New("some error"))
 
-	resp, err := logic.CreateCharacter(session, request)
+	resp, err := logic.CreateCharacter(context.Background(), session, request)
 	db.AssertExpectations(t)
 
 	assert.EqualError(t, err, model.ErrInternalServerError.Error(), "error isn't internal error")
@@ -3,7 +3,10 @@ package logic
 import (
 	"abbysoft/gardarike-online/db"
 	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
@@ -14,9 +17,15 @@ func NewLogicMock() (*SimpleLogic, *DatabaseTransactionMock, *PlayerSession) {
 	var db DatabaseMock
 	s.db = &db
 	s.sessions = make(map[string]*PlayerSession)
+	s.characterNames = make(map[string]string)
 
 	s.log = log.WithField("module", "test")
-	s.EventsChan = make(chan model.EventWrapper, 1)
+	s.EventsChan = make(chan model.EventWrapper, 10)
+	s.config.ConstructionSlots = consts.DefaultConstructionSlots
+	s.resourceManager = NewResourceManager(&s)
+	s.randomEvent = func(chance float32) bool {
+		return false
+	}
 
 	session := NewPlayerSession(1)
 	s.sessions[session.SessionID] = session
@@ -34,6 +43,17 @@ func NewLogicMockWithTerrainGenerator() (*SimpleLogic, *DatabaseTransactionMock,
 	return logic, db, session, terrainGenerator
 }
 
+// newTestCharacter - returns the character selected in the logic tests, it has the default happiness
+func newTestCharacter(name string, towns ...model.Town) *model.Character {
+	return &model.Character{
+		ID:        1,
+		AccountID: 1,
+		Name:      name,
+		Happiness: model.DefaultHappiness,
+		Towns:     towns,
+	}
+}
+
 type TerrainGeneratorMock struct {
 	mock.Mock
 }
@@ -54,27 +74,76 @@ func (t *TerrainGeneratorMock) Seed() int64 {
 type DatabaseTransactionMock struct {
 	mock.Mock
 	isCompleted bool
+	commitError error
 }
 
 func (d *DatabaseTransactionMock) GetEmpiresByCriteria(characterName string, offset, limit uint32, criteria rpc.EmpiresRatingCriteria) ([]*rpc.RatingEntry, *rpc.RatingEntry, error) {
-	panic("implement me")
+	args := d.Called(characterName, offset, limit, criteria)
+	return args.Get(0).([]*rpc.RatingEntry), args.Get(1).(*rpc.RatingEntry), args.Error(2)
 }
 
-func (d *DatabaseTransactionMock) GetProductionRates(characterID int64) (model.Resources, error) {
-	panic("implement me")
+func (d *DatabaseTransactionMock) TakeChunkResources(x, y int64, requested model.ChunkResources) (model.ChunkResources, error) {
+	args := d.Called(x, y, requested)
+	return args.Get(0).(model.ChunkResources), args.Error(1)
 }
 
-func (d *DatabaseTransactionMock) AddOrUpdateResources(resources model.Resources) error {
-	args := d.Called(resources)
+func (d *DatabaseTransactionMock) GetWorkDistribution(characterID int64) (model.WorkDistribution, error) {
+	args := d.Called(characterID)
+	return args.Get(0).(model.WorkDistribution), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateWorkDistribution(characterID int64, distribution model.WorkDistribution) error {
+	args := d.Called(characterID, distribution)
 	return args.Error(0)
 }
 
-func (d *DatabaseTransactionMock) AddOrUpdateProductionRates(rates model.Resources) error {
-	panic("implement me")
+func (d *DatabaseTransactionMock) GetCharacterBuildings(characterID int64) (model.CharacterBuildings, error) {
+	args := d.Called(characterID)
+	return args.Get(0).(model.CharacterBuildings), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddTechnology(characterID int64, id rpc.TechnologyType) error {
+	args := d.Called(characterID, id)
+	return args.Error(0)
 }
 
-func (d *DatabaseTransactionMock) AddTownBuilding(townID int64, building model.Building) error {
+func (d *DatabaseTransactionMock) SetResearch(characterID int64, research model.Research) error {
+	args := d.Called(characterID, research)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteResearch(characterID int64) error {
+	args := d.Called(characterID)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddTownBuilding(townID int64, building model.Building) (int64, error) {
 	args := d.Called(townID, building)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateTownBuilding(building model.Building) error {
+	args := d.Called(building)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteTownBuilding(id int64) error {
+	args := d.Called(id)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddConstruction(construction model.Construction) (int64, error) {
+	args := d.Called(construction)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateConstruction(construction model.Construction) error {
+	args := d.Called(construction)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteConstruction(id int64) error {
+	args := d.Called(id)
 	return args.Error(0)
 }
 
@@ -84,7 +153,7 @@ func (d *DatabaseTransactionMock) GetAllBuildings() (map[int64]model.CharacterBu
 
 func (d *DatabaseTransactionMock) EndTransaction() error {
 	d.isCompleted = true
-	return nil
+	return d.commitError
 }
 
 func (d *DatabaseTransactionMock) IsCompleted() bool {
@@ -111,10 +180,14 @@ type DatabaseMock struct {
 	DatabaseTransactionMock
 }
 
-func (d *DatabaseMock) BeginTransaction(autoCommit bool, autoRollBack bool) (db.DatabaseTransaction, error) {
+func (d *DatabaseMock) BeginTransaction(ctx context.Context, autoCommit bool, autoRollBack bool) (db.DatabaseTransaction, error) {
 	return d, nil
 }
 
+func (d *DatabaseMock) Ping(ctx context.Context) error {
+	return nil
+}
+
 func (d *DatabaseTransactionMock) RenameTown(townID int64, newName string) error {
 	panic("implement me")
 }
@@ -127,17 +200,32 @@ func (d *DatabaseTransactionMock) IncrementMapResources(resources model.ChunkRes
 	panic("implement me")
 }
 
-func (d *DatabaseTransactionMock) GetTownsForRect(xStart, xEnd, yStart, yEnd int) ([]model.Town, error) {
+func (d *DatabaseTransactionMock) GetChunksCount() (model.ChunksCount, error) {
 	panic("implement me")
 }
 
+func (d *DatabaseTransactionMock) UpdateTown(town model.Town) error {
+	args := d.Called(town)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetTown(townID int64) (model.Town, error) {
+	args := d.Called(townID)
+	return args.Get(0).(model.Town), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetTownsForRect(xStart, xEnd, yStart, yEnd int) ([]model.Town, error) {
+	args := d.Called(xStart, xEnd, yStart, yEnd)
+	return args.Get(0).([]model.Town), args.Error(1)
+}
+
 func (d *DatabaseTransactionMock) GetAllTowns() ([]model.Town, error) {
 	panic("implement me")
 }
 
-func (d *DatabaseTransactionMock) AddTown(town model.Town) error {
+func (d *DatabaseTransactionMock) AddTown(town model.Town) (int64, error) {
 	args := d.Called(town)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (d *DatabaseTransactionMock) AddResourcesOrUpdate(characterID int64, resources model.Resources) error {
@@ -145,11 +233,6 @@ func (d *DatabaseTransactionMock) AddResourcesOrUpdate(characterID int64, resour
 	return args.Error(0)
 }
 
-func (d *DatabaseTransactionMock) GetResources(characterID int64) (model.Resources, error) {
-	args := d.Called(characterID)
-	return args.Get(0).(model.Resources), args.Error(1)
-}
-
 func (d *DatabaseTransactionMock) AddAccountCharacter(characterID, accountID int) error {
 	args := d.Called(characterID, accountID)
 	return args.Error(0)
@@ -160,6 +243,11 @@ func (d *DatabaseTransactionMock) GetCharacter(id int64) (model.Character, error
 	return args.Get(0).(model.Character), args.Error(1)
 }
 
+func (d *DatabaseTransactionMock) GetCharacterID(name string) (int64, error) {
+	args := d.Called(name)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (d *DatabaseTransactionMock) AddCharacter(name string) (int, error) {
 	args := d.Called(name)
 	return args.Int(0), args.Error(1)
@@ -190,11 +278,38 @@ func (d *DatabaseTransactionMock) AddAccount(login string, password string, salt
 }
 
 func (d *DatabaseTransactionMock) AddChatMessage(message model.ChatMessage) (int64, error) {
-	panic("implement me")
+	args := d.Called(message)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (d *DatabaseTransactionMock) GetChatMessages(offset int, count int) ([]model.ChatMessage, error) {
-	panic("implement me")
+func (d *DatabaseTransactionMock) GetChatMessages(filter model.ChatFilter, count int) ([]model.ChatMessage, error) {
+	args := d.Called(filter, count)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) JoinChatChannel(membership model.ChatMembership) error {
+	args := d.Called(membership)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) LeaveChatChannel(characterID int64, channel rpc.ChatMessage_Channel) error {
+	args := d.Called(characterID, channel)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetChatChannels(characterID int64) ([]model.ChatMembership, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.ChatMembership), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetChatMessage(id int64) (model.ChatMessage, error) {
+	args := d.Called(id)
+	return args.Get(0).(model.ChatMessage), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) DeleteChatMessages(before time.Time) (int64, error) {
+	args := d.Called(before)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (d *DatabaseTransactionMock) GetMapChunk(x, y, number int64) (model.WorldMapChunk, error) {
@@ -211,3 +326,245 @@ func (d *DatabaseTransactionMock) GetTowns(ownerName string) ([]model.Town, erro
 	args := d.Called(ownerName)
 	return args.Get(0).([]model.Town), args.Error(1)
 }
+
+func (d *DatabaseTransactionMock) AddMarketOrder(order model.MarketOrder) (int64, error) {
+	args := d.Called(order)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetMarketOrder(id int64) (model.MarketOrder, error) {
+	args := d.Called(id)
+	return args.Get(0).(model.MarketOrder), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetMatchingOrders(order model.MarketOrder, limit int) ([]model.MarketOrder, error) {
+	args := d.Called(order, limit)
+	return args.Get(0).([]model.MarketOrder), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetMarketOrders(
+	resource rpc.ResourceType, side rpc.OrderSide, offset, limit int) ([]model.MarketOrder, error) {
+	args := d.Called(resource, side, offset, limit)
+	return args.Get(0).([]model.MarketOrder), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetCharacterMarketOrders(characterID int64) ([]model.MarketOrder, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.MarketOrder), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateMarketOrder(order model.MarketOrder) error {
+	args := d.Called(order)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteMarketOrder(id int64) error {
+	args := d.Called(id)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddTrade(trade model.Trade) (int64, error) {
+	args := d.Called(trade)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetTrades(resource rpc.ResourceType, offset, limit int) ([]model.Trade, error) {
+	args := d.Called(resource, offset, limit)
+	return args.Get(0).([]model.Trade), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetCharacterTrades(characterID int64, offset, limit int) ([]model.Trade, error) {
+	args := d.Called(characterID, offset, limit)
+	return args.Get(0).([]model.Trade), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddDelivery(delivery model.Delivery) error {
+	args := d.Called(delivery)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) TakeDeliveries(townIDs []int64) ([]model.Delivery, error) {
+	args := d.Called(townIDs)
+	return args.Get(0).([]model.Delivery), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) MoveTownGoods(fromTownID, toTownID int64) error {
+	args := d.Called(fromTownID, toTownID)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteTownGoods(townID int64) error {
+	args := d.Called(townID)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddCaravan(caravan model.Caravan) (int64, error) {
+	args := d.Called(caravan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateCaravan(caravan model.Caravan) error {
+	args := d.Called(caravan)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteCaravan(id int64) (int64, error) {
+	args := d.Called(id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetCaravansForRect(xStart, xEnd, yStart, yEnd int) ([]model.Caravan, error) {
+	args := d.Called(xStart, xEnd, yStart, yEnd)
+	return args.Get(0).([]model.Caravan), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddTraining(training model.UnitTraining) (int64, error) {
+	args := d.Called(training)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateTraining(training model.UnitTraining) error {
+	args := d.Called(training)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteTraining(id int64) error {
+	args := d.Called(id)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetGarrisons(townIDs []int64) (map[int64]model.Units, error) {
+	args := d.Called(townIDs)
+	return args.Get(0).(map[int64]model.Units), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddGarrison(townID int64, units model.Units) error {
+	args := d.Called(townID, units)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) TakeGarrison(townID int64, units model.Units) (bool, error) {
+	args := d.Called(townID, units)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddArmy(army model.Army) (int64, error) {
+	args := d.Called(army)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetArmy(id int64) (model.Army, error) {
+	args := d.Called(id)
+	return args.Get(0).(model.Army), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetCharacterArmies(characterID int64) ([]model.Army, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.Army), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetActiveArmies(characterID int64) ([]model.Army, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.Army), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetArmiesNear(
+	location model.Vector2D, radius float64, excludedCharacterID int64) ([]model.Army, error) {
+	args := d.Called(location, radius, excludedCharacterID)
+	return args.Get(0).([]model.Army), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) UpdateArmy(army model.Army) error {
+	args := d.Called(army)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteArmy(id int64) error {
+	args := d.Called(id)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) SetTownOwner(townID int64, ownerName string) error {
+	args := d.Called(townID, ownerName)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetLostTowns(ownerName string, townIDs []int64) ([]int64, error) {
+	args := d.Called(ownerName, townIDs)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetDiplomaticState(characterID, otherID int64) (rpc.DiplomaticState, error) {
+	args := d.Called(characterID, otherID)
+	return args.Get(0).(rpc.DiplomaticState), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetDiplomaticRelations(characterID int64) ([]model.DiplomaticRelation, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.DiplomaticRelation), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) SetDiplomaticState(characterID, otherID int64, state rpc.DiplomaticState) error {
+	args := d.Called(characterID, otherID, state)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddDiplomacyProposal(proposal model.DiplomacyProposal) (int64, error) {
+	args := d.Called(proposal)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetDiplomacyProposal(id int64) (model.DiplomacyProposal, error) {
+	args := d.Called(id)
+	return args.Get(0).(model.DiplomacyProposal), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) GetDiplomacyProposals(characterID int64) ([]model.DiplomacyProposal, error) {
+	args := d.Called(characterID)
+	return args.Get(0).([]model.DiplomacyProposal), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) DeleteDiplomacyProposal(id int64) error {
+	args := d.Called(id)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) DeleteDiplomacyProposals(characterID, otherID int64) error {
+	args := d.Called(characterID, otherID)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetSanctions(characterID int64) (model.Sanctions, error) {
+	args := d.Called(characterID)
+	return args.Get(0).(model.Sanctions), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) SetMutedUntil(characterID int64, until time.Time) error {
+	args := d.Called(characterID, until)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) SetBannedUntil(characterID int64, until time.Time) error {
+	args := d.Called(characterID, until)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) AddChatReport(report model.ChatReport) error {
+	args := d.Called(report)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetChatReports(count int) ([]model.ChatReport, error) {
+	args := d.Called(count)
+	return args.Get(0).([]model.ChatReport), args.Error(1)
+}
+
+func (d *DatabaseTransactionMock) AddModerationRecord(record model.ModerationRecord) error {
+	args := d.Called(record)
+	return args.Error(0)
+}
+
+func (d *DatabaseTransactionMock) GetModerationLog(count int) ([]model.ModerationRecord, error) {
+	args := d.Called(count)
+	return args.Get(0).([]model.ModerationRecord), args.Error(1)
+}
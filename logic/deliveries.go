@@ -0,0 +1,38 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+)
+
+// updateDeliveries - delivers the goods of the filled market orders and the caravans to the towns of the character,
+// the goods exceeding the storage limit are left for delivery until the town has free space
+func (s *SimpleLogic) updateDeliveries(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+	if len(character.Towns) == 0 {
+		return
+	}
+
+	deliveries, err := session.Tx.TakeDeliveries(character.TownIDs())
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to take deliveries")
+		return
+	}
+
+	for _, delivery := range deliveries {
+		town := character.Town(delivery.TownID)
+		if town == nil {
+			continue
+		}
+
+		overflow := town.Store(delivery.Resources)
+		if overflow.IsEmpty() {
+			continue
+		}
+
+		if err := session.Tx.AddDelivery(model.Delivery{TownID: town.ID, Resources: overflow}); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to return delivery overflow")
+			return
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) DemolishBuilding(ctx context.Context, session *PlayerSession, request *rpc.DemolishBuildingRequest) (*rpc.DemolishBuildingResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":  session.SessionID,
+		"townID":     request.TownID,
+		"buildingID": request.BuildingID,
+	}).Info("DemolishBuilding")
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	index := town.FindBuilding(request.BuildingID)
+	if index < 0 {
+		return nil, model.ErrBuildingNotFound
+	}
+
+	// The upgrade should be cancelled first
+	if town.IsUnderConstruction(request.BuildingID) {
+		return nil, model.ErrBuildingUnderConstruction
+	}
+
+	refund := town.Buildings[index].DemolitionRefund()
+
+	// The production and the housing of the town are reduced as soon as the building is removed
+	updated := *town
+	updated.Buildings = append(append([]model.Building{}, town.Buildings[:index]...), town.Buildings[index+1:]...)
+	updated.Store(refund)
+
+	if err := session.Tx.DeleteTownBuilding(request.BuildingID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to delete town building")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	*town = updated
+
+	return &rpc.DemolishBuildingResponse{
+		Refund: refund.ToRPC(),
+	}, nil
+}
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// changeDiplomaticState - sets the state of the relations of the character with another empire
+// and drops the proposals between them, both empires are notified
+func (s *SimpleLogic) changeDiplomaticState(
+	ctx context.Context, session *PlayerSession, otherID int64, otherName string, state rpc.DiplomaticState) error {
+	character := session.SelectedCharacter
+
+	if err := session.Tx.SetDiplomaticState(character.ID, otherID, state); err != nil {
+		return fmt.Errorf("failed to set diplomatic state: %w", err)
+	}
+
+	if err := session.Tx.DeleteDiplomacyProposals(character.ID, otherID); err != nil {
+		return fmt.Errorf("failed to delete diplomacy proposals: %w", err)
+	}
+
+	s.logger(ctx).WithFields(log.Fields{
+		"characterID": character.ID,
+		"otherID":     otherID,
+		"state":       state,
+	}).Info("Diplomatic state changed")
+
+	s.publishEvent(ctx, model.NewDiplomacyEvent(character.ID, rpc.DiplomacyEvent_CHANGED, otherName, state, nil))
+	s.publishEvent(ctx, model.NewDiplomacyEvent(otherID, rpc.DiplomacyEvent_CHANGED, character.Name, state, nil))
+
+	return nil
+}
+
+// atWar - checks if the characters are at war and their armies fight each other
+func atWar(tx db.DatabaseTransaction, characterID, otherID int64) (bool, error) {
+	state, err := tx.GetDiplomaticState(characterID, otherID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get diplomatic state: %w", err)
+	}
+
+	return model.CanFight(state), nil
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_ProposeDiplomacy(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("king")
+
+	propose := func(empire string, state rpc.DiplomaticState) (*rpc.ProposeDiplomacyResponse, model.Error) {
+		return logic.ProposeDiplomacy(context.Background(), session, &rpc.ProposeDiplomacyRequest{
+			EmpireName: empire,
+			State:      state,
+		})
+	}
+
+	_, err := propose("king", rpc.DiplomaticState_ALLIANCE)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	db.On("GetCharacterID", "nobody").Return(int64(0), sql.ErrNoRows)
+	_, err = propose("nobody", rpc.DiplomaticState_ALLIANCE)
+	require.EqualError(t, err, model.ErrCharacterNotFound.Error())
+
+	db.On("GetCharacterID", "duke").Return(int64(2), nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_NEUTRAL, nil).Once()
+
+	_, err = propose("duke", rpc.DiplomaticState_NEUTRAL)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	// The alliance waits for the acceptance
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_NEUTRAL, nil).Once()
+	db.On("AddDiplomacyProposal", mock.MatchedBy(func(proposal model.DiplomacyProposal) bool {
+		return proposal.FromID == 1 && proposal.ToID == 2 && proposal.State == rpc.DiplomaticState_ALLIANCE
+	})).Return(int64(7), nil)
+
+	resp, err := propose("duke", rpc.DiplomaticState_ALLIANCE)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), resp.Proposal.Id)
+	require.Equal(t, rpc.DiplomaticState_NEUTRAL, resp.State)
+
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, rpc.DiplomacyEvent_PROPOSED, event.Event.GetDiplomacyEvent().Type)
+	require.Equal(t, "king", event.Event.GetDiplomacyEvent().EmpireName)
+
+	// The war is declared at once
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_NEUTRAL, nil).Once()
+	db.On("SetDiplomaticState", int64(1), int64(2), rpc.DiplomaticState_WAR).Return(nil)
+	db.On("DeleteDiplomacyProposals", int64(1), int64(2)).Return(nil)
+
+	resp, err = propose("duke", rpc.DiplomaticState_WAR)
+	require.NoError(t, err)
+	require.Nil(t, resp.Proposal)
+	require.Equal(t, rpc.DiplomaticState_WAR, resp.State)
+
+	require.Len(t, logic.EventsChan, 2)
+	require.Equal(t, model.CharacterTopic(1), (<-logic.EventsChan).Topic)
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, rpc.DiplomacyEvent_CHANGED, event.Event.GetDiplomacyEvent().Type)
+
+	// The peace must be accepted
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil).Once()
+	db.On("AddDiplomacyProposal", mock.MatchedBy(func(proposal model.DiplomacyProposal) bool {
+		return proposal.State == rpc.DiplomaticState_NEUTRAL
+	})).Return(int64(8), nil)
+
+	resp, err = propose("duke", rpc.DiplomaticState_NEUTRAL)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), resp.Proposal.Id)
+	require.Equal(t, rpc.DiplomaticState_WAR, resp.State)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_AnswerDiplomacyProposal(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("king")
+
+	answer := func(id int64, accept bool) (*rpc.AnswerDiplomacyProposalResponse, model.Error) {
+		return logic.AnswerDiplomacyProposal(context.Background(), session, &rpc.AnswerDiplomacyProposalRequest{
+			ProposalID: id,
+			Accept:     accept,
+		})
+	}
+
+	db.On("GetDiplomacyProposal", int64(3)).Return(model.DiplomacyProposal{}, sql.ErrNoRows)
+	_, err := answer(3, true)
+	require.EqualError(t, err, model.ErrProposalNotFound.Error())
+
+	// Own proposals can't be accepted
+	db.On("GetDiplomacyProposal", int64(4)).Return(model.DiplomacyProposal{ID: 4, FromID: 1, ToID: 2,
+		State: rpc.DiplomaticState_ALLIANCE}, nil)
+	_, err = answer(4, true)
+	require.EqualError(t, err, model.ErrProposalNotFound.Error())
+
+	proposal := model.DiplomacyProposal{ID: 5, FromID: 2, From: "duke", ToID: 1, To: "king",
+		State: rpc.DiplomaticState_NON_AGGRESSION_PACT}
+	db.On("GetDiplomacyProposal", int64(5)).Return(proposal, nil)
+
+	db.On("DeleteDiplomacyProposal", int64(5)).Return(nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+
+	resp, err := answer(5, false)
+	require.NoError(t, err)
+	require.Equal(t, rpc.DiplomaticState_WAR, resp.State)
+
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, rpc.DiplomacyEvent_REJECTED, event.Event.GetDiplomacyEvent().Type)
+	require.Equal(t, int64(5), event.Event.GetDiplomacyEvent().Proposal.Id)
+
+	db.On("SetDiplomaticState", int64(1), int64(2), rpc.DiplomaticState_NON_AGGRESSION_PACT).Return(nil)
+	db.On("DeleteDiplomacyProposals", int64(1), int64(2)).Return(nil)
+
+	resp, err = answer(5, true)
+	require.NoError(t, err)
+	require.Equal(t, rpc.DiplomaticState_NON_AGGRESSION_PACT, resp.State)
+	require.Len(t, logic.EventsChan, 2)
+
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,50 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) DisbandArmy(ctx context.Context, session *PlayerSession, request *rpc.DisbandArmyRequest) (*rpc.DisbandArmyResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"armyID":    request.ArmyID,
+	}).Info("DisbandArmy")
+
+	army, modelErr := s.characterArmy(ctx, session, request.ArmyID)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+
+	if army.IsMoving {
+		return nil, model.ErrBadRequest
+	}
+
+	var town *model.Town
+	for i, candidate := range session.SelectedCharacter.Towns {
+		if army.DistanceTo(candidate.Location()) <= model.TownBuildRadius {
+			town = &session.SelectedCharacter.Towns[i]
+			break
+		}
+	}
+
+	if town == nil {
+		return nil, model.ErrOutsideTown
+	}
+
+	if err := session.Tx.AddGarrison(town.ID, army.Units); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add units to the garrison")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.DeleteArmy(army.ID); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to delete army")
+		return nil, model.ErrInternalServerError
+	}
+
+	return &rpc.DisbandArmyResponse{
+		Garrison: &rpc.Garrison{TownID: town.ID, Units: army.Units.ToRPC()},
+	}, nil
+}
@@ -1,19 +1,35 @@
 package logic
 
 import (
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/tracing"
+	"context"
+	"fmt"
 	"time"
 )
 
 const (
-	gameLoopTps = 1.0
+	gameLoopTps          = 1.0
+	gameLoopTickInterval = 5 * time.Second
+
+	// The game loop is considered stuck if there were no ticks for this time
+	gameLoopHeartbeatTimeout = 6 * gameLoopTickInterval
 )
 
-func (s *SimpleLogic) updateSessions() {
-	sessionsCount := len(s.sessions)
+// GameLoopStats - timings of the last game loop tick
+type GameLoopStats struct {
+	StartTime        time.Time
+	LastTickTime     time.Time
+	LastTickDuration time.Duration
+}
+
+func (s *SimpleLogic) updateSessions(ctx context.Context) {
+	sessions := s.activeSessions()
+	sessionsCount := len(sessions)
 	finishChan := make(chan bool, sessionsCount)
 
-	for _, session := range s.sessions {
+	for _, session := range sessions {
 		session := session
 
 		go func() {
@@ -25,23 +41,17 @@ func (s *SimpleLogic) updateSessions() {
 				return
 			}
 
-			tx, err := s.db.BeginTransaction(false, true)
+			ctx := sessionContext(ctx, session)
+
+			tx, err := s.db.BeginTransaction(ctx, false, true)
 			if err != nil {
-				s.log.WithError(err).Error("Failed to begin transaction")
+				s.logger(ctx).WithError(err).Error("Failed to begin transaction")
 				finishChan <- true
 				return
 			}
 
 			session.Tx = tx
-
-			s.updateSession(session)
-			s.updateSessionResources(session)
-
-			if !tx.IsCompleted() {
-				if err := tx.EndTransaction(); err != nil {
-					s.log.WithError(err).Error("Failed to commit transaction")
-				}
-			}
+			s.tickSession(ctx, session)
 
 			finishChan <- true
 		}()
@@ -52,67 +62,168 @@ func (s *SimpleLogic) updateSessions() {
 	}
 }
 
+// tickSession - runs all game loop stages for the session character in the session transaction.
+// A failed stage rolls the transaction back, so the rest of the tick is skipped and the character
+// is reloaded to drop the in-memory changes that weren't saved. The events of the tick are sent
+// only after the transaction is committed
+func (s *SimpleLogic) tickSession(ctx context.Context, session *PlayerSession) {
+	ctx, events := withPendingEvents(ctx)
+
+	stages := []func(context.Context, *PlayerSession){
+		s.updateSession,
+		s.updateLostTowns,
+		s.updateConstruction,
+		s.updateResearch,
+		s.updateCaravans,
+		s.updateTraining,
+		s.updateArmies,
+		s.updateDeliveries,
+		s.updateSessionResources,
+	}
+
+	tx := session.Tx
+	for _, stage := range stages {
+		stage(ctx, session)
+
+		if tx.IsCompleted() {
+			s.logger(ctx).Warn("Game loop tick is rolled back")
+			s.reloadCharacter(ctx, session)
+			return
+		}
+	}
+
+	if err := tx.EndTransaction(); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to commit transaction")
+		s.reloadCharacter(ctx, session)
+		return
+	}
+
+	s.sendPendingEvents(events)
+}
+
+// reloadCharacter - replaces the selected character of the session with its saved state
+func (s *SimpleLogic) reloadCharacter(ctx context.Context, session *PlayerSession) {
+	tx, err := s.db.BeginTransaction(ctx, false, true)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to begin transaction")
+		return
+	}
+	defer tx.EndTransaction()
+
+	char, err := tx.GetCharacter(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to reload character")
+		return
+	}
+
+	if char.Towns, err = tx.GetTowns(char.Name); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to reload character's towns")
+		return
+	}
+
+	char.ShareTechnologies()
+	session.SelectedCharacter = &char
+}
+
 // startGameLoop - runs endless game loop
 func (s *SimpleLogic) startGameLoop() {
+	s.loopStatsMutex.Lock()
+	s.loopStats.StartTime = time.Now()
+	s.loopStatsMutex.Unlock()
+
 	go func() {
-		for range time.Tick(5 * time.Second) {
-			s.updateSessions()
+		for range time.Tick(gameLoopTickInterval) {
+			ctx, span := tracing.StartSpan(backgroundContext("GameLoopTick"), "GameLoopTick")
+
+			start := time.Now()
+			s.updateSessions(ctx)
+			duration := time.Since(start)
+			span.End()
+
+			metrics.GameLoopTickDuration.Observe(duration.Seconds())
+			if duration > gameLoopTickInterval {
+				s.logger(ctx).WithField("duration", duration).Warn("Game loop tick overrun")
+				metrics.GameLoopOverruns.Inc()
+			}
+
+			s.loopStatsMutex.Lock()
+			s.loopStats.LastTickTime = start
+			s.loopStats.LastTickDuration = duration
+			s.loopStatsMutex.Unlock()
 		}
 	}()
 
 	go func() {
 		for range time.Tick(time.Minute) {
-			s.resourceManager.Update()
+			s.resourceManager.Update(backgroundContext("ResourcesUpdate"))
 		}
 	}()
-}
-
-func (s *SimpleLogic) characterPopulationGrownEvent(session *PlayerSession) {
-	session.SelectedCharacter.CurrentPopulation++
-	session.WorkDistribution.IdleCount++
 
-	s.log.WithField("sessionID", session.SessionID).
-		WithField("character", session.SelectedCharacter.Name).
-		WithField("population", session.SelectedCharacter.CurrentPopulation).
-		Debugf("Player's population grows")
-
-	if err := session.Tx.UpdateCharacter(*session.SelectedCharacter); err != nil {
-		s.log.WithError(err).Error("Failed to update character")
+	if s.config.ChatRetention > 0 {
+		go func() {
+			for range time.Tick(chatPruneInterval) {
+				s.pruneChatHistory(backgroundContext("ChatHistoryPrune"))
+			}
+		}()
 	}
 }
 
-func (s *SimpleLogic) updateSessionResources(session *PlayerSession) {
+func (s *SimpleLogic) updateSessionResources(ctx context.Context, session *PlayerSession) {
 	character := session.SelectedCharacter
 
-	if !character.Resources.IsLimitReached() {
-		character.Resources.Add(model.Resources{
-			Wood:    1,
-			Food:    1,
-			Stone:   1,
-			Leather: 1,
-		})
+	var production model.Resources
+	if !character.IsStorageFull() {
+		harvested, err := s.resourceManager.Harvest(session, character.Workers.Harvest())
+		if err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to harvest chunk resources")
+			return
+		}
 
-		character.Resources.Add(character.ProductionRate)
+		efficiency := model.ProductionModifier(character.Happiness)
+		production = character.Workers.HarvestedProduction(harvested, efficiency)
+	}
 
-		if err := session.Tx.AddOrUpdateResources(character.Resources); err != nil {
-			s.log.WithError(err).Error("Failed to update resources")
-		}
+	character.Produce(production)
+	s.updateTreasury(ctx, session)
+	s.updatePopulation(ctx, session)
+
+	if session.Tx.IsCompleted() {
+		return
+	}
+
+	if err := session.Tx.UpdateCharacter(*character); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update character")
 	}
 }
 
-func (s *SimpleLogic) updateSession(session *PlayerSession) {
+func (s *SimpleLogic) updateSession(ctx context.Context, session *PlayerSession) {
 	if time.Now().Sub(session.LastRequestTime) > s.config.AFKTimeout {
-		s.log.WithField("sessionID", session.SessionID).
-			WithField("timeout", s.config.AFKTimeout).
+		s.logger(ctx).WithField("timeout", s.config.AFKTimeout).
 			Info("Session AFK timeout, delete session")
-		delete(s.sessions, session.SessionID)
-		return
+		s.removeSession(session.SessionID)
 	}
+}
 
-	character := session.SelectedCharacter
+// LoopStats - returns timings of the last game loop tick
+func (s *SimpleLogic) LoopStats() GameLoopStats {
+	s.loopStatsMutex.RLock()
+	defer s.loopStatsMutex.RUnlock()
 
-	populationGrownEvent := CheckRandomEventHappened(PopulationGrownEventChance)
-	if populationGrownEvent && character.MaxPopulation != character.CurrentPopulation {
-		s.characterPopulationGrownEvent(session)
+	return s.loopStats
+}
+
+// CheckGameLoop - returns an error if the game loop hasn't completed a tick for too long
+func (s *SimpleLogic) CheckGameLoop(ctx context.Context) error {
+	stats := s.LoopStats()
+
+	heartbeat := stats.StartTime
+	if !stats.LastTickTime.IsZero() {
+		heartbeat = stats.LastTickTime.Add(stats.LastTickDuration)
 	}
+
+	if since := time.Since(heartbeat); since > gameLoopHeartbeatTimeout {
+		return fmt.Errorf("no game loop ticks for %v", since.Round(time.Second))
+	}
+
+	return nil
 }
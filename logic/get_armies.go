@@ -0,0 +1,44 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+)
+
+func (s *SimpleLogic) GetArmies(ctx context.Context, session *PlayerSession, request *rpc.GetArmiesRequest) (*rpc.GetArmiesResponse, model.Error) {
+	s.logger(ctx).WithField("sessionID", session.SessionID).Info("GetArmies")
+
+	character := session.SelectedCharacter
+
+	armies, err := session.Tx.GetCharacterArmies(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character armies")
+		return nil, model.ErrInternalServerError
+	}
+
+	garrisons, err := session.Tx.GetGarrisons(character.TownIDs())
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get garrisons")
+		return nil, model.ErrInternalServerError
+	}
+
+	response := &rpc.GetArmiesResponse{}
+	for _, army := range armies {
+		response.Armies = append(response.Armies, army.ToRPC())
+	}
+
+	for _, town := range character.Towns {
+		if units, found := garrisons[town.ID]; found {
+			response.Garrisons = append(response.Garrisons, &rpc.Garrison{TownID: town.ID, Units: units.ToRPC()})
+		}
+
+		response.Training = append(response.Training, town.TrainingToRPC()...)
+	}
+
+	for _, id := range model.UnitTypeIDs() {
+		response.Units = append(response.Units, model.UnitTypes[id].ToRPC())
+	}
+
+	return response, nil
+}
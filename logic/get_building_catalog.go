@@ -0,0 +1,48 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetBuildingCatalog(ctx context.Context, session *PlayerSession, request *rpc.GetBuildingCatalogRequest) (*rpc.GetBuildingCatalogResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"townID":    request.TownID,
+	}).Info("GetBuildingCatalog")
+
+	character := session.SelectedCharacter
+
+	town := character.Capital()
+	if request.TownID != 0 {
+		if town = character.Town(request.TownID); town == nil {
+			return nil, model.ErrTownNotFound
+		}
+	}
+
+	response := &rpc.GetBuildingCatalogResponse{}
+	for _, id := range model.BuildingTypes() {
+		building := model.Buildings[id].AtLevel(1)
+		info := building.ToInfoRPC()
+
+		// Nothing can be built before the first town is placed
+		if town == nil {
+			info.UnavailableReason = rpc.Error_TOWN_NOT_FOUND
+			response.Buildings = append(response.Buildings, info)
+			continue
+		}
+
+		if err := town.CheckRequirements(building); err != nil {
+			info.UnavailableReason = rpc.Error(err.GetCode())
+		} else {
+			info.Available = true
+		}
+
+		info.Affordable = town.Resources.IsEnough(building.Cost)
+		response.Buildings = append(response.Buildings, info)
+	}
+
+	return response, nil
+}
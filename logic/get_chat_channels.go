@@ -0,0 +1,21 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetChatChannels(ctx context.Context, session *PlayerSession, request *rpc.GetChatChannelsRequest) (*rpc.GetChatChannelsResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+	}).Info("GetChatChannels")
+
+	channels, err := s.chatChannelsToRPC(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.GetChatChannelsResponse{Channels: channels}, nil
+}
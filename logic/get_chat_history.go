@@ -3,28 +3,47 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"github.com/sirupsen/logrus"
 )
 
-func (s *SimpleLogic) GetChatHistory(session *PlayerSession, request *rpc.GetChatHistoryRequest) (*rpc.GetChatHistoryResponse, model.Error) {
-	s.log.WithFields(logrus.Fields{
+// defaultChatHistoryCount - number of the messages returned if the count isn't set
+const defaultChatHistoryCount = 10
+
+func (s *SimpleLogic) GetChatHistory(ctx context.Context, session *PlayerSession, request *rpc.GetChatHistoryRequest) (*rpc.GetChatHistoryResponse, model.Error) {
+	s.logger(ctx).WithFields(logrus.Fields{
 		"sessionID": request.SessionID,
-		"offset":    request.Offset,
 		"count":     request.Count,
+		"channel":   request.Channel,
+		"recipient": request.Recipient,
+		"beforeID":  request.BeforeID,
+		"afterID":   request.AfterID,
 	}).Info("GetChatHistory")
 
-	offset := 0
-	limit := 10
-	if request.Offset != 0 {
-		offset = int(request.Offset)
-	}
+	// The count is limited before the conversion, so the huge values don't overflow
+	count := uint64(defaultChatHistoryCount)
 	if request.Count != 0 {
-		limit = int(request.Count)
+		count = request.Count
+	}
+	if count > uint64(s.config.ChatHistoryMaxCount) {
+		count = uint64(s.config.ChatHistoryMaxCount)
+	}
+
+	if !model.IsValidChatChannel(int32(request.Channel)) || request.BeforeID < 0 || request.AfterID < 0 {
+		return nil, model.ErrBadRequest
+	}
+
+	filter, modelErr := s.chatFilter(ctx, session, request.Channel, request.Recipient)
+	if modelErr != nil {
+		return nil, modelErr
 	}
 
-	messages, dbErr := session.Tx.GetChatMessages(offset, limit)
+	filter.BeforeID = request.BeforeID
+	filter.AfterID = request.AfterID
+
+	messages, dbErr := session.Tx.GetChatMessages(filter, int(count))
 	if dbErr != nil {
-		s.log.WithError(dbErr).Error("Failed to GetChatMessages")
+		s.logger(ctx).WithError(dbErr).Error("Failed to GetChatMessages")
 		return nil, model.ErrInternalServerError
 	}
 
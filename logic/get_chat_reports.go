@@ -0,0 +1,40 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxChatReportsCount - maximum number of the reports returned at once
+const maxChatReportsCount = 100
+
+func (s *SimpleLogic) GetChatReports(ctx context.Context, session *PlayerSession, request *rpc.GetChatReportsRequest) (*rpc.GetChatReportsResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"count":     request.Count,
+	}).Info("GetChatReports")
+
+	if session.SelectedCharacter.Role() < model.RoleModerator {
+		return nil, model.ErrForbidden
+	}
+
+	count := int(request.Count)
+	if count == 0 || count > maxChatReportsCount {
+		count = maxChatReportsCount
+	}
+
+	reports, err := session.Tx.GetChatReports(count)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chat reports")
+		return nil, model.ErrInternalServerError
+	}
+
+	response := &rpc.GetChatReportsResponse{}
+	for _, report := range reports {
+		response.Reports = append(response.Reports, report.ToRPC())
+	}
+
+	return response, nil
+}
@@ -0,0 +1,39 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetDiplomacy(ctx context.Context, session *PlayerSession, request *rpc.GetDiplomacyRequest) (*rpc.GetDiplomacyResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+	}).Info("GetDiplomacy")
+
+	character := session.SelectedCharacter
+
+	relations, err := session.Tx.GetDiplomaticRelations(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomatic relations")
+		return nil, model.ErrInternalServerError
+	}
+
+	proposals, err := session.Tx.GetDiplomacyProposals(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomacy proposals")
+		return nil, model.ErrInternalServerError
+	}
+
+	response := &rpc.GetDiplomacyResponse{}
+	for _, relation := range relations {
+		response.Relations = append(response.Relations, relation.ToRPC())
+	}
+
+	for _, proposal := range proposals {
+		response.Proposals = append(response.Proposals, proposal.ToRPC())
+	}
+
+	return response, nil
+}
@@ -0,0 +1,50 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetEmpireStatus(ctx context.Context, session *PlayerSession, request *rpc.GetEmpireStatusRequest) (*rpc.GetEmpireStatusResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+	}).Info("GetEmpireStatus")
+
+	character := session.SelectedCharacter
+
+	factors := character.HappinessFactors()
+	happiness := factors.Happiness()
+	efficiency := model.ProductionModifier(happiness)
+
+	// Expected production if the chunks around the towns have enough resources
+	production := character.Workers.HarvestedProduction(character.Workers.Harvest(), efficiency)
+
+	population, maxPopulation := character.Population(), character.MaxPopulation()
+
+	growChance := PopulationGrowChance(happiness)
+	if character.IsStarving() || population >= maxPopulation {
+		growChance = 0
+	}
+
+	return &rpc.GetEmpireStatusResponse{
+		CurrentPopulation:  population,
+		MaxPopulation:      maxPopulation,
+		IdleCount:          character.Workers.Idle(population),
+		Food:               character.Resources().Food,
+		FoodProduction:     production.Food + character.ProductionRate().Food,
+		FoodConsumption:    character.FoodConsumption(),
+		Starving:           character.IsStarving(),
+		StarvingTicks:      character.StarvingTicks,
+		Happiness:          happiness,
+		HappinessFactors:   factors.ToRPC(),
+		GrowChance:         growChance,
+		ProductionModifier: float32(efficiency),
+		TaxRate:            character.TaxRate,
+		Gold:               character.Resources().Gold,
+		TaxIncome:          character.TaxIncome(),
+		Upkeep:             character.Upkeep(),
+		Debt:               character.Debt(),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_GetEmpireStatus(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 20, Resources: model.Resources{Food: 7}})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.Workers = model.WorkDistribution{rpc.JobType_FARMER: 2, rpc.JobType_WOODCUTTER: 3}
+	session.SelectedCharacter.Towns[0].Buildings = []model.Building{model.Buildings[rpc.BuildingType_HOUSE]}
+
+	resp, err := logic.GetEmpireStatus(context.Background(), session, &rpc.GetEmpireStatusRequest{})
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(20), resp.CurrentPopulation)
+	require.Equal(t, uint64(105), resp.MaxPopulation)
+	require.Equal(t, uint64(15), resp.IdleCount)
+	require.Equal(t, uint64(7), resp.Food)
+	require.Equal(t, uint64(5), resp.FoodConsumption)
+	require.False(t, resp.Starving)
+	require.Equal(t, uint64(65), resp.Happiness)
+	require.Equal(t, int64(10), resp.HappinessFactors.FoodVariety)
+	require.Equal(t, int64(-5), resp.HappinessFactors.Taxes)
+	// 2 farmers produce 2 food each, 15% more because of the happiness, and the buildings produce 1 food
+	require.Equal(t, uint64(5), resp.FoodProduction)
+	require.Equal(t, PopulationGrowChance(65), resp.GrowChance)
+}
+
+func TestSimpleLogic_GetEmpireStatus_Starving(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 20})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.StarvingTicks = 2
+
+	resp, err := logic.GetEmpireStatus(context.Background(), session, &rpc.GetEmpireStatusRequest{})
+	require.NoError(t, err)
+
+	require.True(t, resp.Starving)
+	require.Equal(t, uint64(2), resp.StarvingTicks)
+	require.Equal(t, int64(-model.StarvationHappiness), resp.HappinessFactors.Starvation)
+	require.Equal(t, float32(0), resp.GrowChance)
+}
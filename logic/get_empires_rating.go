@@ -3,11 +3,12 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
 )
 
-func (s *SimpleLogic) GetEmpiresRating(session *PlayerSession, request *rpc.GetEmpiresRatingRequest) (*rpc.GetEmpiresRatingResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) GetEmpiresRating(ctx context.Context, session *PlayerSession, request *rpc.GetEmpiresRatingRequest) (*rpc.GetEmpiresRatingResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": request.SessionID,
 		"offset":    request.Offset,
 		"limit":     request.Limit,
@@ -18,10 +19,25 @@ func (s *SimpleLogic) GetEmpiresRating(session *PlayerSession, request *rpc.GetE
 		session.SelectedCharacter.Name, request.Offset, request.Limit, request.Criteria)
 
 	if err != nil {
-		s.log.WithError(err).Error("Failed to get empires rating")
+		s.logger(ctx).WithError(err).Error("Failed to get empires rating")
 		return nil, model.ErrInternalServerError
 	}
 
+	relations, err := session.Tx.GetDiplomaticRelations(session.SelectedCharacter.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomatic relations")
+		return nil, model.ErrInternalServerError
+	}
+
+	states := make(map[string]rpc.DiplomaticState, len(relations))
+	for _, relation := range relations {
+		states[relation.Other] = relation.State
+	}
+
+	for _, entry := range entries {
+		entry.DiplomaticState = states[entry.EmpireName]
+	}
+
 	return &rpc.GetEmpiresRatingResponse{
 		Entries:      entries,
 		PlayerRating: playerEntry,
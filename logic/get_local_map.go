@@ -1,11 +1,14 @@
 package logic
 
 import (
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"database/sql"
 	"errors"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 func getGlobalChunkCoordsForPosition(location rpc.Vector2D, mapChunkSize int) (int64, int64, int64) {
@@ -64,7 +67,7 @@ func getGlobalChunkCoordsForPosition(location rpc.Vector2D, mapChunkSize int) (i
 	return globalX, globalY, localChunkNumber
 }
 
-func (s *SimpleLogic) generateNewLocalChunk(session *PlayerSession, x, y int64, number int32) (*rpc.GetLocalMapResponse, model.Error) {
+func (s *SimpleLogic) generateNewLocalChunk(ctx context.Context, session *PlayerSession, x, y int64, number int32) (*rpc.GetLocalMapResponse, model.Error) {
 	var offsetX, offsetY float64
 	if number == 2 || number == 4 {
 		offsetX = float64(s.MapChunkSize())/2 - 1
@@ -74,7 +77,9 @@ func (s *SimpleLogic) generateNewLocalChunk(session *PlayerSession, x, y int64,
 		offsetY = float64(s.MapChunkSize())/2 - 1
 	}
 
+	start := time.Now()
 	data := s.localGenerator.GenerateTerrain(s.MapChunkSize(), s.MapChunkSize(), offsetX, offsetY)
+	metrics.ObserveSince(metrics.ChunkGenerationDuration.WithLabelValues("local"), start)
 
 	chunk := rpc.WorldMapChunk{
 		X:          int32(x),
@@ -92,13 +97,13 @@ func (s *SimpleLogic) generateNewLocalChunk(session *PlayerSession, x, y int64,
 
 	modelChunk, err := model.NewWorldMapChunkFromRPC(chunk)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to convert rpc chunk to model chunk")
+		s.logger(ctx).WithError(err).Error("Failed to convert rpc chunk to model chunk")
 		return nil, model.ErrInternalServerError
 	}
 
 	modelChunk.Number = number
 	if err := session.Tx.SaveMapChunkOrUpdate(modelChunk); err != nil {
-		s.log.WithError(err).Error("Failed to save local map chunk")
+		s.logger(ctx).WithError(err).Error("Failed to save local map chunk")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -113,8 +118,8 @@ func (s *SimpleLogic) generateNewLocalChunk(session *PlayerSession, x, y int64,
 	}, nil
 }
 
-func (s *SimpleLogic) GetLocalMap(session *PlayerSession, request *rpc.GetLocalMapRequest) (*rpc.GetLocalMapResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) GetLocalMap(ctx context.Context, session *PlayerSession, request *rpc.GetLocalMapRequest) (*rpc.GetLocalMapResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 		"location":  *request.Location,
 	}).Info("GetLocalMap")
@@ -122,15 +127,15 @@ func (s *SimpleLogic) GetLocalMap(session *PlayerSession, request *rpc.GetLocalM
 	globalChunkX, globalChunkY, number := getGlobalChunkCoordsForPosition(*request.Location, s.MapChunkSize())
 	chunk, err := session.Tx.GetMapChunk(globalChunkX, globalChunkY, number)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		return s.generateNewLocalChunk(session, globalChunkX, globalChunkY, int32(number))
+		return s.generateNewLocalChunk(ctx, session, globalChunkX, globalChunkY, int32(number))
 	} else if err != nil {
-		s.log.WithError(err).Error("Failed to get map chunk")
+		s.logger(ctx).WithError(err).Error("Failed to get map chunk")
 		return nil, model.ErrInternalServerError
 	}
 
 	local, err := chunk.ToLocalRPC()
 	if err != nil {
-		s.log.WithError(err).Error("Failed to convert chunk to local")
+		s.logger(ctx).WithError(err).Error("Failed to convert chunk to local")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -3,6 +3,7 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"database/sql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -52,7 +53,7 @@ func TestSimpleLogic_GetLocalMap(t *testing.T) {
 	generator.On("GenerateTerrain", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return([]float32{10.0, 10.0})
 
-	response, err := logic.GetLocalMap(session, request)
+	response, err := logic.GetLocalMap(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	require.NotNil(t, response.Map)
@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetMarketOrders(ctx context.Context, session *PlayerSession, request *rpc.GetMarketOrdersRequest) (*rpc.GetMarketOrdersResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"resource":  request.Resource,
+		"own":       request.Own,
+		"offset":    request.Offset,
+		"limit":     request.Limit,
+	}).Info("GetMarketOrders")
+
+	if !model.IsValidResourceType(int32(request.Resource)) {
+		return nil, model.ErrBadRequest
+	}
+
+	character := session.SelectedCharacter
+	response := &rpc.GetMarketOrdersResponse{}
+
+	if request.Own {
+		orders, err := session.Tx.GetCharacterMarketOrders(character.ID)
+		if err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to get character market orders")
+			return nil, model.ErrInternalServerError
+		}
+
+		for _, order := range orders {
+			if order.Side == rpc.OrderSide_BUY {
+				response.BuyOrders = append(response.BuyOrders, order.ToRPC(character.ID))
+			} else {
+				response.SellOrders = append(response.SellOrders, order.ToRPC(character.ID))
+			}
+		}
+
+		return response, nil
+	}
+
+	offset, limit := marketPage(request.Offset, request.Limit)
+
+	buyOrders, err := session.Tx.GetMarketOrders(request.Resource, rpc.OrderSide_BUY, offset, limit)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get buy orders")
+		return nil, model.ErrInternalServerError
+	}
+
+	sellOrders, err := session.Tx.GetMarketOrders(request.Resource, rpc.OrderSide_SELL, offset, limit)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get sell orders")
+		return nil, model.ErrInternalServerError
+	}
+
+	for _, order := range buyOrders {
+		response.BuyOrders = append(response.BuyOrders, order.ToRPC(character.ID))
+	}
+
+	for _, order := range sellOrders {
+		response.SellOrders = append(response.SellOrders, order.ToRPC(character.ID))
+	}
+
+	return response, nil
+}
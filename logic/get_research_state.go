@@ -0,0 +1,25 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+)
+
+func (s *SimpleLogic) GetResearchState(ctx context.Context, session *PlayerSession, request *rpc.GetResearchStateRequest) (*rpc.GetResearchStateResponse, model.Error) {
+	s.logger(ctx).WithField("sessionID", session.SessionID).Info("GetResearchState")
+
+	character := session.SelectedCharacter
+
+	response := &rpc.GetResearchStateResponse{}
+	for _, id := range model.TechnologyTypes() {
+		response.Technologies = append(response.Technologies,
+			model.TechnologyTree[id].ToInfoRPC(character.Technologies[id]))
+	}
+
+	if character.Research != nil {
+		response.Current = character.Research.ToRPC()
+	}
+
+	return response, nil
+}
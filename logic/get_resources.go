@@ -3,12 +3,13 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
 )
 
 func (s *SimpleLogic) GetResources(
-	session *PlayerSession, request *rpc.GetResourcesRequest) (*rpc.GetResourcesResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+	ctx context.Context, session *PlayerSession, request *rpc.GetResourcesRequest) (*rpc.GetResourcesResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 	}).Info("GetResources")
 
@@ -16,13 +17,15 @@ func (s *SimpleLogic) GetResources(
 		return nil, model.ErrCharacterNotSelected
 	}
 
-	resources, err := session.Tx.GetResources(session.SelectedCharacter.ID)
-	if err != nil {
-		s.log.WithError(err).Error("Failed to get resources")
-		return nil, model.ErrInternalServerError
+	character := session.SelectedCharacter
+
+	response := &rpc.GetResourcesResponse{
+		Resources: character.Resources().ToRPC(),
+	}
+
+	for _, town := range character.Towns {
+		response.Towns = append(response.Towns, town.ResourcesToRPC(character.TaxRate))
 	}
 
-	return &rpc.GetResourcesResponse{
-		Resources: resources.ToRPC(),
-	}, nil
+	return response, nil
 }
@@ -0,0 +1,34 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetTownDetails(ctx context.Context, session *PlayerSession, request *rpc.GetTownDetailsRequest) (*rpc.GetTownDetailsResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"townID":    request.TownID,
+	}).Info("GetTownDetails")
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	buildings := make([]*rpc.TownBuilding, 0, len(town.Buildings))
+	for _, building := range town.Buildings {
+		buildings = append(buildings, building.ToRPC())
+	}
+
+	return &rpc.GetTownDetailsResponse{
+		Town:              town.ToRPC(),
+		Resources:         town.ResourcesToRPC(session.SelectedCharacter.TaxRate),
+		MaxPopulation:     town.MaxPopulation(),
+		Buildings:         buildings,
+		ConstructionQueue: town.ConstructionToRPC(s.config.ConstructionSlots),
+		ConstructionSlots: uint64(s.config.ConstructionSlots),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) GetTradeHistory(ctx context.Context, session *PlayerSession, request *rpc.GetTradeHistoryRequest) (*rpc.GetTradeHistoryResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"resource":  request.Resource,
+		"own":       request.Own,
+		"offset":    request.Offset,
+		"limit":     request.Limit,
+	}).Info("GetTradeHistory")
+
+	if !model.IsValidResourceType(int32(request.Resource)) {
+		return nil, model.ErrBadRequest
+	}
+
+	offset, limit := marketPage(request.Offset, request.Limit)
+
+	var trades []model.Trade
+	var err error
+	if request.Own {
+		trades, err = session.Tx.GetCharacterTrades(session.SelectedCharacter.ID, offset, limit)
+	} else {
+		trades, err = session.Tx.GetTrades(request.Resource, offset, limit)
+	}
+
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get trades")
+		return nil, model.ErrInternalServerError
+	}
+
+	response := &rpc.GetTradeHistoryResponse{}
+	for _, trade := range trades {
+		response.Trades = append(response.Trades, trade.ToRPC())
+	}
+
+	return response, nil
+}
@@ -3,13 +3,27 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
 )
 
-func (s *SimpleLogic) GetWorkDistribution(session *PlayerSession, request *rpc.GetWorkDistributionRequest) (*rpc.GetWorkDistributionResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) GetWorkDistribution(ctx context.Context, session *PlayerSession, request *rpc.GetWorkDistributionRequest) (*rpc.GetWorkDistributionResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 	}).Info("GetWorkDistribution")
 
-	return &session.WorkDistribution, nil
+	character := session.SelectedCharacter
+
+	buildings, err := session.Tx.GetCharacterBuildings(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character buildings")
+		return nil, model.ErrInternalServerError
+	}
+
+	return &rpc.GetWorkDistributionResponse{
+		IdleCount:       character.Workers.Idle(character.Population()),
+		WoodcutterCount: character.Workers[rpc.JobType_WOODCUTTER],
+		Jobs:            character.Workers.ToRPC(),
+		UnlockedJobs:    model.UnlockedJobs(buildings),
+	}, nil
 }
@@ -1,9 +1,11 @@
 package logic
 
 import (
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -11,6 +13,31 @@ import (
 	"time"
 )
 
+// terrainFields - summary of the terrain heights to log instead of the whole chunk
+func terrainFields(terrain []float32) log.Fields {
+	fields := log.Fields{"points": len(terrain)}
+	if len(terrain) == 0 {
+		return fields
+	}
+
+	min, max, sum := terrain[0], terrain[0], 0.0
+	for _, height := range terrain {
+		if height < min {
+			min = height
+		}
+		if height > max {
+			max = height
+		}
+		sum += float64(height)
+	}
+
+	fields["minHeight"] = min
+	fields["maxHeight"] = max
+	fields["avgHeight"] = sum / float64(len(terrain))
+
+	return fields
+}
+
 func (s *SimpleLogic) saveChunk(chunk rpc.WorldMapChunk, session *PlayerSession) error {
 	modelChunk, err := model.NewWorldMapChunkFromRPC(chunk)
 	if err != nil {
@@ -20,24 +47,25 @@ func (s *SimpleLogic) saveChunk(chunk rpc.WorldMapChunk, session *PlayerSession)
 	return session.Tx.SaveMapChunkOrUpdate(modelChunk)
 }
 
-func (s *SimpleLogic) generateAndSaveMapChunk(x, y int, session *PlayerSession) (*rpc.WorldMapChunk, error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) generateAndSaveMapChunk(ctx context.Context, x, y int, session *PlayerSession) (*rpc.WorldMapChunk, error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"x": x,
 		"y": y,
 	}).Info("Generating map chunk")
 
+	start := time.Now()
 	terrain := s.generator.GenerateTerrain(
 		s.config.ChunkSize,
 		s.config.ChunkSize,
 		float64(s.config.ChunkSize*x),
 		float64(s.config.ChunkSize*y))
+	metrics.ObserveSince(metrics.ChunkGenerationDuration.WithLabelValues("global"), start)
 
 	if s.config.DebugTerrain {
-		s.log.WithFields(log.Fields{
-			"locationX": x,
-			"locationY": y,
-			"data":      terrain,
-		}).Debugf("Chunk generated")
+		s.logger(ctx).WithFields(terrainFields(terrain)).
+			WithField("locationX", x).
+			WithField("locationY", y).
+			Debugf("Chunk generated")
 	}
 
 	chunk := rpc.WorldMapChunk{
@@ -47,10 +75,10 @@ func (s *SimpleLogic) generateAndSaveMapChunk(x, y int, session *PlayerSession)
 		Height:     int32(s.config.ChunkSize),
 		Data:       terrain,
 		Towns:      []*rpc.Town{},
-		Trees:      0,
-		Stones:     0,
-		Animals:    0,
-		Plants:     0,
+		Trees:      model.ChunkResourcesLimit.Trees,
+		Stones:     model.ChunkResourcesLimit.Stones,
+		Animals:    model.ChunkResourcesLimit.Animals,
+		Plants:     model.ChunkResourcesLimit.Plants,
 		WaterLevel: s.config.WaterLevel,
 	}
 
@@ -61,17 +89,17 @@ func (s *SimpleLogic) generateAndSaveMapChunk(x, y int, session *PlayerSession)
 	return &chunk, nil
 }
 
-func (s *SimpleLogic) GetWorldMap(session *PlayerSession, request *rpc.GetWorldMapRequest) (*rpc.GetWorldMapResponse, model.Error) {
-	s.log.WithField("location", request.GetLocation()).
+func (s *SimpleLogic) GetWorldMap(ctx context.Context, session *PlayerSession, request *rpc.GetWorldMapRequest) (*rpc.GetWorldMapResponse, model.Error) {
+	s.logger(ctx).WithField("location", request.GetLocation()).
 		WithField("sessionID", request.GetSessionID()).
 		Infof("GetMap request")
 
 	newChunk := func() (*rpc.GetWorldMapResponse, model.Error) {
-		s.log.WithField("alwaysGenerate", s.config.AlwaysRegenerateMap).
+		s.logger(ctx).WithField("alwaysGenerate", s.config.AlwaysRegenerateMap).
 			WithField("location", request.GetLocation()).Info("Generating chunk")
 
-		if newChunk, err := s.generateAndSaveMapChunk(int(request.Location.X), int(request.Location.Y), session); err != nil {
-			s.log.WithError(err).Error("Failed to regenerate game map")
+		if newChunk, err := s.generateAndSaveMapChunk(ctx, int(request.Location.X), int(request.Location.Y), session); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to regenerate game map")
 			return nil, model.ErrInternalServerError
 		} else {
 			return &rpc.GetWorldMapResponse{Map: newChunk}, nil
@@ -88,7 +116,7 @@ func (s *SimpleLogic) GetWorldMap(session *PlayerSession, request *rpc.GetWorldM
 
 	chunk, err := tx.GetMapChunk(int64(request.Location.X), int64(request.Location.Y), consts.GlobalChunkNumber)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		s.log.WithError(err).Error("Failed to get map chunk")
+		s.logger(ctx).WithError(err).Error("Failed to get map chunk")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -99,15 +127,15 @@ func (s *SimpleLogic) GetWorldMap(session *PlayerSession, request *rpc.GetWorldM
 	}
 
 	if s.config.DebugTerrain {
-		s.log.WithFields(log.Fields{
+		s.logger(ctx).WithFields(log.Fields{
 			"location": *request.Location,
-			"data":     chunk.Data,
+			"bytes":    len(chunk.Data),
 		}).Debugf("Return existing chunk")
 	}
 
 	rpcChunk, err := chunk.ToRPC()
 	if err != nil {
-		s.log.WithError(err).Error("Failed to convert map chunk to the rpc chunk")
+		s.logger(ctx).WithError(err).Error("Failed to convert map chunk to the rpc chunk")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -116,21 +144,42 @@ func (s *SimpleLogic) GetWorldMap(session *PlayerSession, request *rpc.GetWorldM
 	yStart := int(request.Location.Y) * s.MapChunkSize()
 	yEnd := yStart + s.MapChunkSize()
 
-	towns, err := tx.GetTownsForRect(xStart, xEnd, yStart, yEnd)
+	// Towns outside of the chunk may still reach it with their territories
+	towns, err := getTownsAround(float32(xStart), float32(xEnd), float32(yStart), float32(yEnd), tx)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to get chunk towns")
+		s.logger(ctx).WithError(err).Error("Failed to get chunk towns")
 		return nil, model.ErrInternalServerError
 	}
 
 	for _, town := range towns {
-		rpcChunk.Towns = append(rpcChunk.Towns, town.ToRPC())
+		if town.X >= int64(xStart) && town.X <= int64(xEnd) && town.Y >= int64(yStart) && town.Y <= int64(yEnd) {
+			rpcChunk.Towns = append(rpcChunk.Towns, town.ToRPC())
+		}
+
+		if town.TerritoryIntersects(xStart, xEnd, yStart, yEnd) {
+			rpcChunk.Territories = append(rpcChunk.Territories, town.TerritoryToRPC())
+		}
+	}
+
+	caravans, err := tx.GetCaravansForRect(xStart, xEnd, yStart, yEnd)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chunk caravans")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Only the caravans crossing the chunk right now
+	for _, caravan := range caravans {
+		location := caravan.Location()
+		if location.X >= float32(xStart) && location.X < float32(xEnd) &&
+			location.Y >= float32(yStart) && location.Y < float32(yEnd) {
+			rpcChunk.Caravans = append(rpcChunk.Caravans, caravan.ToRPC())
+		}
 	}
 
 	if s.config.DebugTerrain {
-		s.log.WithFields(log.Fields{
-			"location": *request.Location,
-			"data":     rpcChunk.Data,
-		}).Debugf("Chunk after converting to rpc")
+		s.logger(ctx).WithFields(terrainFields(rpcChunk.Data)).
+			WithField("location", request.Location).
+			Debugf("Chunk after converting to rpc")
 	}
 
 	return &rpc.GetWorldMapResponse{Map: rpcChunk}, nil
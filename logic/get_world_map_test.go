@@ -4,6 +4,7 @@ import (
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"database/sql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -30,7 +31,7 @@ func TestSimpleLogic_GetWorldMap_GeneratedWhenNoMapIsFound(t *testing.T) {
 	generator.On("GenerateTerrain", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return([]float32{10.0, 10.0})
 
-	response, err := logic.GetWorldMap(session, request)
+	response, err := logic.GetWorldMap(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	require.NotNil(t, response.Map)
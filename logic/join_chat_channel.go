@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) JoinChatChannel(ctx context.Context, session *PlayerSession, request *rpc.JoinChatChannelRequest) (*rpc.JoinChatChannelResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"channel":   request.Channel,
+	}).Info("JoinChatChannel")
+
+	if !model.IsJoinableChatChannel(request.Channel) {
+		return nil, model.ErrBadRequest
+	}
+
+	character := session.SelectedCharacter
+	membership := model.ChatMembership{
+		CharacterID: character.ID,
+		Channel:     request.Channel,
+	}
+
+	if request.Channel == rpc.ChatMessage_REGION {
+		region, found := s.chatRegion(*character)
+		if !found {
+			return nil, model.ErrTownNotFound
+		}
+
+		membership.Region = region
+	}
+
+	if err := session.Tx.JoinChatChannel(membership); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to join chat channel")
+		return nil, model.ErrInternalServerError
+	}
+
+	channels, err := s.chatChannelsToRPC(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.JoinChatChannelResponse{Channels: channels}, nil
+}
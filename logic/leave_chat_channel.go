@@ -0,0 +1,31 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) LeaveChatChannel(ctx context.Context, session *PlayerSession, request *rpc.LeaveChatChannelRequest) (*rpc.LeaveChatChannelResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"channel":   request.Channel,
+	}).Info("LeaveChatChannel")
+
+	if !model.IsJoinableChatChannel(request.Channel) {
+		return nil, model.ErrBadRequest
+	}
+
+	if err := session.Tx.LeaveChatChannel(session.SelectedCharacter.ID, request.Channel); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to leave chat channel")
+		return nil, model.ErrInternalServerError
+	}
+
+	channels, err := s.chatChannelsToRPC(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.LeaveChatChannelResponse{Channels: channels}, nil
+}
@@ -7,36 +7,75 @@ import (
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"abbysoft/gardarike-online/tracing"
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"sync"
 	"time"
 )
 
 type Logic interface {
-	GetWorldMap(session *PlayerSession, request *rpc.GetWorldMapRequest) (*rpc.GetWorldMapResponse, model.Error)
-	Login(request *rpc.LoginRequest) (*rpc.LoginResponse, model.Error)
-	SelectCharacter(session *PlayerSession, request *rpc.SelectCharacterRequest) (*rpc.SelectCharacterResponse, model.Error)
-	SendChatMessage(session *PlayerSession, request *rpc.SendChatMessageRequest) (*rpc.SendChatMessageResponse, model.Error)
-	GetChatHistory(session *PlayerSession, request *rpc.GetChatHistoryRequest) (*rpc.GetChatHistoryResponse, model.Error)
-	GetWorkDistribution(session *PlayerSession, request *rpc.GetWorkDistributionRequest) (*rpc.GetWorkDistributionResponse, model.Error)
-	CreateAccount(request *rpc.CreateAccountRequest) (*rpc.CreateAccountResponse, model.Error)
-	CreateCharacter(session *PlayerSession, request *rpc.CreateCharacterRequest) (*rpc.CreateCharacterResponse, model.Error)
-	GetResources(session *PlayerSession, request *rpc.GetResourcesRequest) (*rpc.GetResourcesResponse, model.Error)
-	PlaceTown(session *PlayerSession, request *rpc.PlaceTownRequest) (*rpc.PlaceTownResponse, model.Error)
-	PlaceBuilding(session *PlayerSession, request *rpc.PlaceBuildingRequest) (*rpc.PlaceBuildingResponse, model.Error)
-	GetEmpiresRating(session *PlayerSession, request *rpc.GetEmpiresRatingRequest) (*rpc.GetEmpiresRatingResponse, model.Error)
-	RenameTown(session *PlayerSession, request *rpc.RenameTownRequest) (*rpc.RenameTownResponse, model.Error)
-	GetLocalMap(session *PlayerSession, request *rpc.GetLocalMapRequest) (*rpc.GetLocalMapResponse, model.Error)
+	GetWorldMap(ctx context.Context, session *PlayerSession, request *rpc.GetWorldMapRequest) (*rpc.GetWorldMapResponse, model.Error)
+	Login(ctx context.Context, request *rpc.LoginRequest) (*rpc.LoginResponse, model.Error)
+	SelectCharacter(ctx context.Context, session *PlayerSession, request *rpc.SelectCharacterRequest) (*rpc.SelectCharacterResponse, model.Error)
+	SendChatMessage(ctx context.Context, session *PlayerSession, request *rpc.SendChatMessageRequest) (*rpc.SendChatMessageResponse, model.Error)
+	GetChatHistory(ctx context.Context, session *PlayerSession, request *rpc.GetChatHistoryRequest) (*rpc.GetChatHistoryResponse, model.Error)
+	GetWorkDistribution(ctx context.Context, session *PlayerSession, request *rpc.GetWorkDistributionRequest) (*rpc.GetWorkDistributionResponse, model.Error)
+	SetWorkDistribution(ctx context.Context, session *PlayerSession, request *rpc.SetWorkDistributionRequest) (*rpc.SetWorkDistributionResponse, model.Error)
+	CreateAccount(ctx context.Context, request *rpc.CreateAccountRequest) (*rpc.CreateAccountResponse, model.Error)
+	CreateCharacter(ctx context.Context, session *PlayerSession, request *rpc.CreateCharacterRequest) (*rpc.CreateCharacterResponse, model.Error)
+	GetResources(ctx context.Context, session *PlayerSession, request *rpc.GetResourcesRequest) (*rpc.GetResourcesResponse, model.Error)
+	PlaceTown(ctx context.Context, session *PlayerSession, request *rpc.PlaceTownRequest) (*rpc.PlaceTownResponse, model.Error)
+	PlaceBuilding(ctx context.Context, session *PlayerSession, request *rpc.PlaceBuildingRequest) (*rpc.PlaceBuildingResponse, model.Error)
+	GetEmpiresRating(ctx context.Context, session *PlayerSession, request *rpc.GetEmpiresRatingRequest) (*rpc.GetEmpiresRatingResponse, model.Error)
+	RenameTown(ctx context.Context, session *PlayerSession, request *rpc.RenameTownRequest) (*rpc.RenameTownResponse, model.Error)
+	GetLocalMap(ctx context.Context, session *PlayerSession, request *rpc.GetLocalMapRequest) (*rpc.GetLocalMapResponse, model.Error)
+	GetEmpireStatus(ctx context.Context, session *PlayerSession, request *rpc.GetEmpireStatusRequest) (*rpc.GetEmpireStatusResponse, model.Error)
+	CancelConstruction(ctx context.Context, session *PlayerSession, request *rpc.CancelConstructionRequest) (*rpc.CancelConstructionResponse, model.Error)
+	GetTownDetails(ctx context.Context, session *PlayerSession, request *rpc.GetTownDetailsRequest) (*rpc.GetTownDetailsResponse, model.Error)
+	UpgradeBuilding(ctx context.Context, session *PlayerSession, request *rpc.UpgradeBuildingRequest) (*rpc.UpgradeBuildingResponse, model.Error)
+	DemolishBuilding(ctx context.Context, session *PlayerSession, request *rpc.DemolishBuildingRequest) (*rpc.DemolishBuildingResponse, model.Error)
+	MoveBuilding(ctx context.Context, session *PlayerSession, request *rpc.MoveBuildingRequest) (*rpc.MoveBuildingResponse, model.Error)
+	GetBuildingCatalog(ctx context.Context, session *PlayerSession, request *rpc.GetBuildingCatalogRequest) (*rpc.GetBuildingCatalogResponse, model.Error)
+	StartResearch(ctx context.Context, session *PlayerSession, request *rpc.StartResearchRequest) (*rpc.StartResearchResponse, model.Error)
+	GetResearchState(ctx context.Context, session *PlayerSession, request *rpc.GetResearchStateRequest) (*rpc.GetResearchStateResponse, model.Error)
+	SetTaxRate(ctx context.Context, session *PlayerSession, request *rpc.SetTaxRateRequest) (*rpc.SetTaxRateResponse, model.Error)
+	PlaceMarketOrder(ctx context.Context, session *PlayerSession, request *rpc.PlaceMarketOrderRequest) (*rpc.PlaceMarketOrderResponse, model.Error)
+	CancelMarketOrder(ctx context.Context, session *PlayerSession, request *rpc.CancelMarketOrderRequest) (*rpc.CancelMarketOrderResponse, model.Error)
+	GetMarketOrders(ctx context.Context, session *PlayerSession, request *rpc.GetMarketOrdersRequest) (*rpc.GetMarketOrdersResponse, model.Error)
+	GetTradeHistory(ctx context.Context, session *PlayerSession, request *rpc.GetTradeHistoryRequest) (*rpc.GetTradeHistoryResponse, model.Error)
+	SendCaravan(ctx context.Context, session *PlayerSession, request *rpc.SendCaravanRequest) (*rpc.SendCaravanResponse, model.Error)
+	TrainUnits(ctx context.Context, session *PlayerSession, request *rpc.TrainUnitsRequest) (*rpc.TrainUnitsResponse, model.Error)
+	CreateArmy(ctx context.Context, session *PlayerSession, request *rpc.CreateArmyRequest) (*rpc.CreateArmyResponse, model.Error)
+	MoveArmy(ctx context.Context, session *PlayerSession, request *rpc.MoveArmyRequest) (*rpc.MoveArmyResponse, model.Error)
+	DisbandArmy(ctx context.Context, session *PlayerSession, request *rpc.DisbandArmyRequest) (*rpc.DisbandArmyResponse, model.Error)
+	GetArmies(ctx context.Context, session *PlayerSession, request *rpc.GetArmiesRequest) (*rpc.GetArmiesResponse, model.Error)
+	AttackTown(ctx context.Context, session *PlayerSession, request *rpc.AttackTownRequest) (*rpc.AttackTownResponse, model.Error)
+	ProposeDiplomacy(ctx context.Context, session *PlayerSession, request *rpc.ProposeDiplomacyRequest) (*rpc.ProposeDiplomacyResponse, model.Error)
+	AnswerDiplomacyProposal(ctx context.Context, session *PlayerSession, request *rpc.AnswerDiplomacyProposalRequest) (*rpc.AnswerDiplomacyProposalResponse, model.Error)
+	GetDiplomacy(ctx context.Context, session *PlayerSession, request *rpc.GetDiplomacyRequest) (*rpc.GetDiplomacyResponse, model.Error)
+	JoinChatChannel(ctx context.Context, session *PlayerSession, request *rpc.JoinChatChannelRequest) (*rpc.JoinChatChannelResponse, model.Error)
+	LeaveChatChannel(ctx context.Context, session *PlayerSession, request *rpc.LeaveChatChannelRequest) (*rpc.LeaveChatChannelResponse, model.Error)
+	GetChatChannels(ctx context.Context, session *PlayerSession, request *rpc.GetChatChannelsRequest) (*rpc.GetChatChannelsResponse, model.Error)
+	ReportChatMessage(ctx context.Context, session *PlayerSession, request *rpc.ReportChatMessageRequest) (*rpc.ReportChatMessageResponse, model.Error)
+	ModerateCharacter(ctx context.Context, session *PlayerSession, request *rpc.ModerateCharacterRequest) (*rpc.ModerateCharacterResponse, model.Error)
+	GetChatReports(ctx context.Context, session *PlayerSession, request *rpc.GetChatReportsRequest) (*rpc.GetChatReportsResponse, model.Error)
 }
 
 type SimpleLogic struct {
 	db              db2.Database
 	log             *logrus.Entry
 	sessions        map[string]*PlayerSession
+	sessionsMutex   sync.RWMutex
+	characterNames  map[string]string // Names of the selected characters by session IDs, guarded by sessionsMutex
+	loopStats       GameLoopStats
+	loopStatsMutex  sync.RWMutex
 	EventsChan      chan model.EventWrapper
 	config          Config
 	resourceManager ResourceManager
+	randomEvent     func(chance float32) bool   // Checks if the random event of 'chance' percent freq has happened
 	generator       generation.TerrainGenerator // Generator using to generate global chunks
 	localGenerator  generation.TerrainGenerator // Generator using to generate local chunks
 }
@@ -44,10 +83,14 @@ type SimpleLogic struct {
 type Config struct {
 	AFKTimeout           time.Duration
 	ChatMessageMaxLength int
+	ChatBannedWords      []string      // Words masked in the player chat messages
+	ChatHistoryMaxCount  int           // Maximum number of the chat messages returned at once
+	ChatRetention        time.Duration // Chat messages older than this are deleted, zero keeps them forever
 	WaterLevel           float32
 	ChunkSize            int
 	AlwaysRegenerateMap  bool
 	DebugTerrain         bool
+	ConstructionSlots    int // Number of buildings constructed in parallel in every town
 }
 
 func NewLogic(
@@ -70,10 +113,12 @@ func NewLogic(
 		db:             database,
 		log:            logrus.WithField("module", "logic"),
 		sessions:       make(map[string]*PlayerSession),
+		characterNames: make(map[string]string),
 		EventsChan:     eventsChan,
 		config:         config,
 		generator:      generator,
 		localGenerator: localGenerator,
+		randomEvent:    CheckRandomEventHappened,
 	}
 
 	logic.resourceManager = NewResourceManager(logic)
@@ -84,8 +129,8 @@ func NewLogic(
 	return logic, nil
 }
 
-func (s *SimpleLogic) SelectCharacter(session *PlayerSession, request *rpc.SelectCharacterRequest) (*rpc.SelectCharacterResponse, model.Error) {
-	s.log.WithField("characterID", request.GetCharacterID()).
+func (s *SimpleLogic) SelectCharacter(ctx context.Context, session *PlayerSession, request *rpc.SelectCharacterRequest) (*rpc.SelectCharacterResponse, model.Error) {
+	s.logger(ctx).WithField("characterID", request.GetCharacterID()).
 		WithField("sessionID", request.GetSessionID()).
 		Infof("SelectCharacter request")
 
@@ -95,7 +140,7 @@ func (s *SimpleLogic) SelectCharacter(session *PlayerSession, request *rpc.Selec
 	if err != nil && err.Error() == sql.ErrNoRows.Error() {
 		return nil, model.ErrCharacterNotFound
 	} else if err != nil {
-		s.log.WithError(err).Error("Failed to get character")
+		s.logger(ctx).WithError(err).Error("Failed to get character")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -103,23 +148,29 @@ func (s *SimpleLogic) SelectCharacter(session *PlayerSession, request *rpc.Selec
 		return nil, model.ErrForbidden
 	}
 
+	if char.IsBanned(time.Now()) {
+		return nil, model.ErrCharacterBanned
+	}
+
 	towns, err := tx.GetTowns(char.Name)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to get character's towns")
+		s.logger(ctx).WithError(err).Error("Failed to get character's towns")
 		return nil, model.ErrInternalServerError
 	} else {
 		char.Towns = towns
+		char.ShareTechnologies()
 	}
 
 	session.SelectedCharacter = &char
-	s.log.WithFields(logrus.Fields{
+	s.setSessionCharacter(session.SessionID, char.Name)
+	s.logger(ctx).WithFields(logrus.Fields{
 		"sessionID": request.GetSessionID(),
 		"character": char,
 	}).Info("User selected character")
 
-	s.EventsChan <- model.NewSystemChatMessageEvent(consts.MessageCharacterAuthorized(char.Name))
+	s.publishEvent(ctx, model.NewSystemChatMessageEvent(consts.MessageCharacterAuthorized(char.Name)))
 
-	response := &rpc.SelectCharacterResponse{Resources: char.Resources.ToRPC()}
+	response := &rpc.SelectCharacterResponse{Resources: char.Resources().ToRPC()}
 	for _, town := range char.Towns {
 		response.Towns = append(response.Towns, town.ToRPC())
 	}
@@ -127,6 +178,40 @@ func (s *SimpleLogic) SelectCharacter(session *PlayerSession, request *rpc.Selec
 	return response, nil
 }
 
-func (s SimpleLogic) MapChunkSize() int {
+// logger - returns the logic logger tagged with the request info from the context
+func (s *SimpleLogic) logger(ctx context.Context) *logrus.Entry {
+	return tracing.Logger(ctx, s.log)
+}
+
+// pendingEventsKey - the context key of the events held until the transaction is committed
+type pendingEventsKey struct{}
+
+// withPendingEvents - returns the context which holds the published events instead of sending them,
+// the events are sent by sendPendingEvents after the transaction is committed
+func withPendingEvents(ctx context.Context) (context.Context, *[]model.EventWrapper) {
+	var events []model.EventWrapper
+	return context.WithValue(ctx, pendingEventsKey{}, &events), &events
+}
+
+// sendPendingEvents - sends the events held by the context to the clients
+func (s *SimpleLogic) sendPendingEvents(events *[]model.EventWrapper) {
+	for _, event := range *events {
+		s.EventsChan <- event
+	}
+}
+
+// publishEvent - tags the event with the request ID and sends it to the clients,
+// the event is held if the context holds the events of the uncommitted transaction
+func (s *SimpleLogic) publishEvent(ctx context.Context, event model.EventWrapper) {
+	event.Event.RequestID = tracing.RequestID(ctx)
+	if events, ok := ctx.Value(pendingEventsKey{}).(*[]model.EventWrapper); ok {
+		*events = append(*events, event)
+		return
+	}
+
+	s.EventsChan <- event
+}
+
+func (s *SimpleLogic) MapChunkSize() int {
 	return s.config.ChunkSize
 }
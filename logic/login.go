@@ -3,6 +3,7 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"errors"
@@ -18,12 +19,12 @@ func saltPassword(password, salt string) string {
 	return fmt.Sprintf("%x", string(finalPass[:]))
 }
 
-func (s *SimpleLogic) Login(request *rpc.LoginRequest) (*rpc.LoginResponse, model.Error) {
-	s.log.WithField("login", request.GetUsername()).Info("Login request")
+func (s *SimpleLogic) Login(ctx context.Context, request *rpc.LoginRequest) (*rpc.LoginResponse, model.Error) {
+	s.logger(ctx).WithField("login", request.GetUsername()).Info("Login request")
 
-	tx, err := s.db.BeginTransaction(false, true)
+	tx, err := s.db.BeginTransaction(ctx, false, true)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to begin transaction")
+		s.logger(ctx).WithError(err).Error("Failed to begin transaction")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -31,7 +32,7 @@ func (s *SimpleLogic) Login(request *rpc.LoginRequest) (*rpc.LoginResponse, mode
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, model.ErrInvalidUserPassword
 	} else if err != nil {
-		s.log.WithError(err).Error("Failed to get account from the database")
+		s.logger(ctx).WithError(err).Error("Failed to get account from the database")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -41,21 +42,21 @@ func (s *SimpleLogic) Login(request *rpc.LoginRequest) (*rpc.LoginResponse, mode
 
 	chars, err := tx.GetCharacters(acc.ID)
 	if err != nil {
-		s.log.WithError(err).WithField("accID", acc.ID).
+		s.logger(ctx).WithError(err).WithField("accID", acc.ID).
 			Error("Failed to get characters for account")
 		return nil, model.ErrInternalServerError
 	}
 
 	if err := tx.EndTransaction(); err != nil {
-		s.log.WithError(err).Error("Failed to commit transactions")
+		s.logger(ctx).WithError(err).Error("Failed to commit transactions")
 		return nil, model.ErrInternalServerError
 	}
 
 	session := NewPlayerSession(acc.ID)
 
-	s.sessions[session.SessionID] = session
+	s.addSession(session)
 
-	s.log.WithFields(log.Fields{
+	s.logger(ctx).WithFields(log.Fields{
 		"accID":     acc.ID,
 		"login":     acc.Login,
 		"sessionID": session.SessionID,
@@ -3,6 +3,7 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"database/sql"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -19,7 +20,7 @@ func TestSimpleLogic_Login_InvalidUsername(t *testing.T) {
 
 	db.On("GetAccount", "John").Return(model.Account{}, sql.ErrNoRows)
 
-	_, err := logic.Login(request)
+	_, err := logic.Login(context.Background(), request)
 
 	assert.EqualError(t, err, model.ErrInvalidUserPassword.Error())
 	db.AssertExpectations(t)
@@ -41,7 +42,7 @@ func TestSimpleLogic_Login_InvalidPassword(t *testing.T) {
 		LastSessionID: "",
 	}, nil)
 
-	_, err := logic.Login(request)
+	_, err := logic.Login(context.Background(), request)
 	assert.EqualError(t, err, model.ErrInvalidUserPassword.Error())
 	db.AssertExpectations(t)
 }
@@ -69,7 +70,7 @@ func TestSimpleLogic_Login(t *testing.T) {
 	db.On("GetAccount", "test").Return(account, nil)
 	db.On("GetCharacters", account.ID).Return(characters, nil)
 
-	resp, err := logic.Login(request)
+	resp, err := logic.Login(context.Background(), request)
 	if !assert.NoError(t, err) {
 		return
 	}
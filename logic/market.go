@@ -0,0 +1,111 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMarketPageSize = 20
+	maxMarketPageSize     = 100
+)
+
+// matchMarketOrder - fills the saved order with the matching orders of the order book at their prices.
+// The filled book orders are updated and their goods are left for delivery to their owners,
+// the caller delivers the goods of the order. The orders of the empires at war are skipped, the fee of the trade
+// depends on the relations of the empires. Returns the trades and the events for both parties
+func (s *SimpleLogic) matchMarketOrder(ctx context.Context, session *PlayerSession, order *model.MarketOrder) (
+	trades []model.Trade, events []model.EventWrapper, err error) {
+	tx := session.Tx
+
+	bookOrders, err := tx.GetMatchingOrders(*order, model.MaxMatchedOrders)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get matching orders: %w", err)
+	}
+
+	relations, err := tx.GetDiplomaticRelations(order.CharacterID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get diplomatic relations: %w", err)
+	}
+
+	states := model.RelationStates(relations)
+
+	for _, bookOrder := range bookOrders {
+		if order.Amount == 0 {
+			break
+		}
+
+		fee, canTrade := model.MarketFee(states[bookOrder.CharacterID])
+		if !canTrade {
+			continue
+		}
+
+		amount := order.Amount
+		if bookOrder.Amount < amount {
+			amount = bookOrder.Amount
+		}
+
+		trade := model.NewTrade(*order, bookOrder, amount)
+		trade.ChargeFee(fee)
+		order.Amount -= amount
+		bookOrder.Amount -= amount
+
+		if trade.ID, err = tx.AddTrade(trade); err != nil {
+			return nil, nil, fmt.Errorf("failed to add trade: %w", err)
+		}
+
+		if bookOrder.Amount == 0 {
+			err = tx.DeleteMarketOrder(bookOrder.ID)
+		} else {
+			err = tx.UpdateMarketOrder(bookOrder)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update order %d: %w", bookOrder.ID, err)
+		}
+
+		delivery := model.Delivery{TownID: bookOrder.TownID, Resources: bookOrder.Proceeds(trade)}
+		if err = tx.AddDelivery(delivery); err != nil {
+			return nil, nil, fmt.Errorf("failed to add market delivery: %w", err)
+		}
+
+		s.logger(ctx).WithFields(log.Fields{
+			"tradeID":  trade.ID,
+			"buyerID":  trade.BuyerID,
+			"sellerID": trade.SellerID,
+			"price":    trade.Price,
+			"amount":   trade.Amount,
+		}).Info("Market orders matched")
+
+		trades = append(trades, trade)
+		events = append(events,
+			model.NewMarketOrderFilledEvent(bookOrder.CharacterID, bookOrder, trade),
+			model.NewMarketOrderFilledEvent(order.CharacterID, *order, trade))
+	}
+
+	switch {
+	case order.Amount == 0:
+		err = tx.DeleteMarketOrder(order.ID)
+	case len(trades) > 0:
+		err = tx.UpdateMarketOrder(*order)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update order %d: %w", order.ID, err)
+	}
+
+	return trades, events, nil
+}
+
+// marketPage - returns offset and limit of the requested page of orders or trades
+func marketPage(offset, limit uint32) (int, int) {
+	if limit == 0 {
+		limit = defaultMarketPageSize
+	}
+
+	if limit > maxMarketPageSize {
+		limit = maxMarketPageSize
+	}
+
+	return int(offset), int(limit)
+}
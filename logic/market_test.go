@@ -0,0 +1,251 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_PlaceMarketOrder(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	sellOrders := []model.MarketOrder{
+		{ID: 9, CharacterID: 4, Owner: "enemy", TownID: 40, Resource: rpc.ResourceType_STONE, Side: rpc.OrderSide_SELL, Price: 2, Amount: 100},
+		{ID: 10, CharacterID: 2, Owner: "seller", TownID: 20, Resource: rpc.ResourceType_STONE, Side: rpc.OrderSide_SELL, Price: 3, Amount: 40},
+		{ID: 11, CharacterID: 3, Owner: "other", TownID: 30, Resource: rpc.ResourceType_STONE, Side: rpc.OrderSide_SELL, Price: 4, Amount: 100},
+	}
+
+	order := model.MarketOrder{
+		CharacterID: 1,
+		Owner:       "buyer",
+		TownID:      1,
+		Resource:    rpc.ResourceType_STONE,
+		Side:        rpc.OrderSide_BUY,
+		Price:       5,
+		Amount:      100,
+	}
+
+	db.On("GetCharacterMarketOrders", int64(1)).Return([]model.MarketOrder{}, nil)
+	db.On("AddMarketOrder", order).Return(int64(12), nil)
+
+	order.ID = 12
+	db.On("GetMatchingOrders", order, model.MaxMatchedOrders).Return(sellOrders, nil)
+	db.On("GetDiplomaticRelations", int64(1)).Return([]model.DiplomaticRelation{
+		{CharacterID: 1, OtherID: 2, Other: "seller", State: rpc.DiplomaticState_ALLIANCE},
+		{CharacterID: 1, OtherID: 4, Other: "enemy", State: rpc.DiplomaticState_WAR},
+	}, nil)
+
+	// The order of the enemy is skipped, the first sell order is filled completely without the fee
+	// for the ally, the second one partially at its own price with the fee
+	db.On("AddTrade", mock.MatchedBy(func(trade model.Trade) bool {
+		return trade.SellOrderID == 10 && trade.BuyOrderID == 12 && trade.Price == 3 && trade.Amount == 40 &&
+			trade.Fee == 0
+	})).Return(int64(1), nil)
+	db.On("AddTrade", mock.MatchedBy(func(trade model.Trade) bool {
+		return trade.SellOrderID == 11 && trade.BuyOrderID == 12 && trade.Price == 4 && trade.Amount == 60 &&
+			trade.Fee == 240*model.MarketFeePercent/100
+	})).Return(int64(2), nil)
+	db.On("DeleteMarketOrder", int64(10)).Return(nil)
+	db.On("UpdateMarketOrder", mock.MatchedBy(func(order model.MarketOrder) bool {
+		return order.ID == 11 && order.Amount == 40
+	})).Return(nil)
+	db.On("DeleteMarketOrder", int64(12)).Return(nil)
+
+	// The sellers get the gold on the next game loop tick
+	db.On("AddDelivery", model.Delivery{TownID: 20, Resources: model.Resources{Gold: 120}}).Return(nil)
+	db.On("AddDelivery", model.Delivery{TownID: 30, Resources: model.Resources{Gold: 240 - 12}}).Return(nil)
+
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1
+	})).Return(nil)
+
+	resp, err := logic.PlaceMarketOrder(context.Background(), session, &rpc.PlaceMarketOrderRequest{
+		Resource: rpc.ResourceType_STONE,
+		Side:     rpc.OrderSide_BUY,
+		Price:    5,
+		Amount:   100,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp.Order)
+	require.Len(t, resp.Trades, 2)
+	require.Equal(t, "seller", resp.Trades[0].Seller)
+	require.Equal(t, "buyer", resp.Trades[0].Buyer)
+
+	// 500 gold of the escrow minus 360 gold spent
+	town := session.SelectedCharacter.Towns[0]
+	require.Equal(t, uint64(100), town.Resources.Stone)
+	require.Equal(t, uint64(1000-360), town.Resources.Gold)
+
+	require.Len(t, logic.EventsChan, 4)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, int64(10), event.Event.GetMarketOrderFilledEvent().OrderID)
+	require.Equal(t, uint64(0), event.Event.GetMarketOrderFilledEvent().AmountLeft)
+
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.Equal(t, int64(12), event.Event.GetMarketOrderFilledEvent().OrderID)
+	require.Equal(t, uint64(60), event.Event.GetMarketOrderFilledEvent().AmountLeft)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_PlaceMarketOrder_Book(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("GetCharacterMarketOrders", int64(1)).Return([]model.MarketOrder{}, nil)
+	db.On("AddMarketOrder", mock.Anything).Return(int64(5), nil)
+	db.On("GetMatchingOrders", mock.Anything, model.MaxMatchedOrders).Return([]model.MarketOrder{}, nil)
+	db.On("GetDiplomaticRelations", int64(1)).Return([]model.DiplomaticRelation{}, nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	place := func(side rpc.OrderSide, price, amount uint64) (*rpc.PlaceMarketOrderResponse, model.Error) {
+		return logic.PlaceMarketOrder(context.Background(), session, &rpc.PlaceMarketOrderRequest{
+			Resource: rpc.ResourceType_WOOD,
+			Side:     side,
+			Price:    price,
+			Amount:   amount,
+		})
+	}
+
+	_, err := place(rpc.OrderSide_SELL, 0, 10)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+	_, err = place(rpc.OrderSide_SELL, 1, model.MaxOrderAmount+1)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+	_, err = place(rpc.OrderSide_SELL, 1, 101)
+	require.EqualError(t, err, model.ErrNotEnoughResources.Error())
+
+	// Nothing matches, the wood stays in the escrow
+	resp, err := place(rpc.OrderSide_SELL, 2, 60)
+	require.NoError(t, err)
+	require.Empty(t, resp.Trades)
+	require.Equal(t, int64(5), resp.Order.Id)
+	require.Equal(t, int64(1), resp.Order.TownID)
+	require.Equal(t, uint64(40), session.SelectedCharacter.Towns[0].Resources.Wood)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_PlaceMarketOrder_Limit(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("GetCharacterMarketOrders", int64(1)).Return(make([]model.MarketOrder, model.MaxMarketOrders), nil)
+
+	_, err := logic.PlaceMarketOrder(context.Background(), session, &rpc.PlaceMarketOrderRequest{
+		Resource: rpc.ResourceType_WOOD,
+		Side:     rpc.OrderSide_SELL,
+		Price:    1,
+		Amount:   1,
+	})
+	require.EqualError(t, err, model.ErrOrderLimitReached.Error())
+}
+
+func TestSimpleLogic_CancelMarketOrder(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("GetMarketOrder", int64(1)).Return(model.MarketOrder{}, sql.ErrNoRows)
+	db.On("GetMarketOrder", int64(2)).Return(model.MarketOrder{ID: 2, CharacterID: 2}, nil)
+	db.On("GetMarketOrder", int64(3)).Return(model.MarketOrder{
+		ID: 3, CharacterID: 1, TownID: 1, Side: rpc.OrderSide_BUY, Price: 4, Amount: 25,
+	}, nil)
+	db.On("DeleteMarketOrder", int64(3)).Return(nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+
+	cancel := func(id int64) (*rpc.CancelMarketOrderResponse, model.Error) {
+		return logic.CancelMarketOrder(context.Background(), session, &rpc.CancelMarketOrderRequest{OrderID: id})
+	}
+
+	_, err := cancel(1)
+	require.EqualError(t, err, model.ErrOrderNotFound.Error())
+
+	// Orders of other players can't be cancelled
+	_, err = cancel(2)
+	require.EqualError(t, err, model.ErrOrderNotFound.Error())
+
+	resp, err := cancel(3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), resp.Refund.Gold)
+	require.Equal(t, uint64(1100), session.SelectedCharacter.Towns[0].Resources.Gold)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CancelMarketOrder_LostTown(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("GetMarketOrder", int64(4)).Return(model.MarketOrder{
+		ID: 4, CharacterID: 1, TownID: 9, Resource: rpc.ResourceType_WOOD, Side: rpc.OrderSide_SELL, Price: 1, Amount: 1950,
+	}, nil)
+	db.On("DeleteMarketOrder", int64(4)).Return(nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1 && town.Resources.Wood == model.TownStorageLimit.Wood
+	})).Return(nil)
+	db.On("AddDelivery", model.Delivery{TownID: 1, Resources: model.Resources{Wood: 50}}).Return(nil)
+
+	// The escrow goes to the capital, the part exceeding the storage limit is delivered later
+	resp, err := logic.CancelMarketOrder(context.Background(), session, &rpc.CancelMarketOrderRequest{OrderID: 4})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1950), resp.Refund.Wood)
+	require.Equal(t, model.TownStorageLimit.Wood, session.SelectedCharacter.Towns[0].Resources.Wood)
+
+	// Nowhere to return the escrow without towns
+	session.SelectedCharacter.Towns = nil
+	_, err = logic.CancelMarketOrder(context.Background(), session, &rpc.CancelMarketOrderRequest{OrderID: 4})
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateDeliveries(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("TakeDeliveries", []int64{1}).Return([]model.Delivery{
+		{ID: 1, TownID: 1, Resources: model.Resources{Food: 30}},
+		{ID: 2, TownID: 1, Resources: model.Resources{Gold: 50}},
+		{ID: 3, TownID: 1, Resources: model.Resources{Wood: 2000}},
+	}, nil)
+	db.On("AddDelivery", model.Delivery{TownID: 1, Resources: model.Resources{Wood: 100}}).Return(nil)
+
+	logic.updateDeliveries(context.Background(), session)
+
+	// The wood exceeding the storage limit is left for delivery
+	town := session.SelectedCharacter.Towns[0]
+	require.Equal(t, uint64(30), town.Resources.Food)
+	require.Equal(t, uint64(1050), town.Resources.Gold)
+	require.Equal(t, model.TownStorageLimit.Wood, town.Resources.Wood)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_GetMarketOrders(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("buyer", model.Town{ID: 1, Resources: model.Resources{Wood: 100, Gold: 1000}})
+
+	db.On("GetMarketOrders", rpc.ResourceType_FOOD, rpc.OrderSide_BUY, 0, 20).Return([]model.MarketOrder{
+		{ID: 1, CharacterID: 2, TownID: 5, Side: rpc.OrderSide_BUY, Price: 3, Amount: 10},
+	}, nil)
+	db.On("GetMarketOrders", rpc.ResourceType_FOOD, rpc.OrderSide_SELL, 0, 20).Return([]model.MarketOrder{
+		{ID: 2, CharacterID: 1, TownID: 1, Side: rpc.OrderSide_SELL, Price: 4, Amount: 10},
+	}, nil)
+
+	resp, err := logic.GetMarketOrders(context.Background(), session, &rpc.GetMarketOrdersRequest{Resource: rpc.ResourceType_FOOD})
+	require.NoError(t, err)
+	require.Len(t, resp.BuyOrders, 1)
+	require.Len(t, resp.SellOrders, 1)
+
+	// Towns of other players are hidden
+	require.Equal(t, int64(0), resp.BuyOrders[0].TownID)
+	require.Equal(t, int64(1), resp.SellOrders[0].TownID)
+
+	db.AssertExpectations(t)
+}
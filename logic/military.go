@@ -0,0 +1,141 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// updateTraining - advances the training queues of all towns, the trained units join the town garrisons
+func (s *SimpleLogic) updateTraining(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+
+	for i := range character.Towns {
+		town := &character.Towns[i]
+
+		training := town.AdvanceTraining()
+		if training == nil {
+			if len(town.Training) > 0 && town.CanTrainUnits() {
+				if err := session.Tx.UpdateTraining(town.Training[0]); err != nil {
+					s.logger(ctx).WithError(err).Error("Failed to update training")
+					return
+				}
+			}
+
+			continue
+		}
+
+		logger := s.logger(ctx).WithFields(log.Fields{
+			"townID":     town.ID,
+			"trainingID": training.ID,
+			"unitType":   training.UnitType,
+			"count":      training.Count,
+		})
+
+		if err := session.Tx.AddGarrison(town.ID, training.Units()); err != nil {
+			logger.WithError(err).Error("Failed to add trained units to the garrison")
+			return
+		}
+
+		if err := session.Tx.DeleteTraining(training.ID); err != nil {
+			logger.WithError(err).Error("Failed to delete training")
+			return
+		}
+
+		logger.Info("Units trained")
+	}
+}
+
+// updateArmies - moves the armies of the character, the arrived armies fight the foreign armies around
+// and besiege their target towns
+func (s *SimpleLogic) updateArmies(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+
+	armies, err := session.Tx.GetActiveArmies(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get active armies")
+		return
+	}
+
+	for _, army := range armies {
+		logger := s.logger(ctx).WithField("armyID", army.ID)
+
+		if army.IsBesieging() {
+			if err := s.updateSiege(ctx, session, &army); err != nil {
+				logger.WithError(err).Error("Failed to update siege")
+				return
+			}
+		} else if army.Advance() {
+			if army, err = s.fightBattles(ctx, session, army); err != nil {
+				logger.WithError(err).Error("Failed to fight battles")
+				return
+			}
+
+			if army.IsBesieging() && !army.Units.IsEmpty() {
+				if err := s.startSiege(ctx, session, &army); err != nil {
+					logger.WithError(err).Error("Failed to start siege")
+					return
+				}
+			}
+		}
+
+		if err := s.saveArmy(session, army); err != nil {
+			logger.WithError(err).Error("Failed to update army")
+			return
+		}
+	}
+}
+
+// fightBattles - the army attacks the armies of the enemies around its location one by one until it loses a battle,
+// both sides get an event about every battle. Returns the army after the battles, the army itself isn't saved
+func (s *SimpleLogic) fightBattles(ctx context.Context, session *PlayerSession, army model.Army) (model.Army, error) {
+	enemies, err := session.Tx.GetArmiesNear(army.Location, model.ArmyBattleRadius, army.CharacterID)
+	if err != nil {
+		return army, err
+	}
+
+	for _, enemy := range enemies {
+		// Only the empires at war fight each other
+		if war, err := atWar(session.Tx, army.CharacterID, enemy.CharacterID); err != nil {
+			return army, err
+		} else if !war {
+			continue
+		}
+
+		battle := model.ResolveBattle(army.Units, enemy.Units, 0)
+		army.Units.Subtract(battle.AttackerLosses)
+		enemy.Units.Subtract(battle.DefenderLosses)
+
+		if err := s.saveArmy(session, enemy); err != nil {
+			return army, err
+		}
+
+		s.logger(ctx).WithFields(log.Fields{
+			"armyID":         army.ID,
+			"enemyArmyID":    enemy.ID,
+			"attackerLosses": battle.AttackerLosses,
+			"defenderLosses": battle.DefenderLosses,
+			"attackerWon":    battle.AttackerWon,
+		}).Info("Battle")
+
+		s.publishEvent(ctx, model.NewBattleEvent(army.Location, army, enemy,
+			battle.AttackerLosses, battle.DefenderLosses, battle.AttackerWon))
+		s.publishEvent(ctx, model.NewBattleEvent(army.Location, enemy, army,
+			battle.DefenderLosses, battle.AttackerLosses, !battle.AttackerWon && !enemy.Units.IsEmpty()))
+
+		if !battle.AttackerWon {
+			break
+		}
+	}
+
+	return army, nil
+}
+
+// saveArmy - updates the army or deletes it if it's destroyed
+func (s *SimpleLogic) saveArmy(session *PlayerSession, army model.Army) error {
+	if army.Units.IsEmpty() {
+		return session.Tx.DeleteArmy(army.ID)
+	}
+
+	return session.Tx.UpdateArmy(army)
+}
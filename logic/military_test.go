@@ -0,0 +1,288 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSimpleLogic_TrainUnits(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	barracks := model.Buildings[rpc.BuildingType_BARRACKS].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("general",
+		model.Town{ID: 1, Buildings: []model.Building{barracks},
+			Resources: model.Resources{Wood: 1000, Food: 1000, Leather: 1000, Gold: 1000}},
+		model.Town{ID: 2, Resources: model.Resources{Wood: 1000, Food: 1000}},
+	)
+
+	train := func(townID int64, unitType rpc.UnitType, count uint64) (*rpc.TrainUnitsResponse, model.Error) {
+		return logic.TrainUnits(context.Background(), session, &rpc.TrainUnitsRequest{
+			TownID:   townID,
+			UnitType: unitType,
+			Count:    count,
+		})
+	}
+
+	_, err := train(1, rpc.UnitType(100), 1)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+	_, err = train(1, rpc.UnitType_SPEARMAN, model.MaxTrainingCount+1)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+	_, err = train(3, rpc.UnitType_SPEARMAN, 1)
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+
+	// The town has no barracks
+	_, err = train(2, rpc.UnitType_SPEARMAN, 1)
+	require.EqualError(t, err, model.ErrPrerequisitesNotMet.Error())
+
+	_, err = train(1, rpc.UnitType_CAVALRY, 30)
+	require.EqualError(t, err, model.ErrNotEnoughResources.Error())
+
+	db.On("AddTraining", model.UnitTraining{TownID: 1, UnitType: rpc.UnitType_SPEARMAN, Count: 10, TicksLeft: 20}).
+		Return(int64(5), nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1 && town.Resources.Food == 800
+	})).Return(nil)
+
+	resp, err := train(1, rpc.UnitType_SPEARMAN, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.Training.Id)
+	require.True(t, resp.Training.InProgress)
+
+	town := session.SelectedCharacter.Towns[0]
+	require.Len(t, town.Training, 1)
+	require.Equal(t, uint64(900), town.Resources.Wood)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateTraining(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	barracks := model.Buildings[rpc.BuildingType_BARRACKS].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1, Buildings: []model.Building{barracks}})
+
+	town := &session.SelectedCharacter.Towns[0]
+	town.Training = []model.UnitTraining{
+		{ID: 1, TownID: 1, UnitType: rpc.UnitType_ARCHER, Count: 5, TicksLeft: 2},
+		{ID: 2, TownID: 1, UnitType: rpc.UnitType_SPEARMAN, Count: 5, TicksLeft: 10},
+	}
+
+	db.On("UpdateTraining", model.UnitTraining{ID: 1, TownID: 1, UnitType: rpc.UnitType_ARCHER, Count: 5, TicksLeft: 1}).
+		Return(nil)
+	logic.updateTraining(context.Background(), session)
+
+	db.On("AddGarrison", int64(1), model.Units{Archers: 5}).Return(nil)
+	db.On("DeleteTraining", int64(1)).Return(nil)
+	logic.updateTraining(context.Background(), session)
+
+	require.Len(t, town.Training, 1)
+	require.Equal(t, int64(2), town.Training[0].ID)
+
+	// The training stops while the town is in debt and its barracks is disabled
+	town.Debt = 1
+	logic.updateTraining(context.Background(), session)
+	require.Equal(t, uint64(10), town.Training[0].TicksLeft)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CreateArmy(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1, X: 100, Y: 100})
+
+	create := func(units *rpc.Units) (*rpc.CreateArmyResponse, model.Error) {
+		return logic.CreateArmy(context.Background(), session, &rpc.CreateArmyRequest{TownID: 1, Units: units})
+	}
+
+	_, err := create(&rpc.Units{})
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	db.On("GetCharacterArmies", int64(1)).Return([]model.Army{}, nil)
+	db.On("TakeGarrison", int64(1), model.Units{Spearmen: 100}).Return(false, nil)
+	_, err = create(&rpc.Units{Spearmen: 100})
+	require.EqualError(t, err, model.ErrNotEnoughUnits.Error())
+
+	db.On("TakeGarrison", int64(1), model.Units{Spearmen: 10, Archers: 5}).Return(true, nil)
+	db.On("AddArmy", model.Army{
+		CharacterID: 1,
+		Owner:       "general",
+		TownID:      1,
+		Units:       model.Units{Spearmen: 10, Archers: 5},
+		Location:    model.Vector2D{X: 100, Y: 100},
+	}).Return(int64(3), nil)
+
+	resp, err := create(&rpc.Units{Spearmen: 10, Archers: 5})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), resp.Army.Id)
+	require.Nil(t, resp.Army.Destination)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CreateArmy_Limit(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1})
+
+	db.On("GetCharacterArmies", int64(1)).Return(make([]model.Army, model.MaxArmies), nil)
+
+	_, err := logic.CreateArmy(context.Background(), session, &rpc.CreateArmyRequest{
+		TownID: 1,
+		Units:  &rpc.Units{Spearmen: 1},
+	})
+	require.EqualError(t, err, model.ErrArmyLimitReached.Error())
+}
+
+func TestSimpleLogic_MoveArmy(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general")
+
+	army := model.Army{ID: 3, CharacterID: 1, TownID: 1, Units: model.Units{Spearmen: 10},
+		Location: model.Vector2D{X: 100, Y: 100}}
+
+	db.On("GetArmy", int64(1)).Return(model.Army{}, sql.ErrNoRows)
+	db.On("GetArmy", int64(2)).Return(model.Army{ID: 2, CharacterID: 2}, nil)
+	db.On("GetArmy", int64(3)).Return(army, nil)
+
+	move := func(id int64) (*rpc.MoveArmyResponse, model.Error) {
+		return logic.MoveArmy(context.Background(), session, &rpc.MoveArmyRequest{
+			ArmyID:      id,
+			Destination: &rpc.Vector2D{X: 103, Y: 104},
+		})
+	}
+
+	_, err := move(1)
+	require.EqualError(t, err, model.ErrArmyNotFound.Error())
+	_, err = move(2)
+	require.EqualError(t, err, model.ErrArmyNotFound.Error())
+
+	army.Destination = model.Vector2D{X: 103, Y: 104}
+	army.IsMoving = true
+	db.On("UpdateArmy", army).Return(nil)
+
+	resp, err := move(3)
+	require.NoError(t, err)
+	require.Equal(t, float32(103), resp.Army.Destination.X)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateArmies(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general")
+
+	marching := model.Army{ID: 1, CharacterID: 1, Owner: "general", Units: model.Units{Spearmen: 5},
+		Location: model.Vector2D{}, Destination: model.Vector2D{X: 10}, IsMoving: true}
+	attacking := model.Army{ID: 2, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10},
+		Location: model.Vector2D{X: 50, Y: 49}, Destination: model.Vector2D{X: 50, Y: 50}, IsMoving: true}
+	enemy := model.Army{ID: 3, CharacterID: 2, Owner: "enemy", Units: model.Units{Spearmen: 10},
+		Location: model.Vector2D{X: 51, Y: 50}}
+	neutral := model.Army{ID: 4, CharacterID: 3, Owner: "neutral", Units: model.Units{Archers: 1},
+		Location: model.Vector2D{X: 50, Y: 51}}
+
+	db.On("GetActiveArmies", int64(1)).Return([]model.Army{marching, attacking}, nil)
+	db.On("UpdateArmy", mock.MatchedBy(func(army model.Army) bool {
+		return army.ID == 1 && army.Location.X == 1 && army.IsMoving
+	})).Return(nil)
+
+	// The cavalry arrives and destroys the spearmen of the enemy, the neutral army isn't attacked
+	db.On("GetArmiesNear", model.Vector2D{X: 50, Y: 50}, model.ArmyBattleRadius, int64(1)).
+		Return([]model.Army{neutral, enemy}, nil)
+	db.On("GetDiplomaticState", int64(1), int64(3)).Return(rpc.DiplomaticState_NEUTRAL, nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("DeleteArmy", int64(3)).Return(nil)
+	db.On("UpdateArmy", mock.MatchedBy(func(army model.Army) bool {
+		return army.ID == 2 && army.Units.Cavalry == 6 && !army.IsMoving
+	})).Return(nil)
+
+	logic.updateArmies(context.Background(), session)
+
+	require.Len(t, logic.EventsChan, 2)
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.True(t, event.Event.GetBattleEvent().Won)
+	require.Equal(t, uint64(4), event.Event.GetBattleEvent().Losses.Cavalry)
+	require.Equal(t, uint64(10), event.Event.GetBattleEvent().EnemyLosses.Spearmen)
+
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.False(t, event.Event.GetBattleEvent().Won)
+	require.Equal(t, "general", event.Event.GetBattleEvent().Enemy)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_TickSession_BattleRolledBack(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	barracks := model.Buildings[rpc.BuildingType_BARRACKS].AtLevel(1)
+	training := model.UnitTraining{ID: 1, TownID: 1, UnitType: rpc.UnitType_ARCHER, Count: 5, TicksLeft: 1}
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1, Buildings: []model.Building{barracks},
+		Training: []model.UnitTraining{training}})
+	session.LastRequestTime = time.Now()
+	logic.config.AFKTimeout = time.Minute
+
+	attacking := model.Army{ID: 2, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10},
+		Location: model.Vector2D{X: 50, Y: 49}, Destination: model.Vector2D{X: 50, Y: 50}, IsMoving: true}
+	enemy := model.Army{ID: 3, CharacterID: 2, Owner: "enemy", Units: model.Units{Spearmen: 10},
+		Location: model.Vector2D{X: 51, Y: 50}}
+
+	saved := *newTestCharacter("general", model.Town{ID: 1, Buildings: []model.Building{barracks},
+		Training: []model.UnitTraining{training}})
+	db.On("GetLostTowns", "general", []int64{1}).Return([]int64{}, nil)
+	db.On("AddGarrison", int64(1), model.Units{Archers: 5}).Return(nil)
+	db.On("DeleteTraining", int64(1)).Return(nil)
+	db.On("GetActiveArmies", int64(1)).Return([]model.Army{attacking}, nil)
+	db.On("GetArmiesNear", model.Vector2D{X: 50, Y: 50}, model.ArmyBattleRadius, int64(1)).
+		Return([]model.Army{enemy}, nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("DeleteArmy", int64(3)).Return(nil)
+	db.On("UpdateArmy", mock.Anything).Return(sql.ErrConnDone).
+		Run(func(mock.Arguments) { db.isCompleted = true })
+	db.On("GetCharacter", int64(1)).Return(saved, nil)
+	db.On("GetTowns", "general").Return(saved.Towns, nil)
+
+	logic.tickSession(context.Background(), session)
+
+	// Nobody is told about the battle that wasn't saved and the finished training is back in the queue
+	require.Empty(t, logic.EventsChan)
+	require.Equal(t, []model.UnitTraining{training}, session.SelectedCharacter.Towns[0].Training)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ResolveBattle(t *testing.T) {
+	// The defender holds if the attackers are not strong enough to destroy it in time
+	battle := model.ResolveBattle(model.Units{Spearmen: 5}, model.Units{Spearmen: 50}, 0)
+	require.False(t, battle.AttackerWon)
+	require.Equal(t, model.Units{Spearmen: 5}, battle.AttackerLosses)
+
+	// The defender bonus makes the defenders harder to kill
+	withoutBonus := model.ResolveBattle(model.Units{Archers: 10}, model.Units{Spearmen: 20}, 0)
+	withBonus := model.ResolveBattle(model.Units{Archers: 10}, model.Units{Spearmen: 20}, 100)
+	require.Less(t, withBonus.DefenderLosses.Spearmen, withoutBonus.DefenderLosses.Spearmen)
+}
+
+func TestSimpleLogic_DisbandArmy(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1, X: 100, Y: 100}, model.Town{ID: 2, X: 500, Y: 500})
+
+	db.On("GetArmy", int64(1)).Return(model.Army{ID: 1, CharacterID: 1, Units: model.Units{Archers: 3},
+		Location: model.Vector2D{X: 300, Y: 300}}, nil)
+	db.On("GetArmy", int64(2)).Return(model.Army{ID: 2, CharacterID: 1, Units: model.Units{Archers: 3},
+		Location: model.Vector2D{X: 505, Y: 495}}, nil)
+
+	_, err := logic.DisbandArmy(context.Background(), session, &rpc.DisbandArmyRequest{ArmyID: 1})
+	require.EqualError(t, err, model.ErrOutsideTown.Error())
+
+	db.On("AddGarrison", int64(2), model.Units{Archers: 3}).Return(nil)
+	db.On("DeleteArmy", int64(2)).Return(nil)
+
+	resp, err := logic.DisbandArmy(context.Background(), session, &rpc.DisbandArmyRequest{ArmyID: 2})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.Garrison.TownID)
+
+	db.AssertExpectations(t)
+}
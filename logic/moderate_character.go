@@ -0,0 +1,98 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/model/consts"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+	"time"
+	"unicode/utf8"
+)
+
+// ModerateCharacter - mutes or bans the character for the duration or lifts the sanction.
+// Every action is stored in the moderation log
+func (s *SimpleLogic) ModerateCharacter(ctx context.Context, session *PlayerSession, request *rpc.ModerateCharacterRequest) (*rpc.ModerateCharacterResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":     session.SessionID,
+		"characterName": request.CharacterName,
+		"action":        request.Action,
+		"duration":      request.Duration,
+		"reason":        request.Reason,
+	}).Info("ModerateCharacter")
+
+	moderator := session.SelectedCharacter
+	if moderator.Role() < model.RoleModerator {
+		return nil, model.ErrForbidden
+	}
+
+	if !model.IsValidModerationAction(int32(request.Action)) {
+		return nil, model.ErrBadRequest
+	}
+
+	lifting := model.IsLiftingAction(request.Action)
+	if !lifting && (request.Duration == 0 || request.Duration > uint64(model.MaxSanctionDuration/time.Second)) {
+		return nil, model.ErrBadRequest
+	}
+
+	if utf8.RuneCountInString(request.Reason) > s.config.ChatMessageMaxLength {
+		return nil, model.ErrMessageTooLong
+	}
+
+	targetID, modelErr := s.chatRecipient(ctx, session, request.CharacterName)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+
+	now := time.Now()
+	var until time.Time
+	if !lifting {
+		until = now.Add(time.Duration(request.Duration) * time.Second)
+	}
+
+	var err error
+	var notification string
+	switch request.Action {
+	case rpc.ModerateCharacterRequest_MUTE:
+		err = session.Tx.SetMutedUntil(targetID, until)
+		notification = consts.MessageCharacterMuted(until)
+	case rpc.ModerateCharacterRequest_UNMUTE:
+		err = session.Tx.SetMutedUntil(targetID, until)
+		notification = consts.MessageCharacterUnmuted
+	case rpc.ModerateCharacterRequest_BAN:
+		err = session.Tx.SetBannedUntil(targetID, until)
+		notification = consts.MessageCharacterBanned(until)
+	case rpc.ModerateCharacterRequest_UNBAN:
+		err = session.Tx.SetBannedUntil(targetID, until)
+	}
+
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update sanctions")
+		return nil, model.ErrInternalServerError
+	}
+
+	err = session.Tx.AddModerationRecord(model.ModerationRecord{
+		ModeratorID: moderator.ID,
+		TargetID:    targetID,
+		Action:      request.Action,
+		Until:       until,
+		Reason:      request.Reason,
+		Time:        now,
+	})
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add moderation record")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Banned characters can't be selected, so there is nobody to notify about the lifted ban
+	if notification != "" {
+		s.publishEvent(ctx, model.NewPrivateSystemChatMessageEvent(targetID, notification))
+	}
+
+	response := &rpc.ModerateCharacterResponse{}
+	if !lifting {
+		response.Until = until.Unix()
+	}
+
+	return response, nil
+}
@@ -0,0 +1,169 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/model/consts"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimpleLogic_SendChatMessage_WordFilter(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+	logic.config.ChatBannedWords = []string{"DARN", "блин"}
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{}, nil)
+
+	global := model.ChatMembership{CharacterID: 1, Channel: rpc.ChatMessage_GLOBAL}
+	db.On("GetChatChannels", int64(1)).Return([]model.ChatMembership{global}, nil)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: "oh ****, **** ****it",
+		Channel: rpc.ChatMessage_GLOBAL})).
+		Return(int64(1), nil)
+
+	_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    "oh Darn, БЛИН darnit",
+		Channel: rpc.ChatMessage_GLOBAL,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "oh ****, **** ****it", (<-logic.EventsChan).Event.GetChatMessageEvent().Message.Text)
+
+	// The length is counted in letters, not in bytes
+	text := strings.Repeat("я", logic.config.ChatMessageMaxLength)
+	db.On("AddChatMessage", sentChatMessage(model.ChatMessage{Sender: "king", Text: text, Channel: rpc.ChatMessage_GLOBAL})).
+		Return(int64(2), nil)
+
+	_, err = logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    text,
+		Channel: rpc.ChatMessage_GLOBAL,
+	})
+	require.NoError(t, err)
+
+	_, err = logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+		Text:    text + "я",
+		Channel: rpc.ChatMessage_GLOBAL,
+	})
+	require.EqualError(t, err, model.ErrMessageTooLong.Error())
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_SendChatMessage_Sanctions(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+
+	send := func() model.Error {
+		_, err := logic.SendChatMessage(context.Background(), session, &rpc.SendChatMessageRequest{
+			Text:    "hello",
+			Channel: rpc.ChatMessage_GLOBAL,
+		})
+		return err
+	}
+
+	// The sanctions stored in the database are checked, not the ones of the selected character
+	now := time.Now()
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{MutedUntil: now.Add(time.Minute)}, nil).Once()
+	require.EqualError(t, send(), model.ErrCharacterMuted.Error())
+
+	db.On("GetSanctions", int64(1)).Return(model.Sanctions{BannedUntil: now.Add(time.Minute)}, nil).Once()
+	require.EqualError(t, send(), model.ErrCharacterBanned.Error())
+
+	require.Empty(t, logic.EventsChan)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ReportChatMessage(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+
+	report := func(messageID int64) model.Error {
+		_, err := logic.ReportChatMessage(context.Background(), session, &rpc.ReportChatMessageRequest{
+			MessageID: messageID,
+			Reason:    "spam",
+		})
+		return err
+	}
+
+	db.On("GetChatMessage", int64(1)).Return(model.ChatMessage{}, sql.ErrNoRows)
+	require.EqualError(t, report(1), model.ErrMessageNotFound.Error())
+
+	db.On("GetChatMessage", int64(2)).Return(model.ChatMessage{ID: 2, Sender: "king", Text: "buy wood"}, nil)
+	require.EqualError(t, report(2), model.ErrBadRequest.Error())
+
+	db.On("GetChatMessage", int64(3)).Return(model.ChatMessage{ID: 3, Sender: "duke", Text: "psst",
+		Channel: rpc.ChatMessage_DIRECT, Recipient: "baron"}, nil)
+	require.EqualError(t, report(3), model.ErrMessageNotFound.Error())
+
+	db.On("GetChatMessage", int64(4)).Return(model.ChatMessage{ID: 4, Sender: "duke", Text: "buy wood"}, nil)
+	db.On("AddChatReport", mock.MatchedBy(func(report model.ChatReport) bool {
+		return report.MessageID == 4 && report.ReporterID == 1 && report.Reason == "spam"
+	})).Return(nil)
+	require.NoError(t, report(4))
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ModerateCharacter(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+
+	moderate := func(action rpc.ModerateCharacterRequest_Action, duration uint64) (*rpc.ModerateCharacterResponse, model.Error) {
+		return logic.ModerateCharacter(context.Background(), session, &rpc.ModerateCharacterRequest{
+			CharacterName: "duke",
+			Action:        action,
+			Duration:      duration,
+			Reason:        "flood",
+		})
+	}
+
+	_, err := moderate(rpc.ModerateCharacterRequest_MUTE, 60)
+	require.EqualError(t, err, model.ErrForbidden.Error())
+
+	session.SelectedCharacter.IsModerator = true
+
+	_, err = moderate(rpc.ModerateCharacterRequest_MUTE, 0)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	// The end of the sanction overflows if the duration isn't limited
+	_, err = moderate(rpc.ModerateCharacterRequest_MUTE, math.MaxUint64/60)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	var until time.Time
+	db.On("GetCharacterID", "duke").Return(int64(2), nil)
+	db.On("SetMutedUntil", int64(2), mock.AnythingOfType("time.Time")).Run(func(args mock.Arguments) {
+		until = args.Get(1).(time.Time)
+	}).Return(nil).Once()
+	db.On("AddModerationRecord", mock.MatchedBy(func(record model.ModerationRecord) bool {
+		return record.ModeratorID == 1 && record.TargetID == 2 && record.Action == rpc.ModerateCharacterRequest_MUTE &&
+			record.Until.Equal(until) && record.Reason == "flood"
+	})).Return(nil).Once()
+
+	resp, err := moderate(rpc.ModerateCharacterRequest_MUTE, 60)
+	require.NoError(t, err)
+	require.Equal(t, until.Unix(), resp.Until)
+	require.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second)
+
+	// The muted character is notified privately
+	require.Equal(t, model.NewPrivateSystemChatMessageEvent(2, consts.MessageCharacterMuted(until)), <-logic.EventsChan)
+
+	db.On("SetMutedUntil", int64(2), time.Time{}).Return(nil).Once()
+	db.On("AddModerationRecord", mock.MatchedBy(func(record model.ModerationRecord) bool {
+		return record.Action == rpc.ModerateCharacterRequest_UNMUTE && record.Until.IsZero()
+	})).Return(nil).Once()
+
+	resp, err = moderate(rpc.ModerateCharacterRequest_UNMUTE, 0)
+	require.NoError(t, err)
+	require.Zero(t, resp.Until)
+	require.Equal(t, consts.MessageCharacterUnmuted, (<-logic.EventsChan).Event.GetChatMessageEvent().Message.Text)
+
+	db.AssertExpectations(t)
+}
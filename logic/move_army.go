@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"math"
+)
+
+// characterArmy - returns the army of the character and locks it until the end of the transaction
+func (s *SimpleLogic) characterArmy(ctx context.Context, session *PlayerSession, armyID int64) (model.Army, model.Error) {
+	tx := session.Tx
+	tx.SetAutoRollBack(false)
+	army, err := tx.GetArmy(armyID)
+	tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && army.CharacterID != session.SelectedCharacter.ID) {
+		return army, model.ErrArmyNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get army")
+		return army, model.ErrInternalServerError
+	}
+
+	return army, nil
+}
+
+func (s *SimpleLogic) MoveArmy(ctx context.Context, session *PlayerSession, request *rpc.MoveArmyRequest) (*rpc.MoveArmyResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":   session.SessionID,
+		"armyID":      request.ArmyID,
+		"destination": request.Destination,
+	}).Info("MoveArmy")
+
+	if request.Destination == nil ||
+		math.IsNaN(float64(request.Destination.X)) || math.IsInf(float64(request.Destination.X), 0) ||
+		math.IsNaN(float64(request.Destination.Y)) || math.IsInf(float64(request.Destination.Y), 0) {
+		return nil, model.ErrBadRequest
+	}
+
+	army, modelErr := s.characterArmy(ctx, session, request.ArmyID)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+
+	army.MoveTo(model.ToModelVector(request.Destination))
+
+	if err := session.Tx.UpdateArmy(army); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update army")
+		return nil, model.ErrInternalServerError
+	}
+
+	return &rpc.MoveArmyResponse{Army: army.ToRPC()}, nil
+}
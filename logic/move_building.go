@@ -0,0 +1,53 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/db"
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) MoveBuilding(ctx context.Context, session *PlayerSession, request *rpc.MoveBuildingRequest) (*rpc.MoveBuildingResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":  session.SessionID,
+		"townID":     request.TownID,
+		"buildingID": request.BuildingID,
+		"location":   request.Location,
+		"rotation":   request.Rotation,
+	}).Info("MoveBuilding")
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	index := town.FindBuilding(request.BuildingID)
+	if index < 0 {
+		return nil, model.ErrBuildingNotFound
+	}
+
+	building := town.Buildings[index]
+	building.Location = model.ToModelVector(request.Location)
+	building.Rotation = request.Rotation
+
+	if err := s.validatePlacement(ctx, session, *town, building); err != nil {
+		return nil, err
+	}
+
+	err := session.Tx.UpdateTownBuilding(building)
+	if err != nil && errors.Is(err, db.ErrDuplicatedUniqueKey) {
+		return nil, model.ErrLocationOccupied
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town building")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Buildings slice may be shared with a copy of the town, so it isn't changed in place
+	buildings := append([]model.Building{}, town.Buildings...)
+	buildings[index] = building
+	town.Buildings = buildings
+
+	return &rpc.MoveBuildingResponse{}, nil
+}
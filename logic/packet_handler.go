@@ -1,8 +1,11 @@
 package logic
 
 import (
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"abbysoft/gardarike-online/tracing"
+	"context"
 	"fmt"
 	"github.com/golang/protobuf/proto"
 	"github.com/sirupsen/logrus"
@@ -11,6 +14,8 @@ import (
 	"time"
 )
 
+const unknownRequestName = "Unknown"
+
 type PacketHandler struct {
 	logic *SimpleLogic
 	log   *logrus.Entry
@@ -23,7 +28,7 @@ func NewPacketHandler(logic *SimpleLogic) PacketHandler {
 	}
 }
 
-type handleFunc func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error)
+type handleFunc func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error)
 
 type requestHandler struct {
 	handleFunc            handleFunc
@@ -37,8 +42,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 	handler.authorizationRequired = true
 
 	if request.GetLoginRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.Login(request.GetLoginRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.Login(ctx, request.GetLoginRequest())
 			return rpc.Response{
 				Data: &rpc.Response_LoginResponse{
 					LoginResponse: response,
@@ -48,8 +53,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 		handler.authorizationRequired = false
 		handler.characterRequired = false
 	} else if request.GetGetWorldMapRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetWorldMap(s, request.GetGetWorldMapRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetWorldMap(ctx, s, request.GetGetWorldMapRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetWorldMapResponse{
 					GetWorldMapResponse: response,
@@ -59,8 +64,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 
 		handler.characterRequired = false
 	} else if request.GetSelectCharacterRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.SelectCharacter(s, request.GetSelectCharacterRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.SelectCharacter(ctx, s, request.GetSelectCharacterRequest())
 			return rpc.Response{
 				Data: &rpc.Response_SelectCharacterResponse{
 					SelectCharacterResponse: response,
@@ -69,8 +74,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 		}
 		handler.characterRequired = false
 	} else if request.GetSendChatMessageRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.SendChatMessage(s, request.GetSendChatMessageRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.SendChatMessage(ctx, s, request.GetSendChatMessageRequest())
 			return rpc.Response{
 				Data: &rpc.Response_SendChatMessageResponse{
 					SendChatMessageResponse: response,
@@ -78,8 +83,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetGetChatHistoryRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetChatHistory(s, request.GetGetChatHistoryRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetChatHistory(ctx, s, request.GetGetChatHistoryRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetChatHistoryResponse{
 					GetChatHistoryResponse: response,
@@ -87,17 +92,296 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetGetWorkDistributionRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetWorkDistribution(s, request.GetGetWorkDistributionRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetWorkDistribution(ctx, s, request.GetGetWorkDistributionRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetWorkDistributionResponse{
 					GetWorkDistributionResponse: response,
 				},
 			}, err
 		}
+	} else if request.GetSetWorkDistributionRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.SetWorkDistribution(ctx, s, r.GetSetWorkDistributionRequest())
+			return rpc.Response{
+				Data: &rpc.Response_SetWorkDistributionResponse{
+					SetWorkDistributionResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetEmpireStatusRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetEmpireStatus(ctx, s, r.GetGetEmpireStatusRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetEmpireStatusResponse{
+					GetEmpireStatusResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetCancelConstructionRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.CancelConstruction(ctx, s, r.GetCancelConstructionRequest())
+			return rpc.Response{
+				Data: &rpc.Response_CancelConstructionResponse{
+					CancelConstructionResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetTownDetailsRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetTownDetails(ctx, s, r.GetGetTownDetailsRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetTownDetailsResponse{
+					GetTownDetailsResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetUpgradeBuildingRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.UpgradeBuilding(ctx, s, r.GetUpgradeBuildingRequest())
+			return rpc.Response{
+				Data: &rpc.Response_UpgradeBuildingResponse{
+					UpgradeBuildingResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetDemolishBuildingRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.DemolishBuilding(ctx, s, r.GetDemolishBuildingRequest())
+			return rpc.Response{
+				Data: &rpc.Response_DemolishBuildingResponse{
+					DemolishBuildingResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetMoveBuildingRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.MoveBuilding(ctx, s, r.GetMoveBuildingRequest())
+			return rpc.Response{
+				Data: &rpc.Response_MoveBuildingResponse{
+					MoveBuildingResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetBuildingCatalogRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetBuildingCatalog(ctx, s, r.GetGetBuildingCatalogRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetBuildingCatalogResponse{
+					GetBuildingCatalogResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetStartResearchRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.StartResearch(ctx, s, r.GetStartResearchRequest())
+			return rpc.Response{
+				Data: &rpc.Response_StartResearchResponse{
+					StartResearchResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetResearchStateRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetResearchState(ctx, s, r.GetGetResearchStateRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetResearchStateResponse{
+					GetResearchStateResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetSetTaxRateRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.SetTaxRate(ctx, s, r.GetSetTaxRateRequest())
+			return rpc.Response{
+				Data: &rpc.Response_SetTaxRateResponse{
+					SetTaxRateResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetPlaceMarketOrderRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.PlaceMarketOrder(ctx, s, r.GetPlaceMarketOrderRequest())
+			return rpc.Response{
+				Data: &rpc.Response_PlaceMarketOrderResponse{
+					PlaceMarketOrderResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetCancelMarketOrderRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.CancelMarketOrder(ctx, s, r.GetCancelMarketOrderRequest())
+			return rpc.Response{
+				Data: &rpc.Response_CancelMarketOrderResponse{
+					CancelMarketOrderResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetMarketOrdersRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetMarketOrders(ctx, s, r.GetGetMarketOrdersRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetMarketOrdersResponse{
+					GetMarketOrdersResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetTradeHistoryRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetTradeHistory(ctx, s, r.GetGetTradeHistoryRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetTradeHistoryResponse{
+					GetTradeHistoryResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetSendCaravanRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.SendCaravan(ctx, s, r.GetSendCaravanRequest())
+			return rpc.Response{
+				Data: &rpc.Response_SendCaravanResponse{
+					SendCaravanResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetTrainUnitsRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.TrainUnits(ctx, s, r.GetTrainUnitsRequest())
+			return rpc.Response{
+				Data: &rpc.Response_TrainUnitsResponse{
+					TrainUnitsResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetCreateArmyRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.CreateArmy(ctx, s, r.GetCreateArmyRequest())
+			return rpc.Response{
+				Data: &rpc.Response_CreateArmyResponse{
+					CreateArmyResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetMoveArmyRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.MoveArmy(ctx, s, r.GetMoveArmyRequest())
+			return rpc.Response{
+				Data: &rpc.Response_MoveArmyResponse{
+					MoveArmyResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetDisbandArmyRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.DisbandArmy(ctx, s, r.GetDisbandArmyRequest())
+			return rpc.Response{
+				Data: &rpc.Response_DisbandArmyResponse{
+					DisbandArmyResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetArmiesRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetArmies(ctx, s, r.GetGetArmiesRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetArmiesResponse{
+					GetArmiesResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetAttackTownRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.AttackTown(ctx, s, r.GetAttackTownRequest())
+			return rpc.Response{
+				Data: &rpc.Response_AttackTownResponse{
+					AttackTownResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetProposeDiplomacyRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.ProposeDiplomacy(ctx, s, r.GetProposeDiplomacyRequest())
+			return rpc.Response{
+				Data: &rpc.Response_ProposeDiplomacyResponse{
+					ProposeDiplomacyResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetAnswerDiplomacyProposalRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.AnswerDiplomacyProposal(ctx, s, r.GetAnswerDiplomacyProposalRequest())
+			return rpc.Response{
+				Data: &rpc.Response_AnswerDiplomacyProposalResponse{
+					AnswerDiplomacyProposalResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetDiplomacyRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetDiplomacy(ctx, s, r.GetGetDiplomacyRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetDiplomacyResponse{
+					GetDiplomacyResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetJoinChatChannelRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.JoinChatChannel(ctx, s, r.GetJoinChatChannelRequest())
+			return rpc.Response{
+				Data: &rpc.Response_JoinChatChannelResponse{
+					JoinChatChannelResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetLeaveChatChannelRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.LeaveChatChannel(ctx, s, r.GetLeaveChatChannelRequest())
+			return rpc.Response{
+				Data: &rpc.Response_LeaveChatChannelResponse{
+					LeaveChatChannelResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetChatChannelsRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetChatChannels(ctx, s, r.GetGetChatChannelsRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetChatChannelsResponse{
+					GetChatChannelsResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetReportChatMessageRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.ReportChatMessage(ctx, s, r.GetReportChatMessageRequest())
+			return rpc.Response{
+				Data: &rpc.Response_ReportChatMessageResponse{
+					ReportChatMessageResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetModerateCharacterRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.ModerateCharacter(ctx, s, r.GetModerateCharacterRequest())
+			return rpc.Response{
+				Data: &rpc.Response_ModerateCharacterResponse{
+					ModerateCharacterResponse: response,
+				},
+			}, err
+		}
+	} else if request.GetGetChatReportsRequest() != nil {
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetChatReports(ctx, s, r.GetGetChatReportsRequest())
+			return rpc.Response{
+				Data: &rpc.Response_GetChatReportsResponse{
+					GetChatReportsResponse: response,
+				},
+			}, err
+		}
 	} else if request.GetCreateAccountRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.CreateAccount(request.GetCreateAccountRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.CreateAccount(ctx, request.GetCreateAccountRequest())
 			return rpc.Response{
 				Data: &rpc.Response_CreateAccountResponse{
 					CreateAccountResponse: response,
@@ -108,8 +392,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 		handler.authorizationRequired = false
 		handler.characterRequired = false
 	} else if request.GetCreateCharacterRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.CreateCharacter(s, request.GetCreateCharacterRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.CreateCharacter(ctx, s, request.GetCreateCharacterRequest())
 			return rpc.Response{
 				Data: &rpc.Response_CreateCharacterResponse{
 					CreateCharacterResponse: response,
@@ -118,8 +402,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 		}
 		handler.characterRequired = false
 	} else if request.GetGetResourcesRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetResources(s, request.GetGetResourcesRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetResources(ctx, s, request.GetGetResourcesRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetResourcesResponse{
 					GetResourcesResponse: response,
@@ -127,8 +411,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetPlaceTownRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.PlaceTown(s, request.GetPlaceTownRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.PlaceTown(ctx, s, request.GetPlaceTownRequest())
 			return rpc.Response{
 				Data: &rpc.Response_PlaceTownResponse{
 					PlaceTownResponse: response,
@@ -139,8 +423,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 		handler.characterRequired = true
 		handler.authorizationRequired = true
 	} else if request.GetPlaceBuildingRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.PlaceBuilding(s, r.GetPlaceBuildingRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.PlaceBuilding(ctx, s, r.GetPlaceBuildingRequest())
 			return rpc.Response{
 				Data: &rpc.Response_PlaceBuildingResponse{
 					PlaceBuildingResponse: response,
@@ -148,8 +432,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetGetEmpiresRatingRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetEmpiresRating(s, r.GetGetEmpiresRatingRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetEmpiresRating(ctx, s, r.GetGetEmpiresRatingRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetEmpiresRatingResponse{
 					GetEmpiresRatingResponse: response,
@@ -157,8 +441,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetRenameTownRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.RenameTown(s, r.GetRenameTownRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.RenameTown(ctx, s, r.GetRenameTownRequest())
 			return rpc.Response{
 				Data: &rpc.Response_RenameTownResponse{
 					RenameTownResponse: response,
@@ -166,8 +450,8 @@ func (p *PacketHandler) getHandleFunc(request rpc.Request) *requestHandler {
 			}, err
 		}
 	} else if request.GetGetLocalMapRequest() != nil {
-		handler.handleFunc = func(s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
-			response, err := p.logic.GetLocalMap(s, r.GetGetLocalMapRequest())
+		handler.handleFunc = func(ctx context.Context, s *PlayerSession, r rpc.Request) (rpc.Response, model.Error) {
+			response, err := p.logic.GetLocalMap(ctx, s, r.GetGetLocalMapRequest())
 			return rpc.Response{
 				Data: &rpc.Response_GetLocalMapResponse{
 					GetLocalMapResponse: response,
@@ -187,8 +471,34 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 	var requestErr model.Error
 	var response rpc.Response
 
+	start := time.Now()
+	requestName := unknownRequestName
+
+	ctx := tracing.WithRequestInfo(context.Background(), tracing.RequestInfo{
+		RequestID:   tracing.NewTraceID(),
+		RequestName: requestName,
+	})
+	ctx, span := tracing.StartSpan(ctx, "Request")
+	logger := tracing.Logger(ctx, p.log)
+
+	defer func() {
+		code := "OK"
+		if requestErr != nil {
+			code = rpc.Error(requestErr.GetCode()).String()
+			span.SetError(requestErr)
+		}
+
+		span.SetName(requestName)
+		span.SetAttribute("code", code)
+		span.End()
+
+		metrics.Requests.WithLabelValues(requestName, code).Inc()
+		metrics.ObserveSince(metrics.RequestDuration.WithLabelValues(requestName), start)
+	}()
+
 	if err := proto.Unmarshal(data, &request); err != nil || len(data) == 0 {
-		p.log.WithError(err).Error("Failed to serialize client request")
+		logger.WithError(err).Error("Failed to serialize client request")
+		requestErr = model.ErrBadRequest
 
 		response.Data = &rpc.Response_ErrorResponse{
 			ErrorResponse: &rpc.ErrorResponse{
@@ -200,14 +510,18 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 		return &response
 	}
 
-	var requestName string
-
 	requestNameParts := strings.Split(fmt.Sprintf("%T", request.Data), "_")
 	if len(requestNameParts) < 2 {
-		p.log.Errorf("Failed to process packet: wrong request name: %T", request.Data)
+		logger.Errorf("Failed to process packet: wrong request name: %T", request.Data)
+	} else {
 		requestName = requestNameParts[1]
 	}
 
+	info := tracing.RequestInfoFromContext(ctx)
+	info.RequestName = requestName
+	ctx = tracing.WithRequestInfo(ctx, info)
+	logger = tracing.Logger(ctx, p.log)
+
 	var sessionID string
 	var authorized bool
 	var session *PlayerSession
@@ -217,7 +531,7 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 	sessionSubmatch := sessionRegexp.FindStringSubmatch(request.String())
 	if len(sessionSubmatch) == 2 {
 		sessionID = sessionSubmatch[1]
-		session, authorized = p.logic.sessions[sessionID]
+		session, authorized = p.logic.getSession(sessionID)
 
 		if session != nil {
 			session.LastRequestTime = time.Now()
@@ -240,12 +554,18 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 	}
 
 	if handler != nil && handler.handleFunc != nil && requestErr == nil {
+		// The events of the request are sent only after its transaction is committed
+		var events *[]model.EventWrapper
 		if session != nil {
 			session.Mutex.Lock()
 
-			tx, err := p.logic.db.BeginTransaction(false, true)
+			ctx = sessionContext(ctx, session)
+			ctx, events = withPendingEvents(ctx)
+			logger = tracing.Logger(ctx, p.log)
+
+			tx, err := p.logic.db.BeginTransaction(ctx, false, true)
 			if err != nil {
-				p.log.WithError(err).Error("Failed to start transaction")
+				logger.WithError(err).Error("Failed to start transaction")
 				requestErr = model.ErrInternalServerError
 			}
 
@@ -253,14 +573,16 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 		}
 
 		if requestErr == nil {
-			response, requestErr = handler.handleFunc(session, request)
+			response, requestErr = handler.handleFunc(ctx, session, request)
 		}
 		if session != nil {
 			// Only commit should be handled, rollback is happened automatically on errors
 			if session.Tx != nil && !session.Tx.IsCompleted() {
 				if err := session.Tx.EndTransaction(); err != nil {
-					p.log.WithError(err).Error("Failed to commit transaction")
+					logger.WithError(err).Error("Failed to commit transaction")
 					requestErr = model.ErrInternalServerError
+				} else {
+					p.logic.sendPendingEvents(events)
 				}
 			}
 
@@ -269,7 +591,7 @@ func (p *PacketHandler) HandleClientPacket(data []byte) *rpc.Response {
 	}
 
 	if requestErr != nil {
-		p.log.WithField("requestName", requestName).Infof("Sending error response: %v", requestErr.Error())
+		logger.Infof("Sending error response: %v", requestErr.Error())
 
 		response = rpc.Response{
 			Data: &rpc.Response_ErrorResponse{
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/metrics"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"database/sql"
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPacketHandler_HandleClientPacket_Metrics(t *testing.T) {
+	logic, _, _ := NewLogicMock()
+	handler := NewPacketHandler(logic)
+
+	badRequests := metrics.Requests.WithLabelValues(unknownRequestName, rpc.Error_BAD_REQUEST.String())
+	before := testutil.ToFloat64(badRequests)
+
+	response := handler.HandleClientPacket(nil)
+	require.NotNil(t, response.GetErrorResponse())
+	require.Equal(t, before+1, testutil.ToFloat64(badRequests))
+
+	request := &rpc.Request{Data: &rpc.Request_GetResourcesRequest{
+		GetResourcesRequest: &rpc.GetResourcesRequest{SessionID: "unknown"},
+	}}
+	data, err := proto.Marshal(request)
+	require.NoError(t, err)
+
+	notAuthorized := metrics.Requests.WithLabelValues("GetResourcesRequest", rpc.Error_NOT_AUTHORIZED.String())
+	before = testutil.ToFloat64(notAuthorized)
+
+	response = handler.HandleClientPacket(data)
+	require.Equal(t, rpc.Error_NOT_AUTHORIZED, response.GetErrorResponse().GetCode())
+	require.Equal(t, before+1, testutil.ToFloat64(notAuthorized))
+}
+
+func TestPacketHandler_HandleClientPacket_Events(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChatMessageMaxLength = 100
+	session.SelectedCharacter = newTestCharacter("king")
+	handler := NewPacketHandler(logic)
+
+	request := &rpc.Request{Data: &rpc.Request_SendChatMessageRequest{
+		SendChatMessageRequest: &rpc.SendChatMessageRequest{SessionID: session.SessionID, Text: "/help"},
+	}}
+	data, err := proto.Marshal(request)
+	require.NoError(t, err)
+
+	response := handler.HandleClientPacket(data)
+	require.Nil(t, response.GetErrorResponse())
+	require.Len(t, logic.EventsChan, 1)
+	<-logic.EventsChan
+
+	// The events of the request aren't sent if its transaction isn't committed
+	db.isCompleted = false
+	db.commitError = sql.ErrConnDone
+
+	response = handler.HandleClientPacket(data)
+	require.Equal(t, rpc.Error_INTERNAL_SERVER_ERROR, response.GetErrorResponse().GetCode())
+	require.Empty(t, logic.EventsChan)
+}
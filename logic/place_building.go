@@ -3,12 +3,13 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func (s *SimpleLogic) PlaceBuilding(session *PlayerSession, request *rpc.PlaceBuildingRequest) (*rpc.PlaceBuildingResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) PlaceBuilding(ctx context.Context, session *PlayerSession, request *rpc.PlaceBuildingRequest) (*rpc.PlaceBuildingResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID":  session.SessionID,
 		"buildingID": request.BuildingID,
 		"townID":     request.TownID,
@@ -18,34 +19,54 @@ func (s *SimpleLogic) PlaceBuilding(session *PlayerSession, request *rpc.PlaceBu
 
 	building, found := model.Buildings[request.BuildingID]
 	if !found {
-		s.log.WithField("buildingID", request.BuildingID).Error("Failed to find building")
+		s.logger(ctx).WithField("buildingID", request.BuildingID).Error("Failed to find building")
 		return nil, model.ErrBadRequest
 	}
 
-	if !session.SelectedCharacter.HasTown(request.TownID) {
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
 		return nil, model.ErrTownNotFound
 	}
 
+	if town.IsConstructionQueueFull() {
+		return nil, model.ErrConstructionQueueFull
+	}
+
+	if err := town.CheckRequirements(building); err != nil {
+		return nil, err
+	}
+
+	building = building.AtLevel(1)
 	building.Location = model.ToModelVector(request.Location)
 	building.Rotation = request.Rotation
-	if err := session.Tx.AddTownBuilding(request.TownID, building); err != nil {
-		s.log.WithError(err).Error("Failed to add town building")
-		return nil, model.ErrInternalServerError
-	}
 
-	char := session.SelectedCharacter
+	if err := s.validatePlacement(ctx, session, *town, building); err != nil {
+		return nil, err
+	}
 
-	if !char.Resources.Subtract(building.Cost) {
+	updated := *town
+	if !updated.Resources.Subtract(building.Cost) {
 		return nil, model.ErrNotEnoughResources
 	}
 
-	char.ProductionRate.Add(building.Production)
-	char.MaxPopulation += building.PopulationBonus
+	construction := model.NewConstruction(town.ID, building)
+	id, err := session.Tx.AddConstruction(construction)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add construction")
+		return nil, model.ErrInternalServerError
+	}
 
-	if err := session.Tx.UpdateCharacter(*char); err != nil {
-		s.log.WithError(err).Error("Failed to update character")
+	construction.ID = id
+	updated.Construction = append(append([]model.Construction{}, town.Construction...), construction)
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
 		return nil, model.ErrInternalServerError
 	}
 
-	return &rpc.PlaceBuildingResponse{}, nil
+	*town = updated
+
+	return &rpc.PlaceBuildingResponse{
+		Construction: construction.ToRPC(len(town.Construction) <= s.config.ConstructionSlots),
+	}, nil
 }
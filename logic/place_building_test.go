@@ -2,7 +2,9 @@ package logic
 
 import (
 	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -34,26 +36,117 @@ func TestSimpleLogic_PlaceBuilding(t *testing.T) {
 		Rotation:   0,
 	})
 
-	building := model.Buildings[request.BuildingID]
+	building := model.Buildings[request.BuildingID].AtLevel(1)
 	building.Rotation = request.Rotation
 	building.Location = model.ToModelVector(request.Location)
 
-	session.SelectedCharacter.Resources = building.Cost
-	session.SelectedCharacter.MaxPopulation = 10
-
-	db.On("AddTownBuilding", int64(1), building).Return(nil)
-	db.On("UpdateCharacter", mock.MatchedBy(func(char model.Character) bool {
-		return char.MaxPopulation == 10+building.PopulationBonus &&
-			!char.Resources.IsEnough(model.Resources{
-				CharacterID: 0,
-				Wood:        1,
-				Food:        1,
-				Stone:       1,
-				Leather:     1,
-			})
+	session.SelectedCharacter.Towns[0].Resources = building.Cost
+	logic.config.ChunkSize = 10
+	db.On("GetMapChunk", mock.Anything, mock.Anything, mock.Anything).Return(newTerrainChunk(t, 10, flatTerrain), nil)
+
+	construction := model.NewConstruction(1, building)
+	db.On("AddConstruction", construction).Return(int64(3), nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1 && town.Resources == model.Resources{} && len(town.Construction) == 1
 	})).Return(nil)
 
-	resp, err := logic.PlaceBuilding(session, request)
+	resp, err := logic.PlaceBuilding(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotNil(t, resp)
+	require.Equal(t, int64(3), resp.Construction.Id)
+	require.Equal(t, building.BuildTime, resp.Construction.TicksLeft)
+	require.True(t, resp.Construction.InProgress)
+
+	// The building isn't finished yet
+	town := session.SelectedCharacter.Towns[0]
+	require.Empty(t, town.Buildings)
+	require.Equal(t, uint64(consts.TownPopulationBonus), town.MaxPopulation())
+
+	// All resources of the town were spent
+	request.Location = &rpc.Vector2D{X: 5, Y: 5}
+	resp, err = logic.PlaceBuilding(context.Background(), session, request)
+	require.EqualError(t, err, model.ErrNotEnoughResources.Error())
+	require.Nil(t, resp)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_PlaceBuilding_QueueFull(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = &model.Character{
+		ID:    1,
+		Name:  "test",
+		Towns: []model.Town{{ID: 1, Resources: model.TownStorageLimit}},
+	}
+
+	for i := 0; i < model.MaxConstructionQueue; i++ {
+		session.SelectedCharacter.Towns[0].Construction = append(session.SelectedCharacter.Towns[0].Construction,
+			model.NewConstruction(1, model.Buildings[rpc.BuildingType_HOUSE]))
+	}
+
+	resp, err := logic.PlaceBuilding(context.Background(), session, &rpc.PlaceBuildingRequest{
+		TownID:     1,
+		BuildingID: rpc.BuildingType_QUARRY,
+	})
+	require.EqualError(t, err, model.ErrConstructionQueueFull.Error())
+	require.Nil(t, resp)
+}
+
+func TestSimpleLogic_PlaceBuilding_Validation(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 10
+	logic.config.WaterLevel = 0.1
+
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	house.TownBuildingID = 1
+	house.Location = model.Vector2D{X: 20, Y: 20}
+
+	session.SelectedCharacter = &model.Character{
+		ID:   1,
+		Name: "test",
+		Towns: []model.Town{{
+			ID:        1,
+			X:         20,
+			Y:         20,
+			Resources: model.TownStorageLimit,
+			Buildings: []model.Building{house},
+		}},
+	}
+
+	// Water at the left side of the local chunks, hills at the bottom
+	db.On("GetMapChunk", mock.Anything, mock.Anything, mock.Anything).
+		Return(newTerrainChunk(t, 10, func(x, y int) float32 {
+			switch {
+			case x < 2:
+				return 0
+			case y < 4:
+				return 0.2 + float32(y)/10
+			default:
+				return 0.5
+			}
+		}), nil)
+
+	place := func(x, y, rotation float32) model.Error {
+		_, err := logic.PlaceBuilding(context.Background(), session, &rpc.PlaceBuildingRequest{
+			TownID:     1,
+			BuildingID: rpc.BuildingType_HOUSE,
+			Location:   &rpc.Vector2D{X: x, Y: y},
+			Rotation:   rotation,
+		})
+		return err
+	}
+
+	require.EqualError(t, place(60, 20, 0), model.ErrOutsideTown.Error())
+	require.EqualError(t, place(21.5, 21.5, 0), model.ErrLocationOccupied.Error())
+	// The rotated house doesn't fit between the town house and the next one
+	require.EqualError(t, place(22.2, 20, 45), model.ErrLocationOccupied.Error())
+	require.EqualError(t, place(21, 23, 0), model.ErrLocationUnderWater.Error())
+	require.EqualError(t, place(23, 21, 0), model.ErrLocationTooSteep.Error())
+
+	db.On("AddConstruction", mock.Anything).Return(int64(1), nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+	require.NoError(t, place(23, 23, 0))
+
+	// The queued building takes the place as well
+	require.EqualError(t, place(23, 24, 0), model.ErrLocationOccupied.Error())
+	db.AssertExpectations(t)
 }
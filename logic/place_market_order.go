@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) PlaceMarketOrder(ctx context.Context, session *PlayerSession, request *rpc.PlaceMarketOrderRequest) (*rpc.PlaceMarketOrderResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"townID":    request.TownID,
+		"resource":  request.Resource,
+		"side":      request.Side,
+		"price":     request.Price,
+		"amount":    request.Amount,
+	}).Info("PlaceMarketOrder")
+
+	if !model.IsValidResourceType(int32(request.Resource)) || !model.IsValidOrderSide(int32(request.Side)) ||
+		request.Price == 0 || request.Price > model.MaxOrderPrice ||
+		request.Amount == 0 || request.Amount > model.MaxOrderAmount {
+		return nil, model.ErrBadRequest
+	}
+
+	character := session.SelectedCharacter
+
+	town := character.Capital()
+	if request.TownID != 0 {
+		town = character.Town(request.TownID)
+	}
+
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	orders, err := session.Tx.GetCharacterMarketOrders(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character market orders")
+		return nil, model.ErrInternalServerError
+	}
+
+	if len(orders) >= model.MaxMarketOrders {
+		return nil, model.ErrOrderLimitReached
+	}
+
+	order := model.MarketOrder{
+		CharacterID: character.ID,
+		Owner:       character.Name,
+		TownID:      town.ID,
+		Resource:    request.Resource,
+		Side:        request.Side,
+		Price:       request.Price,
+		Amount:      request.Amount,
+	}
+
+	updated := *town
+	if !updated.Resources.Subtract(order.Escrow()) {
+		return nil, model.ErrNotEnoughResources
+	}
+
+	if order.ID, err = session.Tx.AddMarketOrder(order); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add market order")
+		return nil, model.ErrInternalServerError
+	}
+
+	trades, events, err := s.matchMarketOrder(ctx, session, &order)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to match market order")
+		return nil, model.ErrInternalServerError
+	}
+
+	response := &rpc.PlaceMarketOrderResponse{}
+	var overflow model.Resources
+	for _, trade := range trades {
+		overflow.Add(updated.Store(order.Proceeds(trade)))
+		response.Trades = append(response.Trades, trade.ToRPC())
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	// The proceeds exceeding the storage limit wait for the free space
+	if !overflow.IsEmpty() {
+		if err := session.Tx.AddDelivery(model.Delivery{TownID: town.ID, Resources: overflow}); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to add market delivery")
+			return nil, model.ErrInternalServerError
+		}
+	}
+
+	*town = updated
+
+	for _, event := range events {
+		s.publishEvent(ctx, event)
+	}
+
+	if order.Amount > 0 {
+		response.Order = order.ToRPC(character.ID)
+	}
+
+	return response, nil
+}
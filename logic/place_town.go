@@ -5,18 +5,20 @@ import (
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// canPlaceTown - checks if the character can place one more town.
-func canPlaceTown(character model.Character) bool {
+// canPlaceTown - checks if the character can place one more town paid by the source town.
+func canPlaceTown(character model.Character, source model.Town) bool {
 	townCount := len(character.Towns)
 
-	return character.Resources.IsEnough(model.ResourcesPlaceTown) &&
-		character.CurrentPopulation >= uint64(townCount*500)
+	return source.Resources.IsEnough(model.ResourcesPlaceTown) &&
+		character.Population() >= uint64(townCount*500)
 }
 
 func (s *SimpleLogic) getMapChunkHeightAt(chunk *rpc.WorldMapChunk, x, y int) float32 {
@@ -39,9 +41,45 @@ func (s *SimpleLogic) getMapChunkAt(x, y int, tx db.DatabaseTransaction) (*rpc.W
 	return rpcChunk, err
 }
 
+// randomTownPlacementAttempts - how many random locations are tried before giving up
+const randomTownPlacementAttempts = 10
+
+// getTownsAround - returns the towns which may prevent placing a town within the rect.
+// The rect is extended by the largest territory, it covers the minimum town distance as well
+func getTownsAround(xStart, xEnd, yStart, yEnd float32, tx db.DatabaseTransaction) ([]model.Town, error) {
+	return tx.GetTownsForRect(
+		int(math.Floor(float64(xStart)-model.MaxTerritoryRadius)),
+		int(math.Ceil(float64(xEnd)+model.MaxTerritoryRadius)),
+		int(math.Floor(float64(yStart)-model.MaxTerritoryRadius)),
+		int(math.Ceil(float64(yEnd)+model.MaxTerritoryRadius)))
+}
+
+// randomTownLocation - picks a random location on the map which is free for the new town of the owner
+func (s *SimpleLogic) randomTownLocation(ctx context.Context, owner string, tx db.DatabaseTransaction) (*rpc.Vector2D, model.Error) {
+	size := float32(s.config.ChunkSize)
+
+	towns, err := getTownsAround(0, size, 0, size, tx)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get towns of the map")
+		return nil, model.ErrInternalServerError
+	}
+
+	var placementErr model.Error
+	for i := 0; i < randomTownPlacementAttempts; i++ {
+		location := model.Vector2D{X: rand.Float32() * size, Y: rand.Float32() * size}
+
+		if placementErr = model.CheckTownPlacement(owner, location, towns); placementErr == nil {
+			return location.ToRPC(), nil
+		}
+	}
+
+	s.logger(ctx).WithError(placementErr).Error("PlaceTown: no free location found")
+	return nil, placementErr
+}
+
 func (s *SimpleLogic) PlaceTown(
-	session *PlayerSession, request *rpc.PlaceTownRequest) (*rpc.PlaceTownResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+	ctx context.Context, session *PlayerSession, request *rpc.PlaceTownRequest) (*rpc.PlaceTownResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 		"location":  request.Location,
 		"name":      request.Name,
@@ -50,9 +88,20 @@ func (s *SimpleLogic) PlaceTown(
 
 	isFirstTown := len(session.SelectedCharacter.Towns) == 0
 
-	// First town is free but other cost money
+	// First town is free but other cost money, which is taken from the source town or the capital
+	var source *model.Town
 	if !isFirstTown {
-		if !canPlaceTown(*session.SelectedCharacter) {
+		if request.SourceTownID != 0 {
+			source = session.SelectedCharacter.Town(request.SourceTownID)
+		} else {
+			source = session.SelectedCharacter.Capital()
+		}
+
+		if source == nil {
+			return nil, model.ErrTownNotFound
+		}
+
+		if !canPlaceTown(*session.SelectedCharacter, *source) {
 			return nil, model.ErrNotEnoughResources
 		}
 	}
@@ -60,34 +109,48 @@ func (s *SimpleLogic) PlaceTown(
 	tx := session.Tx
 
 	if request.Location == nil {
-		request.Location = &rpc.Vector2D{
-			X: rand.Float32() * float32(s.config.ChunkSize),
-			Y: rand.Float32() * float32(s.config.ChunkSize),
+		location, err := s.randomTownLocation(ctx, session.SelectedCharacter.Name, tx)
+		if err != nil {
+			return nil, err
 		}
+
+		request.Location = location
 	} else {
 		if request.Location.X > float32(s.config.ChunkSize) ||
 			request.Location.Y > float32(s.config.ChunkSize) ||
 			request.Location.X < 0 ||
 			request.Location.Y < 0 {
-			s.log.Error("PlaceTown: incorrect location")
+			s.logger(ctx).Error("PlaceTown: incorrect location")
 			return nil, model.ErrBadRequest
 		}
 
 		mapChunk, err := s.getMapChunkAt(int(request.Location.X), int(request.Location.Y), tx)
 		if err != nil {
-			s.log.WithError(err).Error("Failed to get map chunk")
+			s.logger(ctx).WithError(err).Error("Failed to get map chunk")
 			return nil, model.ErrInternalServerError
 		}
 
 		if s.getMapChunkHeightAt(mapChunk, int(request.Location.X), int(request.Location.Y)) < s.config.WaterLevel {
-			s.log.Error("PlaceTown: trying to place town bellow the water level")
+			s.logger(ctx).Error("PlaceTown: trying to place town bellow the water level")
 			return nil, model.ErrBadRequest
 		}
+
+		location := model.ToModelVector(request.Location)
+		towns, err := getTownsAround(location.X, location.X, location.Y, location.Y, tx)
+		if err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to get towns around the location")
+			return nil, model.ErrInternalServerError
+		}
+
+		if err := model.CheckTownPlacement(session.SelectedCharacter.Name, location, towns); err != nil {
+			s.logger(ctx).WithError(err).Error("PlaceTown: location is taken")
+			return nil, err
+		}
 	}
 
 	if request.Name == "" {
 		err := model.NewError("Field 'Name' must be filled", rpc.Error_BAD_REQUEST)
-		s.log.WithError(err).Error("Failed to add town")
+		s.logger(ctx).WithError(err).Error("Failed to add town")
 		return nil, err
 	}
 
@@ -100,28 +163,28 @@ func (s *SimpleLogic) PlaceTown(
 		Rotation:   request.Rotation,
 	}
 
-	if err := tx.AddTown(town); err != nil {
-		s.log.WithError(err).Error("Failed to add town")
+	id, err := tx.AddTown(town)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add town")
 		return nil, model.ErrInternalServerError
 	}
 
-	session.SelectedCharacter.MaxPopulation += consts.TownPopulationBonus
+	town.ID = id
 
-	if err := tx.UpdateCharacter(*session.SelectedCharacter); err != nil {
-		s.log.WithError(err).Error("Failed to update character")
-		return nil, model.ErrInternalServerError
-	}
+	if source != nil {
+		updated := *source
+		updated.Resources.Subtract(model.ResourcesPlaceTown)
 
-	if !isFirstTown {
-		session.SelectedCharacter.Resources.Subtract(model.ResourcesPlaceTown)
-
-		if err := tx.AddOrUpdateResources(session.SelectedCharacter.Resources); err != nil {
-			s.log.WithError(err).Error("Failed to update character resources")
+		if err := tx.UpdateTown(updated); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to update source town")
 			return nil, model.ErrInternalServerError
 		}
+
+		*source = updated
 	}
 
 	session.SelectedCharacter.Towns = append(session.SelectedCharacter.Towns, town)
+	session.SelectedCharacter.ShareTechnologies()
 	return &rpc.PlaceTownResponse{
 		Location: request.Location,
 	}, nil
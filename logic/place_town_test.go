@@ -4,6 +4,7 @@ import (
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -23,19 +24,20 @@ func TestSimpleLogic_PlaceTown_FirstTown(t *testing.T) {
 		Name:      "test",
 	}
 
+	db.On("GetTownsForRect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]model.Town{}, nil)
 	db.On("AddTown", mock.MatchedBy(func(town model.Town) bool {
 		return town.OwnerName == session.SelectedCharacter.Name &&
 			town.Name == request.Name && town.Rotation == request.Rotation
-	}), mock.Anything).Return(nil)
+	}), mock.Anything).Return(int64(7), nil)
 
-	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
-		return character.MaxPopulation == consts.TownPopulationBonus
-	}), mock.Anything).Return(nil)
-
-	resp, err := logic.PlaceTown(session, request)
+	resp, err := logic.PlaceTown(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotEmpty(t, resp)
 	require.NotNil(t, resp.Location)
+
+	require.True(t, session.SelectedCharacter.HasTown(7))
+	require.Equal(t, uint64(consts.TownPopulationBonus), session.SelectedCharacter.MaxPopulation())
+	db.AssertExpectations(t)
 }
 
 func TestSimpleLogic_PlaceTown_PlacingSecondTown(t *testing.T) {
@@ -51,56 +53,62 @@ func TestSimpleLogic_PlaceTown_PlacingSecondTown(t *testing.T) {
 		Name:      "test",
 		Towns: []model.Town{
 			{
+				ID:         1,
 				X:          10,
 				Y:          15,
 				OwnerName:  "test",
-				Population: 10,
+				Population: 1500,
 				Name:       "TestTown",
+				Resources: model.Resources{
+					Wood:    1000,
+					Food:    1000,
+					Stone:   1000,
+					Leather: 1000,
+				},
 			},
 		},
-		Resources: model.Resources{
-			CharacterID: 1,
-			Wood:        1000,
-			Food:        1000,
-			Stone:       1000,
-			Leather:     1000,
-		},
-		MaxPopulation:     2000,
-		CurrentPopulation: 1500,
 	}
 
+	db.On("GetTownsForRect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]model.Town{}, nil)
 	db.On("AddTown", mock.MatchedBy(func(town model.Town) bool {
 		return town.OwnerName == session.SelectedCharacter.Name &&
 			town.Name == request.Name
-	}), mock.Anything).Return(nil)
-
-	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
-		return character.MaxPopulation == 2000+consts.TownPopulationBonus
-	}), mock.Anything).Return(nil)
+	}), mock.Anything).Return(int64(2), nil)
 
 	resourcesAfterPlacing := model.Resources{
-		CharacterID: 1,
-		Wood:        1000,
-		Food:        1000,
-		Stone:       1000,
-		Leather:     1000,
+		Wood:    1000,
+		Food:    1000,
+		Stone:   1000,
+		Leather: 1000,
 	}
 	resourcesAfterPlacing.Subtract(model.ResourcesPlaceTown)
 
-	db.On("AddOrUpdateResources", resourcesAfterPlacing, mock.Anything).Return(nil)
+	// The capital pays for the new town
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 1 && town.Resources == resourcesAfterPlacing
+	})).Return(nil)
 
-	resp, err := logic.PlaceTown(session, request)
+	resp, err := logic.PlaceTown(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotEmpty(t, resp)
 	require.NotNil(t, resp.Location)
+	require.Len(t, session.SelectedCharacter.Towns, 2)
+	require.Equal(t, resourcesAfterPlacing, session.SelectedCharacter.Towns[0].Resources)
 
-	// Trying to place another town. Should return an error because all resources were spent
-	resp, err = logic.PlaceTown(session, request)
+	// Trying to place another town. Should return an error because all resources of the capital were spent
+	resp, err = logic.PlaceTown(context.Background(), session, request)
 	require.EqualError(t, err, model.ErrNotEnoughResources.Error())
 	require.Nil(t, resp)
 
-	// Placing bellow the waterlevel
-	session.SelectedCharacter.Resources.Add(model.ResourcesPlaceTown)
+	// The source town must belong to the character
+	request.SourceTownID = 10
+	resp, err = logic.PlaceTown(context.Background(), session, request)
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+	require.Nil(t, resp)
+
+	// Placing bellow the waterlevel, the second town pays
+	request.SourceTownID = 2
+	session.SelectedCharacter.Towns[1].Resources.Add(model.ResourcesPlaceTown)
 	logic.config.WaterLevel = 0.1
 	logic.config.ChunkSize = 2
 
@@ -117,7 +125,52 @@ func TestSimpleLogic_PlaceTown_PlacingSecondTown(t *testing.T) {
 		Y: 1,
 	}
 
-	resp, err = logic.PlaceTown(session, request)
+	resp, err = logic.PlaceTown(context.Background(), session, request)
 	require.EqualError(t, err, model.ErrBadRequest.Error())
 	require.Nil(t, resp)
 }
+
+func TestSimpleLogic_PlaceTown_Territory(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.WaterLevel = 0.1
+	logic.config.ChunkSize = 2
+
+	session.SelectedCharacter = &model.Character{
+		ID:   1,
+		Name: "test",
+	}
+
+	chunk, err := model.NewWorldMapChunkFromRPC(rpc.WorldMapChunk{
+		Data: []float32{0.5, 0.5, 0.5, 0.5},
+	})
+	require.NoError(t, err)
+	db.On("GetMapChunk", int64(0), int64(0), int64(consts.GlobalChunkNumber)).Return(chunk, nil)
+
+	request := &rpc.PlaceTownRequest{
+		Name:     "TestTown",
+		Location: &rpc.Vector2D{X: 1, Y: 1},
+	}
+
+	place := func(towns ...model.Town) model.Error {
+		db.On("GetTownsForRect", -99, 101, -99, 101).Return(towns, nil).Once()
+		_, err := logic.PlaceTown(context.Background(), session, request)
+		return err
+	}
+
+	// Too close even to the own town
+	require.EqualError(t, place(model.Town{ID: 1, X: 30, Y: 1, OwnerName: "test"}), model.ErrTownTooClose.Error())
+
+	// The foreign territory grows with the population
+	large := model.Town{ID: 2, X: 79, Y: 1, OwnerName: "neighbour", Population: 4000}
+	require.Equal(t, float64(80), large.TerritoryRadius())
+	require.EqualError(t, place(large), model.ErrForeignTerritory.Error())
+
+	db.On("AddTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.X == 1 && town.Y == 1
+	})).Return(int64(3), nil)
+
+	small := large
+	small.Population = 100
+	require.NoError(t, place(small, model.Town{ID: 1, X: 61, Y: 1, OwnerName: "test", Population: 4000}))
+	require.True(t, session.SelectedCharacter.HasTown(3))
+}
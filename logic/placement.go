@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type localChunkKey struct {
+	x, y, number int64
+}
+
+// localChunkCell - returns the cell of the local chunk containing the location.
+// Every local chunk covers a quarter of the global one with the same number of cells
+func localChunkCell(location model.Vector2D, chunkSize int) (x, y int) {
+	half := chunkSize / 2
+	if half == 0 {
+		return 0, 0
+	}
+
+	cell := func(value float32) int {
+		offset := int(value) % chunkSize
+		if offset < 0 {
+			offset += chunkSize
+		}
+
+		return offset % half * chunkSize / half
+	}
+
+	return cell(location.X), cell(location.Y)
+}
+
+// getLocalTerrain - returns heights of the local chunk, the chunk is generated if nobody has requested it yet
+func (s *SimpleLogic) getLocalTerrain(ctx context.Context, session *PlayerSession, key localChunkKey) ([]float32, model.Error) {
+	chunk, err := session.Tx.GetMapChunk(key.x, key.y, key.number)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		response, err := s.generateNewLocalChunk(ctx, session, key.x, key.y, int32(key.number))
+		if err != nil {
+			return nil, err
+		}
+
+		return response.Map.Data, nil
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get local map chunk")
+		return nil, model.ErrInternalServerError
+	}
+
+	local, err := chunk.ToLocalRPC()
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to convert chunk to local")
+		return nil, model.ErrInternalServerError
+	}
+
+	return local.Data, nil
+}
+
+// getTerrainHeights - returns heights of the local map at the locations
+func (s *SimpleLogic) getTerrainHeights(ctx context.Context, session *PlayerSession, locations []model.Vector2D) ([]float32, model.Error) {
+	chunks := make(map[localChunkKey][]float32)
+	result := make([]float32, 0, len(locations))
+
+	for _, location := range locations {
+		x, y, number := getGlobalChunkCoordsForPosition(*location.ToRPC(), s.MapChunkSize())
+		key := localChunkKey{x: x, y: y, number: number}
+
+		terrain, found := chunks[key]
+		if !found {
+			var err model.Error
+			if terrain, err = s.getLocalTerrain(ctx, session, key); err != nil {
+				return nil, err
+			}
+
+			chunks[key] = terrain
+		}
+
+		cellX, cellY := localChunkCell(location, s.MapChunkSize())
+		index := cellY + cellX*s.MapChunkSize()
+		if index >= len(terrain) {
+			s.logger(ctx).WithFields(log.Fields{
+				"location": location,
+				"size":     len(terrain),
+			}).Error("Local map chunk is smaller than expected")
+			return nil, model.ErrInternalServerError
+		}
+
+		result = append(result, terrain[index])
+	}
+
+	return result, nil
+}
+
+// validatePlacement - checks the building lies within the town build radius, doesn't overlap other buildings
+// of the town and stands on the dry and flat enough terrain
+func (s *SimpleLogic) validatePlacement(ctx context.Context, session *PlayerSession, town model.Town, building model.Building) model.Error {
+	if !town.IsWithinBuildRadius(building) {
+		return model.ErrOutsideTown
+	}
+
+	if town.IsOccupied(building) {
+		return model.ErrLocationOccupied
+	}
+
+	heights, err := s.getTerrainHeights(ctx, session, building.FootprintPoints())
+	if err != nil {
+		return err
+	}
+
+	lowest, highest := heights[0], heights[0]
+	for _, height := range heights {
+		if height < lowest {
+			lowest = height
+		}
+		if height > highest {
+			highest = height
+		}
+	}
+
+	if lowest < s.config.WaterLevel {
+		return model.ErrLocationUnderWater
+	}
+
+	if highest-lowest > model.MaxBuildingSlope {
+		return model.ErrLocationTooSteep
+	}
+
+	return nil
+}
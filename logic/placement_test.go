@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"bytes"
+	"encoding/gob"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func flatTerrain(x, y int) float32 {
+	return 1
+}
+
+// newTerrainChunk - returns the local chunk of 'size' x 'size' cells with the heights returned by 'height'
+func newTerrainChunk(t *testing.T, size int, height func(x, y int) float32) model.WorldMapChunk {
+	data := make([]float32, size*size)
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			data[y+x*size] = height(x, y)
+		}
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buffer).Encode(&data))
+
+	return model.WorldMapChunk{Width: int32(size), Height: int32(size), Data: buffer.Bytes()}
+}
+
+func Test_localChunkCell(t *testing.T) {
+	testFunc := func(location model.Vector2D, x, y int) {
+		cellX, cellY := localChunkCell(location, 10)
+		require.Equal(t, x, cellX)
+		require.Equal(t, y, cellY)
+	}
+
+	testFunc(model.Vector2D{}, 0, 0)
+	testFunc(model.Vector2D{X: 3, Y: 7}, 6, 4)
+	testFunc(model.Vector2D{X: 14, Y: 25}, 8, 0)
+	testFunc(model.Vector2D{X: -1, Y: -6}, 8, 8)
+}
+
+func TestBuilding_Overlaps(t *testing.T) {
+	first := model.Building{Footprint: model.Vector2D{X: 2, Y: 2}}
+	second := model.Building{Footprint: model.Vector2D{X: 2, Y: 2}, Location: model.Vector2D{X: 2.2}}
+
+	require.False(t, first.Overlaps(second))
+
+	// The rotated corner reaches the other building
+	second.Rotation = 45
+	require.True(t, first.Overlaps(second))
+	require.True(t, second.Overlaps(first))
+
+	// Touching buildings don't overlap
+	second.Rotation = 0
+	second.Location.X = 2
+	require.False(t, first.Overlaps(second))
+}
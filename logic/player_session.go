@@ -3,7 +3,6 @@ package logic
 import (
 	"abbysoft/gardarike-online/db"
 	"abbysoft/gardarike-online/model"
-	rpc "abbysoft/gardarike-online/rpc/generated"
 	"github.com/google/uuid"
 	"sync"
 	"time"
@@ -15,7 +14,6 @@ type PlayerSession struct {
 	SelectedCharacter *model.Character
 	Mutex             sync.Mutex
 	LastRequestTime   time.Time
-	WorkDistribution  rpc.GetWorkDistributionResponse
 	Tx                db.DatabaseTransaction
 }
 
@@ -25,9 +23,5 @@ func NewPlayerSession(accountID int64) *PlayerSession {
 		SessionID:         uuid.New().String(),
 		SelectedCharacter: nil,
 		LastRequestTime:   time.Now(),
-		WorkDistribution: rpc.GetWorkDistributionResponse{
-			IdleCount:       0,
-			WoodcutterCount: 0,
-		},
 	}
 }
@@ -0,0 +1,79 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// updatePopulation - feeds the population of every town from the town storage and updates the happiness.
+// Well fed towns grow randomly, the food shortage in any town stops the growth of the whole empire
+// and the starving towns start to shrink if the shortage lasts too long
+func (s *SimpleLogic) updatePopulation(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+
+	unfed := make([]uint64, len(character.Towns))
+	totalUnfed := uint64(0)
+	for i := range character.Towns {
+		unfed[i] = character.Towns[i].Feed()
+		totalUnfed += unfed[i]
+	}
+
+	if totalUnfed > 0 {
+		character.StarvingTicks++
+	} else {
+		character.StarvingTicks = 0
+	}
+
+	character.Happiness = character.HappinessFactors().Happiness()
+
+	logger := s.logger(ctx).WithFields(log.Fields{
+		"population": character.Population(),
+		"happiness":  character.Happiness,
+		"unfed":      totalUnfed,
+	})
+
+	switch {
+	case character.StarvingTicks > model.StarvationGraceTicks:
+		for i := range character.Towns {
+			town := &character.Towns[i]
+
+			loss := model.StarvationLoss(unfed[i])
+			if loss == 0 {
+				continue
+			}
+
+			town.Population -= loss
+			logger.WithField("townID", town.ID).WithField("loss", loss).Info("Player's population starves")
+		}
+
+		s.dismissWorkers(ctx, session)
+	case character.IsStarving():
+		logger.Debug("Player's population growth stopped by the food shortage")
+	default:
+		chance := PopulationGrowChance(character.Happiness)
+		for i := range character.Towns {
+			town := &character.Towns[i]
+
+			if town.Population < town.MaxPopulation() && s.randomEvent(chance) {
+				town.Population++
+				logger.WithField("townID", town.ID).Debug("Player's population grows")
+			}
+		}
+	}
+}
+
+// dismissWorkers - removes the workers which died from their jobs
+func (s *SimpleLogic) dismissWorkers(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+
+	assigned, population := character.Workers.Assigned(), character.Population()
+	if assigned <= population {
+		return
+	}
+
+	character.Workers.Dismiss(assigned - population)
+	if err := session.Tx.UpdateWorkDistribution(character.ID, character.Workers); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update work distribution")
+	}
+}
@@ -0,0 +1,204 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_UpdatePopulation_Grows(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Food: 5}})
+	session.SelectedCharacter.TaxRate = 10
+
+	var chance float32
+	logic.randomEvent = func(c float32) bool {
+		chance = c
+		return true
+	}
+
+	logic.updatePopulation(context.Background(), session)
+
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(11), character.Towns[0].Population)
+	require.Equal(t, uint64(2), character.Towns[0].Resources.Food)
+	require.Equal(t, uint64(0), character.StarvingTicks)
+	// Free housing bonus and taxes penalty
+	require.Equal(t, uint64(55), character.Happiness)
+	require.Equal(t, PopulationGrowChance(55), chance)
+}
+
+func TestSimpleLogic_UpdatePopulation_NoHousing(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 100, Resources: model.Resources{Food: 100}})
+	session.SelectedCharacter.TaxRate = 10
+
+	logic.randomEvent = func(chance float32) bool {
+		return true
+	}
+
+	logic.updatePopulation(context.Background(), session)
+
+	require.Equal(t, uint64(100), session.SelectedCharacter.Towns[0].Population)
+	require.Equal(t, uint64(35), session.SelectedCharacter.Happiness)
+}
+
+func TestSimpleLogic_UpdatePopulation_ShortageStopsGrowth(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Food: 1}})
+	session.SelectedCharacter.TaxRate = 10
+
+	logic.randomEvent = func(chance float32) bool {
+		return true
+	}
+
+	logic.updatePopulation(context.Background(), session)
+
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(10), character.Towns[0].Population)
+	require.Equal(t, uint64(0), character.Towns[0].Resources.Food)
+	require.Equal(t, uint64(1), character.StarvingTicks)
+	require.True(t, character.IsStarving())
+	require.Equal(t, uint64(25), character.Happiness)
+}
+
+func TestSimpleLogic_UpdatePopulation_Starves(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 30})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.StarvingTicks = model.StarvationGraceTicks
+	session.SelectedCharacter.Workers = model.WorkDistribution{
+		rpc.JobType_FARMER:     10,
+		rpc.JobType_WOODCUTTER: 2,
+		rpc.JobType_BUILDER:    18,
+	}
+
+	expectedWorkers := model.WorkDistribution{
+		rpc.JobType_FARMER:     10,
+		rpc.JobType_WOODCUTTER: 2,
+		rpc.JobType_BUILDER:    15,
+	}
+	db.On("UpdateWorkDistribution", int64(1), expectedWorkers).Return(nil)
+
+	logic.updatePopulation(context.Background(), session)
+
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(27), character.Towns[0].Population)
+	require.Equal(t, model.StarvationGraceTicks+1, int(character.StarvingTicks))
+	require.Equal(t, expectedWorkers, character.Workers)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdatePopulation_PartialShortage(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Food: 1}})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.StarvingTicks = model.StarvationGraceTicks
+
+	logic.updatePopulation(context.Background(), session)
+
+	// 4 citizens are fed, at least one of 6 unfed dies
+	require.Equal(t, uint64(9), session.SelectedCharacter.Towns[0].Population)
+}
+
+func TestSimpleLogic_UpdatePopulation_Recovers(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Food: 10}})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.StarvingTicks = 5
+
+	logic.updatePopulation(context.Background(), session)
+
+	character := session.SelectedCharacter
+	require.False(t, character.IsStarving())
+	require.Equal(t, uint64(10), character.Towns[0].Population)
+	require.Equal(t, uint64(55), character.Happiness)
+}
+
+func TestSimpleLogic_UpdatePopulation_UnhappyDoesntGrow(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Food: 10}})
+	session.SelectedCharacter.TaxRate = 100
+
+	logic.updatePopulation(context.Background(), session)
+
+	require.Equal(t, uint64(10), session.SelectedCharacter.Happiness)
+	require.Equal(t, float32(0.4), PopulationGrowChance(session.SelectedCharacter.Happiness))
+	require.Equal(t, float32(0), PopulationGrowChance(0))
+}
+
+func TestCharacter_HappinessFactors(t *testing.T) {
+	character := newTestCharacter("test", model.Town{ID: 1, Population: 95})
+	character.Workers = model.WorkDistribution{rpc.JobType_FARMER: 1, rpc.JobType_HUNTER: 1, rpc.JobType_MINER: 5}
+	character.StarvingTicks = 1
+	character.TaxRate = 20
+
+	factors := character.HappinessFactors()
+	require.Equal(t, model.HappinessFactors{FoodVariety: 20, Housing: 0, Taxes: -10, Starvation: -30}, factors)
+	require.Equal(t, uint64(30), factors.Happiness())
+
+	character.StarvingTicks = 0
+	character.Towns[0].Population = 10
+	character.TaxRate = 0
+	require.Equal(t, uint64(80), character.HappinessFactors().Happiness())
+
+	// The second level temple
+	character.Towns[0].Buildings = []model.Building{model.Buildings[rpc.BuildingType_TEMPLE].AtLevel(2)}
+	require.Equal(t, int64(10), character.HappinessFactors().Buildings)
+	require.Equal(t, uint64(90), character.HappinessFactors().Happiness())
+}
+
+func TestSimpleLogic_UpdateSessionResources_Happiness(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 4})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.Happiness = model.MaxHappiness
+	session.SelectedCharacter.Workers = model.WorkDistribution{rpc.JobType_WOODCUTTER: 4}
+	session.SelectedCharacter.Towns[0].X, session.SelectedCharacter.Towns[0].Y = 50, 50
+
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 4}).
+		Return(model.ChunkResources{Trees: 4}, nil)
+	db.On("UpdateCharacter", mock.Anything).Return(nil)
+
+	logic.updateSessionResources(context.Background(), session)
+
+	// The happiest workers produce 1.5 times more, but the population starts to starve
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(6), character.Towns[0].Resources.Wood)
+	require.True(t, character.IsStarving())
+	require.Equal(t, uint64(25), character.Happiness)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdatePopulation_StarvingTown(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 20, Resources: model.Resources{Food: 10}})
+	session.SelectedCharacter.TaxRate = 10
+	session.SelectedCharacter.Towns = append(session.SelectedCharacter.Towns, model.Town{ID: 2, Population: 10})
+	session.SelectedCharacter.StarvingTicks = model.StarvationGraceTicks
+
+	logic.randomEvent = func(chance float32) bool {
+		return true
+	}
+
+	logic.updatePopulation(context.Background(), session)
+
+	// The capital has enough food, but the second town starves and stops the growth of the whole empire
+	character := session.SelectedCharacter
+	require.Equal(t, uint64(20), character.Towns[0].Population)
+	require.Equal(t, uint64(5), character.Towns[0].Resources.Food)
+	require.Equal(t, uint64(9), character.Towns[1].Population)
+	require.True(t, character.IsStarving())
+}
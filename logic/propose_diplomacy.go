@@ -0,0 +1,77 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+func (s *SimpleLogic) ProposeDiplomacy(ctx context.Context, session *PlayerSession, request *rpc.ProposeDiplomacyRequest) (*rpc.ProposeDiplomacyResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":  session.SessionID,
+		"empireName": request.EmpireName,
+		"state":      request.State,
+	}).Info("ProposeDiplomacy")
+
+	character := session.SelectedCharacter
+	tx := session.Tx
+
+	if !model.IsValidDiplomaticState(int32(request.State)) || request.EmpireName == character.Name {
+		return nil, model.ErrBadRequest
+	}
+
+	tx.SetAutoRollBack(false)
+	otherID, err := tx.GetCharacterID(request.EmpireName)
+	tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrCharacterNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character")
+		return nil, model.ErrInternalServerError
+	}
+
+	current, err := tx.GetDiplomaticState(character.ID, otherID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get diplomatic state")
+		return nil, model.ErrInternalServerError
+	}
+
+	if current == request.State {
+		return nil, model.ErrBadRequest
+	}
+
+	if !model.NeedsAcceptance(current, request.State) {
+		if err := s.changeDiplomaticState(ctx, session, otherID, request.EmpireName, request.State); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to change diplomatic state")
+			return nil, model.ErrInternalServerError
+		}
+
+		return &rpc.ProposeDiplomacyResponse{State: request.State}, nil
+	}
+
+	proposal := model.DiplomacyProposal{
+		FromID: character.ID,
+		From:   character.Name,
+		ToID:   otherID,
+		To:     request.EmpireName,
+		State:  request.State,
+		Time:   time.Now(),
+	}
+
+	if proposal.ID, err = tx.AddDiplomacyProposal(proposal); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add diplomacy proposal")
+		return nil, model.ErrInternalServerError
+	}
+
+	s.publishEvent(ctx, model.NewDiplomacyEvent(otherID, rpc.DiplomacyEvent_PROPOSED, character.Name, current, &proposal))
+
+	return &rpc.ProposeDiplomacyResponse{
+		Proposal: proposal.ToRPC(),
+		State:    current,
+	}, nil
+}
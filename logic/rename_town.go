@@ -3,6 +3,7 @@ package logic
 import (
 	"abbysoft/gardarike-online/model"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -16,8 +17,8 @@ func haveTown(towns []model.Town, id int64) bool {
 	return false
 }
 
-func (s *SimpleLogic) RenameTown(session *PlayerSession, request *rpc.RenameTownRequest) (*rpc.RenameTownResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) RenameTown(ctx context.Context, session *PlayerSession, request *rpc.RenameTownRequest) (*rpc.RenameTownResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": session.SessionID,
 		"townID":    request.TownID,
 		"newName":   request.NewName,
@@ -25,7 +26,7 @@ func (s *SimpleLogic) RenameTown(session *PlayerSession, request *rpc.RenameTown
 
 	towns, err := session.Tx.GetTowns(session.SelectedCharacter.Name)
 	if err != nil {
-		s.log.WithError(err).Error("Failed to get characters towns")
+		s.logger(ctx).WithError(err).Error("Failed to get characters towns")
 		return nil, model.ErrInternalServerError
 	}
 
@@ -34,7 +35,7 @@ func (s *SimpleLogic) RenameTown(session *PlayerSession, request *rpc.RenameTown
 	}
 
 	if err := session.Tx.RenameTown(request.TownID, request.NewName); err != nil {
-		s.log.WithError(err).Error("Failed to rename town")
+		s.logger(ctx).WithError(err).Error("Failed to rename town")
 		return nil, model.ErrInternalServerError
 	}
 
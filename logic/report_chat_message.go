@@ -0,0 +1,59 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"time"
+	"unicode/utf8"
+)
+
+func (s *SimpleLogic) ReportChatMessage(ctx context.Context, session *PlayerSession, request *rpc.ReportChatMessageRequest) (*rpc.ReportChatMessageResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"messageID": request.MessageID,
+		"reason":    request.Reason,
+	}).Info("ReportChatMessage")
+
+	if utf8.RuneCountInString(request.Reason) > s.config.ChatMessageMaxLength {
+		return nil, model.ErrMessageTooLong
+	}
+
+	character := session.SelectedCharacter
+
+	session.Tx.SetAutoRollBack(false)
+	message, err := session.Tx.GetChatMessage(request.MessageID)
+	session.Tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrMessageNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get chat message")
+		return nil, model.ErrInternalServerError
+	}
+
+	// Direct messages of other characters aren't visible to the player
+	if message.Channel == rpc.ChatMessage_DIRECT && message.Recipient != character.Name && message.Sender != character.Name {
+		return nil, model.ErrMessageNotFound
+	}
+
+	if message.Sender == character.Name {
+		return nil, model.ErrBadRequest
+	}
+
+	err = session.Tx.AddChatReport(model.ChatReport{
+		MessageID:  message.ID,
+		ReporterID: character.ID,
+		Reason:     request.Reason,
+		Time:       time.Now(),
+	})
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add chat report")
+		return nil, model.ErrInternalServerError
+	}
+
+	return &rpc.ReportChatMessageResponse{}, nil
+}
@@ -0,0 +1,39 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"context"
+)
+
+// updateResearch - advances the research of the character, the researched technology is saved
+// and the character gets an event about it
+func (s *SimpleLogic) updateResearch(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+	if character.Research == nil {
+		return
+	}
+
+	technology := character.AdvanceResearch()
+	if technology == nil {
+		if err := session.Tx.SetResearch(character.ID, *character.Research); err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to update research")
+		}
+
+		return
+	}
+
+	logger := s.logger(ctx).WithField("technologyID", technology.ID)
+
+	if err := session.Tx.AddTechnology(character.ID, technology.ID); err != nil {
+		logger.WithError(err).Error("Failed to add technology")
+		return
+	}
+
+	if err := session.Tx.DeleteResearch(character.ID); err != nil {
+		logger.WithError(err).Error("Failed to delete research")
+		return
+	}
+
+	logger.Info("Technology researched")
+	s.publishEvent(ctx, model.NewResearchCompletedEvent(character.ID, *technology))
+}
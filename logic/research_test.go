@@ -0,0 +1,119 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_StartResearch(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 10, Resources: model.Resources{Wood: 100, Food: 100}},
+		model.Town{ID: 2, Population: 10, Resources: model.Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000}},
+	)
+	session.SelectedCharacter.ShareTechnologies()
+
+	start := func(id rpc.TechnologyType, townID int64) (*rpc.StartResearchResponse, model.Error) {
+		return logic.StartResearch(context.Background(), session, &rpc.StartResearchRequest{TechnologyID: id, TownID: townID})
+	}
+
+	_, err := start(rpc.TechnologyType(100), 0)
+	require.EqualError(t, err, model.ErrTechnologyNotFound.Error())
+
+	_, err = start(rpc.TechnologyType_TANNING, 0)
+	require.EqualError(t, err, model.ErrTechnologyRequired.Error())
+
+	// The capital can't afford trapping
+	_, err = start(rpc.TechnologyType_TRAPPING, 0)
+	require.EqualError(t, err, model.ErrNotEnoughResources.Error())
+
+	_, err = start(rpc.TechnologyType_TRAPPING, 3)
+	require.EqualError(t, err, model.ErrTownNotFound.Error())
+
+	trapping := model.TechnologyTree[rpc.TechnologyType_TRAPPING]
+	db.On("SetResearch", int64(1), model.NewResearch(trapping)).Return(nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 2
+	})).Return(nil)
+
+	resp, err := start(rpc.TechnologyType_TRAPPING, 2)
+	require.NoError(t, err)
+	require.Equal(t, trapping.ResearchTime, resp.Research.TicksLeft)
+	require.Equal(t, uint64(1000-trapping.Cost.Wood), session.SelectedCharacter.Towns[1].Resources.Wood)
+	require.Equal(t, &model.Research{TechnologyID: trapping.ID, TicksLeft: trapping.ResearchTime},
+		session.SelectedCharacter.Research)
+
+	_, err = start(rpc.TechnologyType_CARPENTRY, 2)
+	require.EqualError(t, err, model.ErrResearchInProgress.Error())
+
+	// Researched technologies can't be started again
+	session.SelectedCharacter.Research = nil
+	session.SelectedCharacter.Technologies[rpc.TechnologyType_TRAPPING] = true
+	_, err = start(rpc.TechnologyType_TRAPPING, 2)
+	require.EqualError(t, err, model.ErrTechnologyAlreadyResearched.Error())
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateResearch(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 10})
+	session.SelectedCharacter.ShareTechnologies()
+	session.SelectedCharacter.Research = &model.Research{TechnologyID: rpc.TechnologyType_STOREHOUSES, TicksLeft: 2}
+
+	db.On("SetResearch", int64(1), model.Research{TechnologyID: rpc.TechnologyType_STOREHOUSES, TicksLeft: 1}).Return(nil)
+	logic.updateResearch(context.Background(), session)
+	require.Equal(t, uint64(1), session.SelectedCharacter.Research.TicksLeft)
+
+	db.On("AddTechnology", int64(1), rpc.TechnologyType_STOREHOUSES).Return(nil)
+	db.On("DeleteResearch", int64(1)).Return(nil)
+	logic.updateResearch(context.Background(), session)
+	require.Nil(t, session.SelectedCharacter.Research)
+	require.True(t, session.SelectedCharacter.Technologies[rpc.TechnologyType_STOREHOUSES])
+
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.Equal(t, rpc.TechnologyType_STOREHOUSES, event.Event.GetResearchCompletedEvent().TechnologyID)
+
+	// The towns get the storage bonus and the warehouse is unlocked
+	town := session.SelectedCharacter.Towns[0]
+	require.Equal(t, model.TownStorageLimit.Wood+500, town.StorageLimit().Wood)
+	require.True(t, town.Technologies.IsBuildingUnlocked(rpc.BuildingType_WAREHOUSE))
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_ResearchProductionBonus(t *testing.T) {
+	character := newTestCharacter("test", model.Town{
+		ID:        1,
+		Buildings: []model.Building{model.Buildings[rpc.BuildingType_SAWMILL].AtLevel(5)},
+	})
+	character.ShareTechnologies()
+	require.Equal(t, uint64(10), character.Towns[0].ProductionRate().Wood)
+
+	character.Technologies[rpc.TechnologyType_CARPENTRY] = true
+	require.Equal(t, uint64(12), character.Towns[0].ProductionRate().Wood)
+}
+
+func TestSimpleLogic_GetResearchState(t *testing.T) {
+	logic, _, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test")
+	session.SelectedCharacter.Technologies = model.Technologies{rpc.TechnologyType_TRAPPING: true}
+	session.SelectedCharacter.Research = &model.Research{TechnologyID: rpc.TechnologyType_TANNING, TicksLeft: 5}
+
+	resp, err := logic.GetResearchState(context.Background(), session, &rpc.GetResearchStateRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Technologies, len(model.TechnologyTree))
+	require.True(t, resp.Technologies[rpc.TechnologyType_TRAPPING].Researched)
+	require.False(t, resp.Technologies[rpc.TechnologyType_TANNING].Researched)
+	require.Equal(t, []rpc.TechnologyType{rpc.TechnologyType_TRAPPING},
+		resp.Technologies[rpc.TechnologyType_TANNING].RequiredTechnologies)
+
+	require.Equal(t, rpc.TechnologyType_TANNING, resp.Current.TechnologyID)
+	require.Equal(t, uint64(5), resp.Current.TicksLeft)
+	require.Equal(t, model.TechnologyTree[rpc.TechnologyType_TANNING].ResearchTime, resp.Current.ResearchTime)
+}
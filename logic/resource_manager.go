@@ -2,7 +2,12 @@ package logic
 
 import (
 	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/tracing"
+	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
 	"time"
 )
 
@@ -29,14 +34,72 @@ var resourceIncrementValue = model.ChunkResources{
 	Plants:  6,
 }
 
-func (r *ResourceManager) Update() {
-	tx, err := r.logic.db.BeginTransaction(true, true)
+func (r *ResourceManager) Update(ctx context.Context) {
+	logger := tracing.Logger(ctx, r.logger)
+
+	tx, err := r.logic.db.BeginTransaction(ctx, true, true)
 	if err != nil {
-		r.logger.WithError(err).Error("Failed to begin transaction")
+		logger.WithError(err).Error("Failed to begin transaction")
 		return
 	}
 
 	if err := tx.IncrementMapResources(resourceIncrementValue, model.ChunkResourcesLimit); err != nil {
-		r.logger.WithError(err).Error("Failed to increment map resources")
+		logger.WithError(err).Error("Failed to increment map resources")
+	}
+}
+
+// Harvest - takes the requested resources from the global chunks reached by the territories of the character's towns.
+// Chunks are visited in the same order by all sessions, so concurrent harvests don't deadlock
+func (r *ResourceManager) Harvest(session *PlayerSession, requested model.ChunkResources) (taken model.ChunkResources, err error) {
+	if requested.IsEmpty() {
+		return
 	}
+
+	type chunkCoords struct {
+		x, y int64
+	}
+
+	size := r.logic.MapChunkSize()
+
+	var chunks []chunkCoords
+	visited := make(map[chunkCoords]bool)
+	for _, town := range session.SelectedCharacter.Towns {
+		radius := int(math.Ceil(town.TerritoryRadius()))
+		for x := floorDiv(int(town.X)-radius, size); x <= floorDiv(int(town.X)+radius, size); x++ {
+			for y := floorDiv(int(town.Y)-radius, size); y <= floorDiv(int(town.Y)+radius, size); y++ {
+				if !town.TerritoryIntersects(x*size, (x+1)*size, y*size, (y+1)*size) {
+					continue
+				}
+
+				coords := chunkCoords{x: int64(x), y: int64(y)}
+				if !visited[coords] {
+					visited[coords] = true
+					chunks = append(chunks, coords)
+				}
+			}
+		}
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		if chunks[i].x != chunks[j].x {
+			return chunks[i].x < chunks[j].x
+		}
+		return chunks[i].y < chunks[j].y
+	})
+
+	for _, chunk := range chunks {
+		remaining := requested.Subtract(taken)
+		if remaining.IsEmpty() {
+			break
+		}
+
+		chunkTaken, err := session.Tx.TakeChunkResources(chunk.x, chunk.y, remaining)
+		if err != nil {
+			return taken, fmt.Errorf("failed to take resources of chunk (%d, %d): %w", chunk.x, chunk.y, err)
+		}
+
+		taken = taken.Add(chunkTaken)
+	}
+
+	return
 }
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResourceManager_Harvest(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+
+	// The territory of the first town reaches the neighbouring chunk
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, X: 95, Y: 50},
+		model.Town{ID: 2, X: -150, Y: 50},
+	)
+
+	// The chunks are visited in the sorted order, the depleted ones give what they have left
+	db.On("TakeChunkResources", int64(-2), int64(0), model.ChunkResources{Trees: 10, Plants: 2}).
+		Return(model.ChunkResources{Trees: 3}, nil)
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 7, Plants: 2}).
+		Return(model.ChunkResources{}, nil)
+	db.On("TakeChunkResources", int64(1), int64(0), model.ChunkResources{Trees: 7, Plants: 2}).
+		Return(model.ChunkResources{Trees: 7, Plants: 1}, nil)
+
+	taken, err := logic.resourceManager.Harvest(session, model.ChunkResources{Trees: 10, Plants: 2})
+	require.NoError(t, err)
+	require.Equal(t, model.ChunkResources{Trees: 10, Plants: 1}, taken)
+	db.AssertExpectations(t)
+}
+
+func TestResourceManager_Harvest_Failed(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, X: 50, Y: 50})
+
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 4}).
+		Return(model.ChunkResources{}, sql.ErrConnDone)
+
+	_, err := logic.resourceManager.Harvest(session, model.ChunkResources{Trees: 4})
+	require.True(t, errors.Is(err, sql.ErrConnDone))
+	db.AssertExpectations(t)
+}
@@ -4,9 +4,11 @@ import (
 	"abbysoft/gardarike-online/model"
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestSimpleLogic_SelectCharacter(t *testing.T) {
@@ -18,21 +20,21 @@ func TestSimpleLogic_SelectCharacter(t *testing.T) {
 
 	session.AccountID = 1
 	character := model.Character{
-		ID:                2,
-		AccountID:         1,
-		Name:              "test2",
-		MaxPopulation:     1,
-		CurrentPopulation: 1,
-		Towns:             nil,
-		Resources:         model.ResourcesPlaceTown,
+		ID:        2,
+		AccountID: 1,
+		Name:      "test2",
+		Towns:     nil,
 	}
 
-	towns := []model.Town{{ID: 1, X: 1, Y: 5, OwnerName: "test2", Name: "town", Population: 100}}
+	towns := []model.Town{
+		{ID: 1, X: 1, Y: 5, OwnerName: "test2", Name: "town", Population: 100, Resources: model.Resources{Wood: 600, Food: 1000}},
+		{ID: 2, X: 7, Y: 5, OwnerName: "test2", Name: "town2", Population: 10, Resources: model.Resources{Wood: 400, Stone: 1000}},
+	}
 
 	db.On("GetCharacter", int64(2)).Return(character, nil)
 	db.On("GetTowns", character.Name).Return(towns, nil)
 
-	resp, err := logic.SelectCharacter(session, request)
+	resp, err := logic.SelectCharacter(context.Background(), session, request)
 	require.NoError(t, err)
 	require.NotNil(t, resp)
 	require.Equal(t, 1, len(logic.EventsChan))
@@ -55,19 +57,42 @@ func TestSimpleLogic_SelectCharacter_WrongAccount(t *testing.T) {
 
 	session.AccountID = 1
 	character := model.Character{
-		ID:                10,
-		AccountID:         2,
-		Name:              "test10",
-		MaxPopulation:     1,
-		CurrentPopulation: 1,
-		Towns:             nil,
+		ID:        10,
+		AccountID: 2,
+		Name:      "test10",
+		Towns:     nil,
 	}
 
 	db.On("GetCharacter", int64(10)).Return(character, nil)
 
-	resp, err := logic.SelectCharacter(session, request)
+	resp, err := logic.SelectCharacter(context.Background(), session, request)
 	db.AssertExpectations(t)
 
 	assert.EqualError(t, err, model.ErrForbidden.Error())
 	assert.Nil(t, resp)
 }
+
+func TestSimpleLogic_SelectCharacter_Banned(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	request := &rpc.SelectCharacterRequest{
+		SessionID:   "sessionID",
+		CharacterID: 3,
+	}
+
+	session.AccountID = 1
+	character := model.Character{
+		ID:        3,
+		AccountID: 1,
+		Name:      "test3",
+		Sanctions: model.Sanctions{BannedUntil: time.Now().Add(time.Hour)},
+	}
+
+	db.On("GetCharacter", int64(3)).Return(character, nil)
+
+	resp, err := logic.SelectCharacter(context.Background(), session, request)
+	db.AssertExpectations(t)
+
+	assert.EqualError(t, err, model.ErrCharacterBanned.Error())
+	assert.Nil(t, resp)
+	assert.Nil(t, session.SelectedCharacter)
+}
@@ -0,0 +1,139 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// caravanDestination - returns the destination town of the caravan and ID of its owner
+func (s *SimpleLogic) caravanDestination(ctx context.Context, session *PlayerSession, request *rpc.SendCaravanRequest) (model.Town, int64, model.Error) {
+	character := session.SelectedCharacter
+	tx := session.Tx
+
+	if request.ToTownID != 0 {
+		if town := character.Town(request.ToTownID); town != nil {
+			return *town, character.ID, nil
+		}
+	} else if request.ToCharacterName == "" {
+		return model.Town{}, 0, model.ErrBadRequest
+	}
+
+	tx.SetAutoRollBack(false)
+	defer tx.SetAutoRollBack(true)
+
+	var town model.Town
+	if request.ToTownID != 0 {
+		var err error
+		if town, err = tx.GetTown(request.ToTownID); errors.Is(err, sql.ErrNoRows) {
+			return town, 0, model.ErrTownNotFound
+		} else if err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to get destination town")
+			return town, 0, model.ErrInternalServerError
+		}
+	} else {
+		towns, err := tx.GetTowns(request.ToCharacterName)
+		if err != nil {
+			s.logger(ctx).WithError(err).Error("Failed to get recipient towns")
+			return town, 0, model.ErrInternalServerError
+		}
+
+		if len(towns) == 0 {
+			return town, 0, model.ErrTownNotFound
+		}
+
+		// The first town is the capital
+		town = towns[0]
+	}
+
+	ownerID, err := tx.GetCharacterID(town.OwnerName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return town, 0, model.ErrCharacterNotFound
+	} else if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get recipient")
+		return town, 0, model.ErrInternalServerError
+	}
+
+	return town, ownerID, nil
+}
+
+func (s *SimpleLogic) SendCaravan(ctx context.Context, session *PlayerSession, request *rpc.SendCaravanRequest) (*rpc.SendCaravanResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":       session.SessionID,
+		"fromTownID":      request.FromTownID,
+		"toTownID":        request.ToTownID,
+		"toCharacterName": request.ToCharacterName,
+		"resources":       request.Resources,
+	}).Info("SendCaravan")
+
+	resources := model.ToModelResources(request.Resources)
+	if resources.IsEmpty() {
+		return nil, model.ErrBadRequest
+	}
+
+	character := session.SelectedCharacter
+
+	source := character.Capital()
+	if request.FromTownID != 0 {
+		source = character.Town(request.FromTownID)
+	}
+
+	if source == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	if len(character.Caravans) >= model.MaxCaravans {
+		return nil, model.ErrCaravanLimitReached
+	}
+
+	destination, recipientID, modelErr := s.caravanDestination(ctx, session, request)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+
+	if destination.ID == source.ID {
+		return nil, model.ErrBadRequest
+	}
+
+	updated := *source
+	if !updated.Resources.Subtract(resources) {
+		return nil, model.ErrNotEnoughResources
+	}
+
+	travelTime, err := s.caravanTravelTime(session.Tx, source.Location(), destination.Location())
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to calculate caravan travel time")
+		return nil, model.ErrInternalServerError
+	}
+
+	caravan := model.Caravan{
+		CharacterID:   character.ID,
+		Owner:         character.Name,
+		ToCharacterID: recipientID,
+		FromTownID:    source.ID,
+		ToTownID:      destination.ID,
+		From:          source.Location(),
+		To:            destination.Location(),
+		Resources:     resources,
+		TravelTime:    travelTime,
+		TicksLeft:     travelTime,
+	}
+
+	if caravan.ID, err = session.Tx.AddCaravan(caravan); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add caravan")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update source town")
+		return nil, model.ErrInternalServerError
+	}
+
+	*source = updated
+	character.Caravans = append(character.Caravans, caravan)
+
+	return &rpc.SendCaravanResponse{Caravan: caravan.ToRPC()}, nil
+}
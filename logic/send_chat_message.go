@@ -2,40 +2,86 @@ package logic
 
 import (
 	"abbysoft/gardarike-online/model"
-	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
 	log "github.com/sirupsen/logrus"
+	"time"
+	"unicode/utf8"
 )
 
-func (s *SimpleLogic) SendChatMessage(session *PlayerSession, request *rpc.SendChatMessageRequest) (*rpc.SendChatMessageResponse, model.Error) {
-	s.log.WithFields(log.Fields{
+func (s *SimpleLogic) SendChatMessage(ctx context.Context, session *PlayerSession, request *rpc.SendChatMessageRequest) (*rpc.SendChatMessageResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
 		"sessionID": request.SessionID,
 		"text":      request.Text,
+		"channel":   request.Channel,
+		"recipient": request.Recipient,
 	}).Info("SendChatMessage")
 
-	if len(request.Text) > s.config.ChatMessageMaxLength {
+	if utf8.RuneCountInString(request.Text) > s.config.ChatMessageMaxLength {
 		return nil, model.ErrMessageTooLong
 	}
 
+	// Commands are sent to any channel, the result is sent to the player only
+	if model.IsChatCommand(request.Text) {
+		if err := s.executeChatCommand(ctx, session, request.Text); err != nil {
+			return nil, err
+		}
+
+		return &rpc.SendChatMessageResponse{}, nil
+	}
+
+	if !model.IsValidChatChannel(int32(request.Channel)) {
+		return nil, model.ErrBadRequest
+	}
+
 	message := model.ChatMessage{
-		ID:     0,
-		Sender: session.SelectedCharacter.Name,
-		Text:   request.Text,
+		ID:      0,
+		Sender:  session.SelectedCharacter.Name,
+		Text:    model.UnescapeChatCommand(request.Text),
+		Channel: request.Channel,
+	}
+
+	if request.Channel == rpc.ChatMessage_DIRECT {
+		message.Recipient = request.Recipient
+	}
+
+	messageID, err := s.sendChatMessage(ctx, session, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.SendChatMessageResponse{
+		MessageID: messageID,
+	}, nil
+}
+
+// sendChatMessage - filters, stores and delivers the message of the player, returns ID of the stored message
+func (s *SimpleLogic) sendChatMessage(ctx context.Context, session *PlayerSession, message model.ChatMessage) (int64, model.Error) {
+	if err := s.checkChatSanctions(ctx, session); err != nil {
+		return 0, err
+	}
+
+	message.Text = model.MaskBannedWords(message.Text, s.config.ChatBannedWords)
+	message.Time = time.Now()
+
+	topics, modelErr := s.chatDelivery(ctx, session, &message)
+	if modelErr != nil {
+		return 0, modelErr
 	}
 
 	if insertedID, err := session.Tx.AddChatMessage(message); err != nil {
-		s.log.WithError(err).Error("Failed to SendChatMessage")
-		return nil, model.ErrInternalServerError
+		s.logger(ctx).WithError(err).Error("Failed to SendChatMessage")
+		return 0, model.ErrInternalServerError
 	} else {
 		message.ID = insertedID
 	}
 
-	s.EventsChan <- model.EventWrapper{
-		Topic: consts.GlobalTopic,
-		Event: model.NewChatMessageEvent(message).Event,
+	for _, topic := range topics {
+		s.publishEvent(ctx, model.EventWrapper{
+			Topic: topic,
+			Event: model.NewChatMessageEvent(message).Event,
+		})
 	}
 
-	return &rpc.SendChatMessageResponse{
-		MessageID: message.ID,
-	}, nil
+	return message.ID, nil
 }
@@ -0,0 +1,145 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/metrics"
+	"abbysoft/gardarike-online/model"
+	"abbysoft/gardarike-online/tracing"
+	"context"
+	"sort"
+	"time"
+)
+
+// SessionInfo - read-only snapshot of the player session
+type SessionInfo struct {
+	SessionID       string
+	AccountID       int64
+	CharacterID     int64
+	CharacterName   string
+	LastRequestTime time.Time
+}
+
+func (s *SimpleLogic) getSession(sessionID string) (*PlayerSession, bool) {
+	s.sessionsMutex.RLock()
+	defer s.sessionsMutex.RUnlock()
+
+	session, found := s.sessions[sessionID]
+	return session, found
+}
+
+func (s *SimpleLogic) addSession(session *PlayerSession) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	s.sessions[session.SessionID] = session
+	metrics.OnlineSessions.Set(float64(len(s.sessions)))
+}
+
+func (s *SimpleLogic) removeSession(sessionID string) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	delete(s.sessions, sessionID)
+	delete(s.characterNames, sessionID)
+	metrics.OnlineSessions.Set(float64(len(s.sessions)))
+}
+
+// setSessionCharacter - remembers the character selected in the session
+func (s *SimpleLogic) setSessionCharacter(sessionID string, name string) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	s.characterNames[sessionID] = name
+}
+
+// onlineCharacters - returns sorted names of the characters selected in the sessions. Unlike Sessions,
+// it doesn't lock the sessions, so it's safe to call while handling the request of some session
+func (s *SimpleLogic) onlineCharacters() []string {
+	s.sessionsMutex.RLock()
+	defer s.sessionsMutex.RUnlock()
+
+	unique := make(map[string]bool, len(s.characterNames))
+	result := make([]string, 0, len(s.characterNames))
+	for _, name := range s.characterNames {
+		if !unique[name] {
+			unique[name] = true
+			result = append(result, name)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// activeSessions - returns all sessions registered at the moment of the call
+func (s *SimpleLogic) activeSessions() []*PlayerSession {
+	s.sessionsMutex.RLock()
+	defer s.sessionsMutex.RUnlock()
+
+	result := make([]*PlayerSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		result = append(result, session)
+	}
+
+	return result
+}
+
+// findCharacterSession - returns the first session where the selected character matches the predicate
+func (s *SimpleLogic) findCharacterSession(match func(character *model.Character) bool) *PlayerSession {
+	for _, session := range s.activeSessions() {
+		session.Mutex.Lock()
+		selected := session.SelectedCharacter != nil && match(session.SelectedCharacter)
+		session.Mutex.Unlock()
+
+		if selected {
+			return session
+		}
+	}
+
+	return nil
+}
+
+// sessionContext - returns the context tagged with the session and its selected character.
+// Should be called with the session mutex locked
+func sessionContext(ctx context.Context, session *PlayerSession) context.Context {
+	info := tracing.RequestInfoFromContext(ctx)
+	info.SessionID = session.SessionID
+	info.AccountID = session.AccountID
+
+	if session.SelectedCharacter != nil {
+		info.CharacterID = session.SelectedCharacter.ID
+		info.CharacterName = session.SelectedCharacter.Name
+	}
+
+	return tracing.WithRequestInfo(ctx, info)
+}
+
+// backgroundContext - returns the context for the server initiated work with the new request ID
+func backgroundContext(name string) context.Context {
+	return tracing.WithRequestInfo(context.Background(), tracing.RequestInfo{
+		RequestID:   tracing.NewTraceID(),
+		RequestName: name,
+	})
+}
+
+// Sessions - returns snapshots of all online sessions
+func (s *SimpleLogic) Sessions() []SessionInfo {
+	var result []SessionInfo
+
+	for _, session := range s.activeSessions() {
+		session.Mutex.Lock()
+		info := SessionInfo{
+			SessionID:       session.SessionID,
+			AccountID:       session.AccountID,
+			LastRequestTime: session.LastRequestTime,
+		}
+		if session.SelectedCharacter != nil {
+			info.CharacterID = session.SelectedCharacter.ID
+			info.CharacterName = session.SelectedCharacter.Name
+		}
+		session.Mutex.Unlock()
+
+		result = append(result, info)
+	}
+
+	return result
+}
@@ -0,0 +1,35 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) SetTaxRate(ctx context.Context, session *PlayerSession, request *rpc.SetTaxRateRequest) (*rpc.SetTaxRateResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"taxRate":   request.TaxRate,
+	}).Info("SetTaxRate")
+
+	if request.TaxRate > model.MaxTaxRate {
+		return nil, model.ErrInvalidTaxRate
+	}
+
+	// The character is changed only if it's saved
+	updated := *session.SelectedCharacter
+	updated.TaxRate = request.TaxRate
+
+	if err := session.Tx.UpdateCharacter(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update character")
+		return nil, model.ErrInternalServerError
+	}
+
+	*session.SelectedCharacter = updated
+
+	return &rpc.SetTaxRateResponse{
+		TaxRate:   updated.TaxRate,
+		TaxIncome: updated.TaxIncome(),
+	}, nil
+}
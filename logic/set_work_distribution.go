@@ -0,0 +1,57 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) SetWorkDistribution(ctx context.Context, session *PlayerSession, request *rpc.SetWorkDistributionRequest) (*rpc.SetWorkDistributionResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"jobs":      request.Jobs,
+	}).Info("SetWorkDistribution")
+
+	character := session.SelectedCharacter
+
+	buildings, err := session.Tx.GetCharacterBuildings(character.ID)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get character buildings")
+		return nil, model.ErrInternalServerError
+	}
+
+	distribution := make(model.WorkDistribution)
+	for _, assignment := range request.Jobs {
+		job, found := model.Jobs[assignment.Job]
+		if !found {
+			return nil, model.ErrBadRequest
+		}
+
+		if _, duplicated := distribution[assignment.Job]; duplicated {
+			return nil, model.ErrBadRequest
+		}
+
+		if assignment.Workers > 0 && !job.IsUnlocked(buildings) {
+			return nil, model.ErrJobLocked
+		}
+
+		distribution[assignment.Job] = assignment.Workers
+	}
+
+	if distribution.Assigned() > character.Population() {
+		return nil, model.ErrNotEnoughWorkers
+	}
+
+	if err := session.Tx.UpdateWorkDistribution(character.ID, distribution); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update work distribution")
+		return nil, model.ErrInternalServerError
+	}
+
+	character.Workers = distribution
+
+	return &rpc.SetWorkDistributionResponse{
+		IdleCount: distribution.Idle(character.Population()),
+		Jobs:      distribution.ToRPC(),
+	}, nil
+}
@@ -0,0 +1,139 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_SetWorkDistribution(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 5})
+
+	request := &rpc.SetWorkDistributionRequest{
+		Jobs: []*rpc.JobAssignment{
+			{Job: rpc.JobType_WOODCUTTER, Workers: 2},
+			{Job: rpc.JobType_MINER, Workers: 1},
+		},
+	}
+
+	expected := model.WorkDistribution{rpc.JobType_WOODCUTTER: 2, rpc.JobType_MINER: 1}
+	db.On("GetCharacterBuildings", int64(1)).Return(model.CharacterBuildings{rpc.BuildingType_QUARRY: 1}, nil)
+	db.On("UpdateWorkDistribution", int64(1), expected).Return(nil)
+
+	resp, err := logic.SetWorkDistribution(context.Background(), session, request)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.IdleCount)
+	require.Len(t, resp.Jobs, len(model.Jobs))
+	require.Equal(t, expected, session.SelectedCharacter.Workers)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_SetWorkDistribution_JobLocked(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 5})
+
+	request := &rpc.SetWorkDistributionRequest{
+		Jobs: []*rpc.JobAssignment{{Job: rpc.JobType_MINER, Workers: 1}},
+	}
+
+	db.On("GetCharacterBuildings", int64(1)).Return(model.CharacterBuildings{}, nil)
+
+	_, err := logic.SetWorkDistribution(context.Background(), session, request)
+	require.EqualError(t, err, model.ErrJobLocked.Error())
+}
+
+func TestSimpleLogic_SetWorkDistribution_NotEnoughWorkers(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 5})
+
+	request := &rpc.SetWorkDistributionRequest{
+		Jobs: []*rpc.JobAssignment{
+			{Job: rpc.JobType_WOODCUTTER, Workers: 3},
+			{Job: rpc.JobType_BUILDER, Workers: 3},
+		},
+	}
+
+	db.On("GetCharacterBuildings", int64(1)).Return(model.CharacterBuildings{}, nil)
+
+	_, err := logic.SetWorkDistribution(context.Background(), session, request)
+	require.EqualError(t, err, model.ErrNotEnoughWorkers.Error())
+	require.Nil(t, session.SelectedCharacter.Workers)
+}
+
+func TestSimpleLogic_UpdateSessionResources(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{
+			ID:         1,
+			X:          150,
+			Y:          50,
+			Population: 5,
+			Resources:  model.Resources{Food: 10},
+			Buildings:  []model.Building{model.Buildings[rpc.BuildingType_QUARRY]},
+		},
+		model.Town{ID: 2, X: 50, Y: 50},
+		model.Town{ID: 3, X: 55, Y: 45},
+	)
+	session.SelectedCharacter.Workers = model.WorkDistribution{rpc.JobType_WOODCUTTER: 2, rpc.JobType_FARMER: 1}
+
+	// The first chunk has only one tree left, the second one gives the rest
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 2, Plants: 1}).
+		Return(model.ChunkResources{Trees: 1, Plants: 1}, nil)
+	db.On("TakeChunkResources", int64(1), int64(0), model.ChunkResources{Trees: 1}).
+		Return(model.ChunkResources{Trees: 1}, nil)
+	// 5 citizens eat 2 food
+	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
+		return character.Towns[0].Resources == model.Resources{Wood: 2, Food: 10, Stone: 1} &&
+			character.Towns[1].Resources == model.Resources{}
+	})).Return(nil)
+
+	logic.updateSessionResources(context.Background(), session)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateSessionResources_DepletedChunk(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, X: 50, Y: 50, Population: 5, Resources: model.Resources{Food: 10}})
+	session.SelectedCharacter.Workers = model.WorkDistribution{rpc.JobType_WOODCUTTER: 4, rpc.JobType_HUNTER: 1}
+
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 4, Animals: 1}).
+		Return(model.ChunkResources{Trees: 2}, nil)
+	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
+		return character.Resources() == model.Resources{Wood: 2, Food: 8}
+	})).Return(nil)
+
+	logic.updateSessionResources(context.Background(), session)
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateSessionResources_SharedByTowns(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	logic.config.ChunkSize = 100
+
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, X: 50, Y: 50, Population: 3, Resources: model.Resources{Wood: 1999, Food: 10}},
+		model.Town{ID: 2, X: 55, Y: 45, Population: 1, Resources: model.Resources{Food: 10}},
+	)
+	session.SelectedCharacter.Workers = model.WorkDistribution{rpc.JobType_WOODCUTTER: 4}
+
+	db.On("TakeChunkResources", int64(0), int64(0), model.ChunkResources{Trees: 4}).
+		Return(model.ChunkResources{Trees: 4}, nil)
+	// The wood is shared by population, the capital storage keeps only one of its three
+	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
+		return character.Towns[0].Resources == model.Resources{Wood: 2000, Food: 9} &&
+			character.Towns[1].Resources == model.Resources{Wood: 1, Food: 9}
+	})).Return(nil)
+
+	logic.updateSessionResources(context.Background(), session)
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,210 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// siegeTarget - returns the town besieged by the army and ID of its owner,
+// returns false if the town doesn't exist anymore, is already owned by the army owner or the peace is made
+func (s *SimpleLogic) siegeTarget(session *PlayerSession, army model.Army) (model.Town, int64, bool, error) {
+	tx := session.Tx
+	tx.SetAutoRollBack(false)
+	town, err := tx.GetTown(army.TargetTownID)
+	tx.SetAutoRollBack(true)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return town, 0, false, nil
+	} else if err != nil {
+		return town, 0, false, fmt.Errorf("failed to get besieged town: %w", err)
+	}
+
+	if town.OwnerName == army.Owner {
+		return town, 0, false, nil
+	}
+
+	defenderID, err := tx.GetCharacterID(town.OwnerName)
+	if err != nil {
+		return town, 0, false, fmt.Errorf("failed to get town owner: %w", err)
+	}
+
+	war, err := atWar(tx, army.CharacterID, defenderID)
+	if err != nil {
+		return town, 0, false, err
+	}
+
+	return town, defenderID, war, nil
+}
+
+// publishSiegeEvent - sends the siege event to the attacker and the defender
+func (s *SimpleLogic) publishSiegeEvent(ctx context.Context, state rpc.SiegeEvent_State, defenderID int64,
+	town model.Town, army model.Army, battle model.Battle) {
+	s.publishEvent(ctx, model.NewSiegeEvent(army.CharacterID, state, town, army, battle))
+	s.publishEvent(ctx, model.NewSiegeEvent(defenderID, state, town, army, battle))
+}
+
+// startSiege - starts the siege of the target town by the arrived army
+func (s *SimpleLogic) startSiege(ctx context.Context, session *PlayerSession, army *model.Army) error {
+	town, defenderID, found, err := s.siegeTarget(session, *army)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		army.TargetTownID = 0
+		return nil
+	}
+
+	s.logger(ctx).WithFields(log.Fields{
+		"armyID": army.ID,
+		"townID": town.ID,
+	}).Info("Siege started")
+
+	s.publishSiegeEvent(ctx, rpc.SiegeEvent_STARTED, defenderID, town, *army, model.Battle{})
+	return nil
+}
+
+// updateSiege - the army fights a battle round with the garrison of the besieged town. The town is captured
+// if the garrison is destroyed, the siege is lifted if the army fails to capture the town in time
+func (s *SimpleLogic) updateSiege(ctx context.Context, session *PlayerSession, army *model.Army) error {
+	town, defenderID, found, err := s.siegeTarget(session, *army)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		army.TargetTownID = 0
+		army.SiegeTicks = 0
+		return nil
+	}
+
+	garrisons, err := session.Tx.GetGarrisons([]int64{town.ID})
+	if err != nil {
+		return fmt.Errorf("failed to get town garrison: %w", err)
+	}
+
+	garrison := garrisons[town.ID]
+	battle := model.ResolveBattleRound(army.Units, garrison, town.DefenseBonus())
+	army.Units.Subtract(battle.AttackerLosses)
+	garrison.Subtract(battle.DefenderLosses)
+	army.SiegeTicks++
+
+	if !battle.DefenderLosses.IsEmpty() {
+		if _, err := session.Tx.TakeGarrison(town.ID, battle.DefenderLosses); err != nil {
+			return fmt.Errorf("failed to take garrison losses: %w", err)
+		}
+	}
+
+	state := rpc.SiegeEvent_IN_PROGRESS
+	switch {
+	case army.Units.IsEmpty():
+		state = rpc.SiegeEvent_REPELLED
+	case garrison.IsEmpty() && army.SiegeTicks >= model.MinSiegeTicks:
+		state = rpc.SiegeEvent_CAPTURED
+		if err := s.captureTown(session, army, town); err != nil {
+			return err
+		}
+	case army.SiegeTicks >= model.MaxSiegeTicks:
+		state = rpc.SiegeEvent_LIFTED
+		army.TargetTownID = 0
+		army.SiegeTicks = 0
+	}
+
+	if state != rpc.SiegeEvent_IN_PROGRESS {
+		s.logger(ctx).WithFields(log.Fields{
+			"armyID": army.ID,
+			"townID": town.ID,
+			"state":  state,
+		}).Info("Siege finished")
+	}
+
+	s.publishSiegeEvent(ctx, state, defenderID, town, *army, battle)
+	return nil
+}
+
+// captureTown - transfers the town to the owner of the army, the army joins the town garrison.
+// The goods of the previous owner on the way to the town don't go to the new owner
+func (s *SimpleLogic) captureTown(session *PlayerSession, army *model.Army, town model.Town) error {
+	tx := session.Tx
+
+	captured := town
+	captured.Capture(army.Owner)
+	capturedAt := time.Now()
+	captured.CapturedAt = &capturedAt
+
+	if err := tx.SetTownOwner(town.ID, army.Owner); err != nil {
+		return fmt.Errorf("failed to change town owner: %w", err)
+	}
+
+	towns, err := tx.GetTowns(town.OwnerName)
+	if err != nil {
+		return fmt.Errorf("failed to get towns of previous owner: %w", err)
+	}
+
+	// The market orders, the deliveries and the caravans on the way to the town are moved to the capital
+	// of the previous owner, they are lost with its last town
+	previousOwner := model.Character{Towns: towns}
+	if capital := previousOwner.Capital(); capital != nil {
+		err = tx.MoveTownGoods(town.ID, capital.ID)
+	} else {
+		err = tx.DeleteTownGoods(town.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move goods of captured town: %w", err)
+	}
+
+	if err := tx.UpdateTown(captured); err != nil {
+		return fmt.Errorf("failed to update captured town: %w", err)
+	}
+
+	for _, training := range town.Training {
+		if err := tx.DeleteTraining(training.ID); err != nil {
+			return fmt.Errorf("failed to delete training: %w", err)
+		}
+	}
+
+	if err := tx.AddGarrison(town.ID, army.Units); err != nil {
+		return fmt.Errorf("failed to add army to the garrison: %w", err)
+	}
+
+	// The army is deleted after joining the garrison
+	army.Units = model.Units{}
+
+	character := session.SelectedCharacter
+	character.Towns = append(character.Towns, captured)
+	character.ShareTechnologies()
+
+	return nil
+}
+
+// updateLostTowns - removes the towns captured by other players from the character
+func (s *SimpleLogic) updateLostTowns(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+	if len(character.Towns) == 0 {
+		return
+	}
+
+	lost, err := session.Tx.GetLostTowns(character.Name, character.TownIDs())
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to get lost towns")
+		return
+	}
+
+	for _, id := range lost {
+		towns := make([]model.Town, 0, len(character.Towns))
+		for _, town := range character.Towns {
+			if town.ID != id {
+				towns = append(towns, town)
+			}
+		}
+
+		character.Towns = towns
+		s.logger(ctx).WithField("townID", id).Info("Town lost")
+	}
+}
@@ -0,0 +1,255 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSimpleLogic_AttackTown(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1}, model.Town{ID: 2})
+	session.SelectedCharacter.ProtectedUntil = time.Now().Add(time.Hour)
+
+	army := model.Army{ID: 3, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10},
+		Location: model.Vector2D{X: 100, Y: 100}}
+	db.On("GetArmy", int64(3)).Return(army, nil)
+
+	attack := func(townID int64) (*rpc.AttackTownResponse, model.Error) {
+		return logic.AttackTown(context.Background(), session, &rpc.AttackTownRequest{ArmyID: 3, TownID: townID})
+	}
+
+	// Own towns can't be attacked
+	db.On("GetTown", int64(1)).Return(model.Town{ID: 1, OwnerName: "general"}, nil)
+	_, err := attack(1)
+	require.EqualError(t, err, model.ErrBadRequest.Error())
+
+	// Only the towns of the empires at war can be attacked
+	db.On("GetTown", int64(6)).Return(model.Town{ID: 6, OwnerName: "neighbour"}, nil)
+	db.On("GetCharacterID", "neighbour").Return(int64(6), nil)
+	db.On("GetDiplomaticState", int64(1), int64(6)).Return(rpc.DiplomaticState_NON_AGGRESSION_PACT, nil)
+	_, err = attack(6)
+	require.EqualError(t, err, model.ErrNotAtWar.Error())
+
+	db.On("GetTown", int64(4)).Return(model.Town{ID: 4, OwnerName: "newbie"}, nil)
+	db.On("GetCharacterID", "newbie").Return(int64(4), nil)
+	db.On("GetDiplomaticState", int64(1), int64(4)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("GetCharacter", int64(4)).Return(model.Character{ID: 4, ProtectedUntil: time.Now().Add(time.Hour)}, nil)
+	_, err = attack(4)
+	require.EqualError(t, err, model.ErrTownProtected.Error())
+
+	db.On("GetTown", int64(5)).Return(model.Town{ID: 5, X: 200, Y: 200, OwnerName: "defender"}, nil)
+	db.On("GetCharacterID", "defender").Return(int64(2), nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("GetCharacter", int64(2)).Return(model.Character{ID: 2}, nil)
+	db.On("UpdateArmy", mock.MatchedBy(func(army model.Army) bool {
+		return army.TargetTownID == 5 && army.IsMoving && army.Destination == model.Vector2D{X: 200, Y: 200}
+	})).Return(nil)
+
+	resp, err := attack(5)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.Army.TargetTownID)
+
+	// The attacker isn't protected anymore
+	require.False(t, session.SelectedCharacter.IsProtected(time.Now()))
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateSiege(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 6}, model.Town{ID: 2})
+
+	army := model.Army{ID: 3, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10},
+		Location: model.Vector2D{X: 200, Y: 200}, TargetTownID: 5}
+	require.True(t, army.IsBesieging())
+
+	town := model.Town{
+		ID:        5,
+		Name:      "fort",
+		OwnerName: "defender",
+		Buildings: []model.Building{model.Buildings[rpc.BuildingType_WALL].AtLevel(1)},
+		Resources: model.Resources{Wood: 100, Gold: 40},
+		Training:  []model.UnitTraining{{ID: 7, TownID: 5, UnitType: rpc.UnitType_SPEARMAN, Count: 1, TicksLeft: 1}},
+	}
+	require.Equal(t, uint64(model.WallDefenseBonus), town.DefenseBonus())
+
+	db.On("GetTown", int64(5)).Return(town, nil)
+	db.On("GetCharacterID", "defender").Return(int64(2), nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("GetGarrisons", []int64{5}).Return(map[int64]model.Units{5: {Spearmen: 2}}, nil).Once()
+	db.On("GetGarrisons", []int64{5}).Return(map[int64]model.Units{}, nil)
+	db.On("TakeGarrison", int64(5), model.Units{Spearmen: 2}).Return(true, nil)
+
+	// The garrison is destroyed at once, but the town holds until the minimum siege time passes
+	for i := 0; i < model.MinSiegeTicks-1; i++ {
+		require.NoError(t, logic.updateSiege(context.Background(), session, &army))
+		event := <-logic.EventsChan
+		require.Equal(t, rpc.SiegeEvent_IN_PROGRESS, event.Event.GetSiegeEvent().State)
+		<-logic.EventsChan
+	}
+	require.Equal(t, uint64(9), army.Units.Cavalry)
+
+	db.On("SetTownOwner", int64(5), "general").Return(nil)
+	// The goods on the way to the town are sent to the capital of the defender
+	db.On("GetTowns", "defender").Return([]model.Town{{ID: 9, OwnerName: "defender"}}, nil)
+	db.On("MoveTownGoods", int64(5), int64(9)).Return(nil)
+	db.On("UpdateTown", mock.MatchedBy(func(town model.Town) bool {
+		return town.ID == 5 && town.OwnerName == "general" && town.Resources == model.Resources{Wood: 50, Gold: 20}
+	})).Return(nil)
+	db.On("DeleteTraining", int64(7)).Return(nil)
+	db.On("AddGarrison", int64(5), model.Units{Cavalry: 9}).Return(nil)
+
+	require.NoError(t, logic.updateSiege(context.Background(), session, &army))
+	require.True(t, army.Units.IsEmpty())
+
+	event := <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(1), event.Topic)
+	require.Equal(t, rpc.SiegeEvent_CAPTURED, event.Event.GetSiegeEvent().State)
+	event = <-logic.EventsChan
+	require.Equal(t, model.CharacterTopic(2), event.Topic)
+	require.Equal(t, "defender", event.Event.GetSiegeEvent().Defender)
+
+	// The captured town keeps its buildings and gets the technologies of the new owner
+	character := session.SelectedCharacter
+	require.Len(t, character.Towns, 3)
+	captured := character.Town(5)
+	require.Len(t, captured.Buildings, 1)
+	require.Empty(t, captured.Training)
+	require.NotNil(t, captured.Technologies)
+
+	// The capital isn't changed by the capture of the town with the lower ID
+	require.Equal(t, int64(6), character.Capital().ID)
+	require.NotNil(t, captured.CapturedAt)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateSiege_Lifted(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1}, model.Town{ID: 2})
+
+	army := model.Army{ID: 3, CharacterID: 1, Owner: "general", Units: model.Units{Spearmen: 20},
+		TargetTownID: 5, SiegeTicks: model.MaxSiegeTicks - 1}
+
+	db.On("GetTown", int64(5)).Return(model.Town{ID: 5, OwnerName: "defender"}, nil)
+	db.On("GetCharacterID", "defender").Return(int64(2), nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("GetGarrisons", []int64{5}).Return(map[int64]model.Units{5: {Spearmen: 20}}, nil)
+	db.On("TakeGarrison", int64(5), mock.Anything).Return(true, nil)
+
+	require.NoError(t, logic.updateSiege(context.Background(), session, &army))
+	require.Equal(t, rpc.SiegeEvent_LIFTED, (<-logic.EventsChan).Event.GetSiegeEvent().State)
+	require.False(t, army.IsBesieging())
+	require.Equal(t, int64(0), army.TargetTownID)
+}
+
+func TestSimpleLogic_TickSession_CaptureRolledBack(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1}, model.Town{ID: 2})
+	session.LastRequestTime = time.Now()
+	logic.config.AFKTimeout = time.Minute
+
+	army := model.Army{ID: 3, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10},
+		Location: model.Vector2D{X: 200, Y: 200}, TargetTownID: 5, SiegeTicks: model.MinSiegeTicks - 1}
+
+	saved := *newTestCharacter("general", model.Town{ID: 1}, model.Town{ID: 2})
+	db.On("GetLostTowns", "general", []int64{1, 2}).Return([]int64{}, nil)
+	db.On("GetActiveArmies", int64(1)).Return([]model.Army{army}, nil)
+	db.On("GetTown", int64(5)).Return(model.Town{ID: 5, OwnerName: "defender",
+		Training: []model.UnitTraining{{ID: 7, TownID: 5, UnitType: rpc.UnitType_SPEARMAN, Count: 1}}}, nil)
+	db.On("GetCharacterID", "defender").Return(int64(2), nil)
+	db.On("GetDiplomaticState", int64(1), int64(2)).Return(rpc.DiplomaticState_WAR, nil)
+	db.On("GetGarrisons", []int64{5}).Return(map[int64]model.Units{}, nil)
+	db.On("SetTownOwner", int64(5), "general").Return(nil)
+	db.On("GetTowns", "defender").Return([]model.Town{}, nil)
+	db.On("DeleteTownGoods", int64(5)).Return(nil)
+	db.On("UpdateTown", mock.Anything).Return(nil)
+	db.On("DeleteTraining", int64(7)).Return(nil)
+	db.On("AddGarrison", int64(5), model.Units{Cavalry: 10}).Return(nil)
+	db.On("DeleteArmy", int64(3)).Return(sql.ErrConnDone).
+		Run(func(mock.Arguments) { db.isCompleted = true })
+	db.On("GetCharacter", int64(1)).Return(saved, nil)
+	db.On("GetTowns", "general").Return(saved.Towns, nil)
+
+	logic.tickSession(context.Background(), session)
+
+	// The capture isn't saved, so the town isn't added to the character and nobody is told about it
+	require.Len(t, session.SelectedCharacter.Towns, 2)
+	require.Nil(t, session.SelectedCharacter.Town(5))
+	require.Empty(t, logic.EventsChan)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_UpdateLostTowns(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1}, model.Town{ID: 2})
+
+	db.On("GetLostTowns", "general", []int64{1, 2}).Return([]int64{1}, nil)
+	logic.updateLostTowns(context.Background(), session)
+
+	require.Len(t, session.SelectedCharacter.Towns, 1)
+	require.Equal(t, int64(2), session.SelectedCharacter.Capital().ID)
+
+	db.AssertExpectations(t)
+}
+
+func TestSimpleLogic_CaptureTown_Goods(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("general", model.Town{ID: 1, Resources: model.Resources{Gold: 1000}})
+
+	// The sell order and the delivery of the defender wait in the besieged town
+	order := model.MarketOrder{ID: 4, CharacterID: 2, Owner: "defender", TownID: 5, Resource: rpc.ResourceType_STONE,
+		Side: rpc.OrderSide_SELL, Price: 2, Amount: 10}
+	deliveries := []model.Delivery{{ID: 3, TownID: 5, Resources: model.Resources{Wood: 50}}}
+
+	db.On("SetTownOwner", int64(5), "general").Return(nil)
+	db.On("GetTowns", "defender").Return([]model.Town{{ID: 9, OwnerName: "defender"}}, nil)
+	db.On("MoveTownGoods", int64(5), int64(9)).Return(nil).Run(func(mock.Arguments) {
+		order.TownID = 9
+		deliveries[0].TownID = 9
+	})
+	db.On("UpdateTown", mock.Anything).Return(nil)
+	db.On("AddGarrison", int64(5), model.Units{Cavalry: 10}).Return(nil)
+
+	army := model.Army{ID: 3, CharacterID: 1, Owner: "general", Units: model.Units{Cavalry: 10}, TargetTownID: 5}
+	require.NoError(t, logic.captureTown(session, &army, model.Town{ID: 5, OwnerName: "defender"}))
+
+	// The delivery waiting in the captured town is delivered to the capital of the previous owner
+	defender := NewPlayerSession(2)
+	defender.Tx = session.Tx
+	defender.SelectedCharacter = &model.Character{ID: 2, Name: "defender", Towns: []model.Town{{ID: 9}}}
+	db.On("TakeDeliveries", []int64{9}).Return(deliveries, nil)
+
+	logic.updateDeliveries(context.Background(), defender)
+	require.Equal(t, uint64(50), defender.SelectedCharacter.Towns[0].Resources.Wood)
+
+	// The gold for the order filled after the capture goes to the capital of the previous owner
+	db.On("GetCharacterMarketOrders", int64(1)).Return([]model.MarketOrder{}, nil)
+	db.On("AddMarketOrder", mock.Anything).Return(int64(12), nil)
+	db.On("GetMatchingOrders", mock.Anything, model.MaxMatchedOrders).Return([]model.MarketOrder{order}, nil)
+	db.On("GetDiplomaticRelations", int64(1)).Return([]model.DiplomaticRelation{}, nil)
+	db.On("AddTrade", mock.Anything).Return(int64(1), nil)
+	db.On("DeleteMarketOrder", int64(4)).Return(nil)
+	db.On("DeleteMarketOrder", int64(12)).Return(nil)
+	db.On("AddDelivery", mock.MatchedBy(func(delivery model.Delivery) bool {
+		return delivery.TownID == 9 && delivery.Resources.Gold > 0
+	})).Return(nil)
+
+	_, err := logic.PlaceMarketOrder(context.Background(), session, &rpc.PlaceMarketOrderRequest{
+		Resource: rpc.ResourceType_STONE,
+		Side:     rpc.OrderSide_BUY,
+		Price:    2,
+		Amount:   10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), session.SelectedCharacter.Towns[0].Resources.Stone)
+
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,63 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) StartResearch(ctx context.Context, session *PlayerSession, request *rpc.StartResearchRequest) (*rpc.StartResearchResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":    session.SessionID,
+		"technologyID": request.TechnologyID,
+		"townID":       request.TownID,
+	}).Info("StartResearch")
+
+	character := session.SelectedCharacter
+
+	technology, found := model.TechnologyTree[request.TechnologyID]
+	if !found {
+		return nil, model.ErrTechnologyNotFound
+	}
+
+	if character.Research != nil {
+		return nil, model.ErrResearchInProgress
+	}
+
+	if err := character.Technologies.CanResearch(technology); err != nil {
+		return nil, err
+	}
+
+	town := character.Capital()
+	if request.TownID != 0 {
+		town = character.Town(request.TownID)
+	}
+
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	updated := *town
+	if !updated.Resources.Subtract(technology.Cost) {
+		return nil, model.ErrNotEnoughResources
+	}
+
+	research := model.NewResearch(technology)
+	if err := session.Tx.SetResearch(character.ID, research); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to save research")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	*town = updated
+	character.Research = &research
+
+	return &rpc.StartResearchResponse{
+		Research: research.ToRPC(),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) TrainUnits(ctx context.Context, session *PlayerSession, request *rpc.TrainUnitsRequest) (*rpc.TrainUnitsResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID": session.SessionID,
+		"townID":    request.TownID,
+		"unitType":  request.UnitType,
+		"count":     request.Count,
+	}).Info("TrainUnits")
+
+	if !model.IsValidUnitType(int32(request.UnitType)) || request.Count == 0 || request.Count > model.MaxTrainingCount {
+		return nil, model.ErrBadRequest
+	}
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	if !town.CanTrainUnits() {
+		return nil, model.ErrPrerequisitesNotMet
+	}
+
+	if town.IsTrainingQueueFull() {
+		return nil, model.ErrTrainingQueueFull
+	}
+
+	updated := *town
+	if !updated.Resources.Subtract(model.UnitTypes[request.UnitType].Cost.Multiply(request.Count)) {
+		return nil, model.ErrNotEnoughResources
+	}
+
+	training := model.NewUnitTraining(town.ID, request.UnitType, request.Count)
+
+	id, err := session.Tx.AddTraining(training)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add training")
+		return nil, model.ErrInternalServerError
+	}
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	training.ID = id
+	updated.Training = append(updated.Training, training)
+	*town = updated
+
+	return &rpc.TrainUnitsResponse{
+		Training: training.ToRPC(len(town.Training) == 1),
+	}, nil
+}
@@ -0,0 +1,32 @@
+package logic
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// updateTreasury - collects the taxes and pays the upkeep of every town,
+// the buildings of the towns which can't pay the upkeep are disabled until the debt is repaid
+func (s *SimpleLogic) updateTreasury(ctx context.Context, session *PlayerSession) {
+	character := session.SelectedCharacter
+
+	for i := range character.Towns {
+		town := &character.Towns[i]
+
+		wasInDebt := town.IsInDebt()
+		town.UpdateTreasury(character.TaxRate)
+
+		logger := s.logger(ctx).WithFields(log.Fields{
+			"townID": town.ID,
+			"gold":   town.Resources.Gold,
+			"debt":   town.Debt,
+		})
+
+		switch {
+		case !wasInDebt && town.IsInDebt():
+			logger.Info("Town can't pay the upkeep, buildings are disabled")
+		case wasInDebt && !town.IsInDebt():
+			logger.Info("Town repaid the debt, buildings are enabled")
+		}
+	}
+}
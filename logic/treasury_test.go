@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimpleLogic_UpdateTreasury(t *testing.T) {
+	logic, _, session := NewLogicMock()
+
+	quarry := model.Buildings[rpc.BuildingType_QUARRY].AtLevel(1)
+	temple := model.Buildings[rpc.BuildingType_TEMPLE].AtLevel(1)
+	house := model.Buildings[rpc.BuildingType_HOUSE].AtLevel(1)
+	session.SelectedCharacter = newTestCharacter("test",
+		model.Town{ID: 1, Population: 50, Buildings: []model.Building{house, quarry, temple}})
+	session.SelectedCharacter.TaxRate = 10
+	town := &session.SelectedCharacter.Towns[0]
+
+	// 5 gold of taxes, 5 gold of upkeep
+	require.Equal(t, uint64(5), town.TaxIncome(10))
+	require.Equal(t, uint64(5), town.Upkeep())
+
+	town.Resources.Gold = 3
+	logic.updateTreasury(context.Background(), session)
+	require.Equal(t, uint64(3), town.Resources.Gold)
+	require.False(t, town.IsInDebt())
+
+	// Lower taxes don't cover the upkeep, the town gets into debt and its buildings with upkeep are disabled
+	session.SelectedCharacter.TaxRate = 2
+	logic.updateTreasury(context.Background(), session)
+	require.Equal(t, uint64(0), town.Resources.Gold)
+	require.Equal(t, uint64(1), town.Debt)
+
+	require.Equal(t, model.Resources{Food: 1}, town.ProductionRate())
+	require.Equal(t, int64(0), session.SelectedCharacter.HappinessFactors().Buildings)
+
+	// The debt is repaid before the upkeep
+	session.SelectedCharacter.TaxRate = 20
+	logic.updateTreasury(context.Background(), session)
+	require.Equal(t, uint64(4), town.Resources.Gold)
+	require.False(t, town.IsInDebt())
+
+	require.Equal(t, model.Resources{Food: 1, Stone: 1}, town.ProductionRate())
+	require.Equal(t, int64(5), session.SelectedCharacter.HappinessFactors().Buildings)
+}
+
+func TestSimpleLogic_StoreGold(t *testing.T) {
+	town := model.Town{ID: 1}
+	town.Store(model.Resources{Wood: model.TownStorageLimit.Wood + 1, Gold: 1_000_000})
+
+	require.Equal(t, model.TownStorageLimit.Wood, town.Resources.Wood)
+	require.Equal(t, uint64(1_000_000), town.Resources.Gold)
+}
+
+func TestSimpleLogic_SetTaxRate(t *testing.T) {
+	logic, db, session := NewLogicMock()
+	session.SelectedCharacter = newTestCharacter("test", model.Town{ID: 1, Population: 50})
+
+	_, err := logic.SetTaxRate(context.Background(), session, &rpc.SetTaxRateRequest{TaxRate: model.MaxTaxRate + 1})
+	require.EqualError(t, err, model.ErrInvalidTaxRate.Error())
+
+	db.On("UpdateCharacter", mock.MatchedBy(func(character model.Character) bool {
+		return character.TaxRate == 30
+	})).Return(nil)
+
+	resp, err := logic.SetTaxRate(context.Background(), session, &rpc.SetTaxRateRequest{TaxRate: 30})
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), resp.TaxRate)
+	require.Equal(t, uint64(15), resp.TaxIncome)
+	require.Equal(t, uint64(30), session.SelectedCharacter.TaxRate)
+
+	// Higher taxes make the population less happy
+	require.Equal(t, int64(-15), session.SelectedCharacter.HappinessFactors().Taxes)
+
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,66 @@
+package logic
+
+import (
+	"abbysoft/gardarike-online/model"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *SimpleLogic) UpgradeBuilding(ctx context.Context, session *PlayerSession, request *rpc.UpgradeBuildingRequest) (*rpc.UpgradeBuildingResponse, model.Error) {
+	s.logger(ctx).WithFields(log.Fields{
+		"sessionID":  session.SessionID,
+		"townID":     request.TownID,
+		"buildingID": request.BuildingID,
+	}).Info("UpgradeBuilding")
+
+	town := session.SelectedCharacter.Town(request.TownID)
+	if town == nil {
+		return nil, model.ErrTownNotFound
+	}
+
+	index := town.FindBuilding(request.BuildingID)
+	if index < 0 {
+		return nil, model.ErrBuildingNotFound
+	}
+
+	building := town.Buildings[index]
+	if building.Level >= model.MaxBuildingLevel {
+		return nil, model.ErrMaxLevelReached
+	}
+
+	if town.IsUnderConstruction(building.TownBuildingID) {
+		return nil, model.ErrBuildingUnderConstruction
+	}
+
+	if town.IsConstructionQueueFull() {
+		return nil, model.ErrConstructionQueueFull
+	}
+
+	construction := model.NewConstruction(town.ID, building.AtLevel(building.Level+1))
+
+	updated := *town
+	if !updated.Resources.Subtract(construction.Building.Cost) {
+		return nil, model.ErrNotEnoughResources
+	}
+
+	id, err := session.Tx.AddConstruction(construction)
+	if err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to add construction")
+		return nil, model.ErrInternalServerError
+	}
+
+	construction.ID = id
+	updated.Construction = append(append([]model.Construction{}, town.Construction...), construction)
+
+	if err := session.Tx.UpdateTown(updated); err != nil {
+		s.logger(ctx).WithError(err).Error("Failed to update town")
+		return nil, model.ErrInternalServerError
+	}
+
+	*town = updated
+
+	return &rpc.UpgradeBuildingResponse{
+		Construction: construction.ToRPC(len(town.Construction) <= s.config.ConstructionSlots),
+	}, nil
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const namespace = "gardarike"
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of handled client requests by request type and error code.",
+	}, []string{"request", "code"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Client request handling latency by request type.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"request"})
+
+	OnlineSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "online_sessions",
+		Help:      "Number of player sessions registered on the server.",
+	})
+
+	GameLoopTickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "game_loop_tick_duration_seconds",
+		Help:      "Time spent to update all sessions in a game loop tick.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	GameLoopOverruns = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "game_loop_overruns_total",
+		Help:      "Number of game loop ticks which took longer than the tick interval.",
+	})
+
+	ChunkGenerationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chunk_generation_duration_seconds",
+		Help:      "Terrain generation time by chunk type (global or local).",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"type"})
+
+	DBTransactionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Database transaction latency from begin to commit or rollback.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"result"})
+
+	DBRollbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_rollbacks_total",
+		Help:      "Number of rolled back database transactions.",
+	})
+
+	EventsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Number of events published to the clients.",
+	})
+
+	EventPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_publish_failures_total",
+		Help:      "Number of events which failed to be published by failure reason.",
+	}, []string{"reason"})
+)
+
+// Handler - returns HTTP handler exposing all registered metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveSince - observes seconds passed since the start
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
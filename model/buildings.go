@@ -1,15 +1,34 @@
 package model
 
-import rpc "abbysoft/gardarike-online/rpc/generated"
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"sort"
+)
+
+const (
+	MaxBuildingLevel        = 5
+	DemolitionRefundPercent = 50 // Part of the resources spent on all levels returned if the building is demolished
+)
 
 type Building struct {
 	ID              rpc.BuildingType
+	TownBuildingID  int64 // Stable ID of the building placed in a town, 0 until the construction is finished
+	Level           uint64
 	Name            string
 	Cost            Resources
 	Production      Resources
 	Location        Vector2D
 	Rotation        float32
 	PopulationBonus uint64
+	StorageBonus    Resources // Storage capacity added to the town
+	HappinessBonus  uint64    // Happiness of the whole empire
+	Upkeep          uint64    // Gold paid by the town every game loop tick
+	BuildTime       uint64    // Game loop ticks required to construct the building
+	Footprint       Vector2D  // Width and length of the building in the world units before the rotation
+
+	Requires      []rpc.BuildingType // Buildings required in the same town
+	MinPopulation uint64             // Population of the town required
+	MaxPerTown    uint64             // Number of such buildings in a town including the queued ones, 0 if unlimited
 }
 
 // CharacterBuildings - number of buildings of each type
@@ -23,6 +42,8 @@ var (
 			Cost:            Resources{Wood: 30, Food: 10, Stone: 15, Leather: 20},
 			Production:      Resources{Food: 1},
 			PopulationBonus: 5,
+			BuildTime:       6,
+			Footprint:       Vector2D{X: 2, Y: 2},
 		},
 		rpc.BuildingType_QUARRY: {
 			ID:              rpc.BuildingType_QUARRY,
@@ -30,11 +51,234 @@ var (
 			Cost:            Resources{Wood: 100, Food: 50, Stone: 0, Leather: 80},
 			Production:      Resources{Stone: 1},
 			PopulationBonus: 0,
+			Upkeep:          1,
+			BuildTime:       12,
+			Footprint:       Vector2D{X: 4, Y: 4},
+		},
+		rpc.BuildingType_SAWMILL: {
+			ID:            rpc.BuildingType_SAWMILL,
+			Name:          "sawmill",
+			Cost:          Resources{Wood: 80, Food: 30, Stone: 40},
+			Production:    Resources{Wood: 2},
+			Upkeep:        1,
+			BuildTime:     10,
+			Footprint:     Vector2D{X: 3, Y: 4},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_HOUSE},
+			MinPopulation: 20,
+			MaxPerTown:    2,
+		},
+		rpc.BuildingType_FARM: {
+			ID:         rpc.BuildingType_FARM,
+			Name:       "farm",
+			Cost:       Resources{Wood: 50, Food: 20, Stone: 10},
+			Production: Resources{Food: 3},
+			Upkeep:     1,
+			BuildTime:  8,
+			Footprint:  Vector2D{X: 5, Y: 5},
+			Requires:   []rpc.BuildingType{rpc.BuildingType_HOUSE},
+			MaxPerTown: 4,
+		},
+		rpc.BuildingType_HUNTER_LODGE: {
+			ID:         rpc.BuildingType_HUNTER_LODGE,
+			Name:       "hunter lodge",
+			Cost:       Resources{Wood: 40, Food: 20, Leather: 10},
+			Production: Resources{Food: 1, Leather: 1},
+			Upkeep:     1,
+			BuildTime:  8,
+			Footprint:  Vector2D{X: 3, Y: 3},
+			Requires:   []rpc.BuildingType{rpc.BuildingType_HOUSE},
+			MaxPerTown: 2,
+		},
+		rpc.BuildingType_TANNERY: {
+			ID:            rpc.BuildingType_TANNERY,
+			Name:          "tannery",
+			Cost:          Resources{Wood: 60, Food: 30, Stone: 30, Leather: 40},
+			Production:    Resources{Leather: 2},
+			Upkeep:        2,
+			BuildTime:     12,
+			Footprint:     Vector2D{X: 3, Y: 4},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_HUNTER_LODGE},
+			MinPopulation: 30,
+			MaxPerTown:    2,
+		},
+		rpc.BuildingType_WAREHOUSE: {
+			ID:            rpc.BuildingType_WAREHOUSE,
+			Name:          "warehouse",
+			Cost:          Resources{Wood: 120, Food: 20, Stone: 60},
+			StorageBonus:  Resources{Wood: 1000, Food: 1000, Stone: 1000, Leather: 1000},
+			Upkeep:        1,
+			BuildTime:     15,
+			Footprint:     Vector2D{X: 4, Y: 6},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_HOUSE},
+			MinPopulation: 20,
+			MaxPerTown:    3,
+		},
+		rpc.BuildingType_MARKET: {
+			ID:            rpc.BuildingType_MARKET,
+			Name:          "market",
+			Cost:          Resources{Wood: 150, Food: 50, Stone: 100, Leather: 50},
+			Upkeep:        3,
+			BuildTime:     20,
+			Footprint:     Vector2D{X: 6, Y: 6},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_WAREHOUSE},
+			MinPopulation: 50,
+			MaxPerTown:    1,
+		},
+		rpc.BuildingType_BARRACKS: {
+			ID:            rpc.BuildingType_BARRACKS,
+			Name:          "barracks",
+			Cost:          Resources{Wood: 150, Food: 100, Stone: 100, Leather: 60},
+			Upkeep:        5,
+			BuildTime:     20,
+			Footprint:     Vector2D{X: 5, Y: 7},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_HOUSE},
+			MinPopulation: 50,
+			MaxPerTown:    1,
+		},
+		rpc.BuildingType_WALL: {
+			ID:            rpc.BuildingType_WALL,
+			Name:          "wall",
+			Cost:          Resources{Wood: 100, Stone: 400},
+			Upkeep:        2,
+			BuildTime:     30,
+			Footprint:     Vector2D{X: 10, Y: 1},
+			Requires:      []rpc.BuildingType{rpc.BuildingType_BARRACKS},
+			MinPopulation: 80,
+			MaxPerTown:    1,
+		},
+		rpc.BuildingType_TEMPLE: {
+			ID:             rpc.BuildingType_TEMPLE,
+			Name:           "temple",
+			Cost:           Resources{Wood: 200, Food: 100, Stone: 300, Leather: 50},
+			HappinessBonus: 5,
+			Upkeep:         4,
+			BuildTime:      30,
+			Footprint:      Vector2D{X: 6, Y: 8},
+			Requires:       []rpc.BuildingType{rpc.BuildingType_MARKET},
+			MinPopulation:  100,
+			MaxPerTown:     1,
 		},
 	}
 )
 
+// AtLevel - returns the building with the catalog values of the type multiplied by the level.
+// The cost and the build time are the ones of upgrading to the level
+func (b Building) AtLevel(level uint64) Building {
+	base := Buildings[b.ID]
+
+	b.Level = level
+	b.Cost = base.Cost.Multiply(level)
+	b.Production = base.Production.Multiply(level)
+	b.PopulationBonus = base.PopulationBonus * level
+	b.StorageBonus = base.StorageBonus.Multiply(level)
+	b.HappinessBonus = base.HappinessBonus * level
+	b.Upkeep = base.Upkeep * level
+	b.BuildTime = base.BuildTime * level
+
+	return b
+}
+
+// DemolitionRefund - returns resources given back if the building is demolished
+func (b Building) DemolitionRefund() Resources {
+	spent := Buildings[b.ID].Cost.Multiply(b.Level * (b.Level + 1) / 2)
+	return spent.Share(DemolitionRefundPercent, 100)
+}
+
+func (b Building) ToRPC() *rpc.TownBuilding {
+	return &rpc.TownBuilding{
+		BuildingID: b.ID,
+		Location:   b.Location.ToRPC(),
+		Rotation:   b.Rotation,
+		Id:         b.TownBuildingID,
+		Level:      b.Level,
+	}
+}
+
 func IsValidBuildingType(typeValue int32) bool {
 	_, found := rpc.BuildingType_name[typeValue]
 	return found
 }
+
+// BuildingTypes - returns types of all buildings of the catalog sorted by ID
+func BuildingTypes() (result []rpc.BuildingType) {
+	for id := range Buildings {
+		result = append(result, id)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return
+}
+
+// ToInfoRPC - returns the catalog entry of the building
+func (b Building) ToInfoRPC() *rpc.BuildingInfo {
+	return &rpc.BuildingInfo{
+		BuildingID:        b.ID,
+		Name:              b.Name,
+		Cost:              b.Cost.ToRPC(),
+		Production:        b.Production.ToRPC(),
+		PopulationBonus:   b.PopulationBonus,
+		StorageBonus:      b.StorageBonus.ToRPC(),
+		HappinessBonus:    b.HappinessBonus,
+		BuildTime:         b.BuildTime,
+		Footprint:         b.Footprint.ToRPC(),
+		RequiredBuildings: b.Requires,
+		MinPopulation:     b.MinPopulation,
+		MaxPerTown:        b.MaxPerTown,
+		Upkeep:            b.Upkeep,
+	}
+}
+
+// BuildingCount - returns number of the buildings of the type in the town including the queued ones
+func (t Town) BuildingCount(id rpc.BuildingType) (result uint64) {
+	for _, building := range t.Buildings {
+		if building.ID == id {
+			result++
+		}
+	}
+
+	for _, construction := range t.Construction {
+		if !construction.IsUpgrade() && construction.Building.ID == id {
+			result++
+		}
+	}
+
+	return
+}
+
+// HasBuilding - returns true if a finished building of the type is placed in the town
+func (t Town) HasBuilding(id rpc.BuildingType) bool {
+	for _, building := range t.Buildings {
+		if building.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckRequirements - returns an error if the building can't be placed in the town
+// because of the owner technologies, its prerequisites or the per town limit
+func (t Town) CheckRequirements(building Building) Error {
+	if !t.Technologies.IsBuildingUnlocked(building.ID) {
+		return ErrTechnologyRequired
+	}
+
+	if t.Population < building.MinPopulation {
+		return ErrPrerequisitesNotMet
+	}
+
+	for _, required := range building.Requires {
+		if !t.HasBuilding(required) {
+			return ErrPrerequisitesNotMet
+		}
+	}
+
+	if building.MaxPerTown != 0 && t.BuildingCount(building.ID) >= building.MaxPerTown {
+		return ErrBuildingLimitReached
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"math"
+)
+
+const (
+	MaxCaravans         = 10  // Caravans of a single character on the way
+	CaravanSpeed        = 2.0 // World units per game loop tick on the flat land
+	CaravanSlopePenalty = 20.0
+	CaravanWaterPenalty = 3.0 // Crossing the water is this times slower
+)
+
+// Caravan - resources on the way from the town of the owner to another town,
+// the resources are stored to the destination town on arrival
+type Caravan struct {
+	ID            int64
+	CharacterID   int64  `db:"character_id"`
+	Owner         string // Name of the character
+	ToCharacterID int64  `db:"to_character_id"` // Owner of the destination town
+	FromTownID    int64  `db:"from_town_id"`
+	ToTownID      int64  `db:"to_town_id"`
+	From          Vector2D
+	To            Vector2D
+	Resources     Resources `db:"resources"`
+	TravelTime    uint64    `db:"travel_time"`
+	TicksLeft     uint64    `db:"ticks_left"`
+}
+
+// HeightFunc - returns the terrain height at the world position or false if the terrain is unknown
+type HeightFunc func(x, y int) (float32, bool)
+
+// CaravanTravelTime - returns ticks the caravan needs to go straight from one point to another.
+// Every unit of the way costs more on the slopes and under the water, the unknown terrain is flat
+func CaravanTravelTime(from, to Vector2D, height HeightFunc, waterLevel float32) uint64 {
+	dx := float64(to.X - from.X)
+	dy := float64(to.Y - from.Y)
+	distance := math.Hypot(dx, dy)
+	steps := int(math.Ceil(distance))
+	length := distance / float64(steps)
+
+	heightAt := func(step int) (float32, bool) {
+		ratio := float64(step) / float64(steps)
+		return height(int(float64(from.X)+dx*ratio), int(float64(from.Y)+dy*ratio))
+	}
+
+	cost := 0.0
+	previous, known := heightAt(0)
+
+	for step := 1; step <= steps; step++ {
+		current, currentKnown := heightAt(step)
+
+		stepCost := length
+		if known && currentKnown {
+			stepCost += math.Abs(float64(current-previous)) * CaravanSlopePenalty
+		}
+		if currentKnown && current < waterLevel {
+			stepCost *= CaravanWaterPenalty
+		}
+
+		cost += stepCost
+		previous, known = current, currentKnown
+	}
+
+	return uint64(math.Max(1, math.Ceil(cost/CaravanSpeed)))
+}
+
+// Location - returns the current position of the caravan on the straight way between the towns
+func (c Caravan) Location() Vector2D {
+	if c.TravelTime == 0 {
+		return c.To
+	}
+
+	progress := float32(c.TravelTime-c.TicksLeft) / float32(c.TravelTime)
+	return Vector2D{
+		X: c.From.X + (c.To.X-c.From.X)*progress,
+		Y: c.From.Y + (c.To.Y-c.From.Y)*progress,
+	}
+}
+
+// Advance - moves the caravan by one game loop tick, returns true if the caravan has arrived
+func (c *Caravan) Advance() bool {
+	if c.TicksLeft > 0 {
+		c.TicksLeft--
+	}
+
+	return c.TicksLeft == 0
+}
+
+func (c Caravan) ToRPC() *rpc.Caravan {
+	return &rpc.Caravan{
+		Id:         c.ID,
+		Owner:      c.Owner,
+		FromTownID: c.FromTownID,
+		ToTownID:   c.ToTownID,
+		From:       c.From.ToRPC(),
+		To:         c.To.ToRPC(),
+		Location:   c.Location().ToRPC(),
+		Resources:  c.Resources.ToRPC(),
+		TicksLeft:  c.TicksLeft,
+		TravelTime: c.TravelTime,
+	}
+}
+
+// Location - returns the position of the town in the world
+func (t Town) Location() Vector2D {
+	return Vector2D{X: float32(t.X), Y: float32(t.Y)}
+}
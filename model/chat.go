@@ -0,0 +1,53 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+// ChatMembership - the chat channel the character is a member of
+type ChatMembership struct {
+	CharacterID int64 `db:"character_id"`
+	Channel     rpc.ChatMessage_Channel
+	Region      string // Region of the region channel
+}
+
+// ChatFilter - selects the messages of the channel history
+type ChatFilter struct {
+	Channel    rpc.ChatMessage_Channel
+	Region     string
+	Senders    []string // Any sender if empty
+	Recipients []string // Any recipient if empty
+	BeforeID   int64    // Only the messages older than this one if set
+	AfterID    int64    // Only the messages newer than this one if set
+	AudienceID int64    // Only the messages delivered to this character if set
+}
+
+func IsValidChatChannel(channelValue int32) bool {
+	_, found := rpc.ChatMessage_Channel_name[channelValue]
+	return found
+}
+
+// IsJoinableChatChannel - checks if the channel membership is managed by the player,
+// the alliance channel depends on the diplomacy and the direct messages have no channel
+func IsJoinableChatChannel(channel rpc.ChatMessage_Channel) bool {
+	return channel == rpc.ChatMessage_GLOBAL || channel == rpc.ChatMessage_REGION || channel == rpc.ChatMessage_TRADE
+}
+
+// FindChatMembership - returns the membership of the channel, false if the character isn't a member
+func FindChatMembership(memberships []ChatMembership, channel rpc.ChatMessage_Channel) (ChatMembership, bool) {
+	for _, membership := range memberships {
+		if membership.Channel == channel {
+			return membership, true
+		}
+	}
+
+	return ChatMembership{}, false
+}
+
+func (m ChatMembership) ToRPC() *rpc.ChatChannelMembership {
+	return &rpc.ChatChannelMembership{
+		Channel: m.Channel,
+		Region:  m.Region,
+		Topic:   ChatChannelTopic(m.Channel, m.Region),
+	}
+}
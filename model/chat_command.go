@@ -0,0 +1,123 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ChatCommandPrefix - chat messages starting with the prefix are commands, the doubled prefix escapes it
+const ChatCommandPrefix = "/"
+
+// Role - permissions of the character, every role has the permissions of the lower ones
+type Role int
+
+const (
+	RolePlayer Role = iota
+	RoleModerator
+	RoleAdmin
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+func (c Character) Role() Role {
+	if c.IsAdmin {
+		return RoleAdmin
+	} else if c.IsModerator {
+		return RoleModerator
+	}
+
+	return RolePlayer
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleModerator:
+		return "moderator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "player"
+	}
+}
+
+// ChatCommand - parsed command line like /whisper "Ivan the Great" hello
+type ChatCommand struct {
+	Name    string // Lower case, without the prefix
+	Args    []string
+	text    string
+	offsets []int // Offsets of the arguments in the text
+}
+
+// IsChatCommand - checks if the message is a command, the escaped prefix is a plain message
+func IsChatCommand(text string) bool {
+	return strings.HasPrefix(text, ChatCommandPrefix) && !strings.HasPrefix(text, ChatCommandPrefix+ChatCommandPrefix)
+}
+
+// UnescapeChatCommand - removes the escaping prefix of the plain message starting with the command prefix
+func UnescapeChatCommand(text string) string {
+	if strings.HasPrefix(text, ChatCommandPrefix+ChatCommandPrefix) {
+		return strings.TrimPrefix(text, ChatCommandPrefix)
+	}
+
+	return text
+}
+
+// ParseChatCommand - splits the command line by spaces, the quoted arguments may contain spaces
+func ParseChatCommand(text string) (ChatCommand, error) {
+	var tokens []string
+	var offsets []int
+	var token strings.Builder
+	inToken, quoted := false, false
+
+	for i, r := range text {
+		switch {
+		case r == '"':
+			if !inToken {
+				inToken = true
+				offsets = append(offsets, i)
+			}
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			if !inToken {
+				inToken = true
+				offsets = append(offsets, i)
+			}
+			token.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return ChatCommand{}, ErrUnterminatedQuote
+	}
+
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+
+	if len(tokens) == 0 {
+		return ChatCommand{text: text}, nil
+	}
+
+	return ChatCommand{
+		Name:    strings.ToLower(strings.TrimPrefix(tokens[0], ChatCommandPrefix)),
+		Args:    tokens[1:],
+		text:    text,
+		offsets: offsets[1:],
+	}, nil
+}
+
+// Tail - returns the text of the command line starting with the argument as it was typed
+func (c ChatCommand) Tail(argIndex int) string {
+	if argIndex >= len(c.offsets) {
+		return ""
+	}
+
+	return strings.TrimSpace(c.text[c.offsets[argIndex]:])
+}
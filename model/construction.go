@@ -0,0 +1,143 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+const (
+	MaxConstructionQueue      = 10 // Buildings queued in a single town including the ones under construction
+	ConstructionRefundPercent = 50 // Part of the cost returned if the construction is cancelled after its start
+)
+
+// Construction - building waiting in the town queue or being constructed
+type Construction struct {
+	ID        int64
+	TownID    int64
+	Building  Building
+	TicksLeft uint64 // Game loop ticks left to finish the construction
+}
+
+// IsUpgrade - returns true if the construction upgrades a building placed in the town
+func (c Construction) IsUpgrade() bool {
+	return c.Building.TownBuildingID != 0
+}
+
+// IsStarted - returns true if the construction has progressed at least one tick
+func (c Construction) IsStarted() bool {
+	return c.TicksLeft < c.Building.BuildTime
+}
+
+// Refund - returns resources given back if the construction is cancelled
+func (c Construction) Refund() Resources {
+	if !c.IsStarted() {
+		return c.Building.Cost
+	}
+
+	return c.Building.Cost.Share(ConstructionRefundPercent, 100)
+}
+
+func (c Construction) ToRPC(inProgress bool) *rpc.Construction {
+	return &rpc.Construction{
+		Id:         c.ID,
+		Building:   c.Building.ToRPC(),
+		TicksLeft:  c.TicksLeft,
+		BuildTime:  c.Building.BuildTime,
+		InProgress: inProgress,
+	}
+}
+
+// NewConstruction - returns the construction of the building in the town, the construction takes the building time
+func NewConstruction(townID int64, building Building) Construction {
+	return Construction{
+		TownID:    townID,
+		Building:  building,
+		TicksLeft: building.BuildTime,
+	}
+}
+
+// ConstructionToRPC - returns the construction queue, the first 'slots' entries are in progress
+func (t Town) ConstructionToRPC(slots int) []*rpc.Construction {
+	result := make([]*rpc.Construction, 0, len(t.Construction))
+	for i, construction := range t.Construction {
+		result = append(result, construction.ToRPC(i < slots))
+	}
+
+	return result
+}
+
+// FindConstruction - returns index of the construction in the queue or -1 if it isn't found
+func (t Town) FindConstruction(id int64) int {
+	for i, construction := range t.Construction {
+		if construction.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// IsConstructionQueueFull - returns true if no more buildings can be queued
+func (t Town) IsConstructionQueueFull() bool {
+	return len(t.Construction) >= MaxConstructionQueue
+}
+
+// AdvanceConstruction - progresses the first 'slots' constructions by one tick,
+// returns the finished constructions removed from the queue
+func (t *Town) AdvanceConstruction(slots int) (completed []Construction) {
+	queue := make([]Construction, 0, len(t.Construction))
+	for i, construction := range t.Construction {
+		if i < slots && construction.TicksLeft > 0 {
+			construction.TicksLeft--
+		}
+
+		if i < slots && construction.TicksLeft == 0 {
+			completed = append(completed, construction)
+			continue
+		}
+
+		queue = append(queue, construction)
+	}
+
+	t.Construction = queue
+	return
+}
+
+// CompleteConstruction - replaces the upgraded building keeping its location or adds the new one to the town,
+// returns the building placed in the town
+func (t *Town) CompleteConstruction(construction Construction) Building {
+	building := construction.Building
+
+	index := t.FindBuilding(building.TownBuildingID)
+	if index < 0 {
+		t.Buildings = append(t.Buildings, building)
+		return building
+	}
+
+	building.Location = t.Buildings[index].Location
+	building.Rotation = t.Buildings[index].Rotation
+	t.Buildings[index] = building
+
+	return building
+}
+
+// FindBuilding - returns index of the town building or -1 if it isn't found
+func (t Town) FindBuilding(id int64) int {
+	for i, building := range t.Buildings {
+		if building.TownBuildingID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// IsUnderConstruction - returns true if an upgrade of the building is queued
+func (t Town) IsUnderConstruction(buildingID int64) bool {
+	for _, construction := range t.Construction {
+		if construction.IsUpgrade() && construction.Building.TownBuildingID == buildingID {
+			return true
+		}
+	}
+
+	return false
+}
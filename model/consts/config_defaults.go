@@ -5,4 +5,5 @@ const (
 	DefaultWaterLevel          = 0.1
 	DefaultAlwaysRegenerateMap = false
 	DefaultDebugTerrain        = false
+	DefaultConstructionSlots   = 2
 )
@@ -1,6 +1,9 @@
 package consts
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
 	// Messages
@@ -8,4 +11,14 @@ var (
 	MessageCharacterAuthorized = func(name string) string {
 		return fmt.Sprintf("\"%s\" enters the world!", name)
 	}
+
+	MessageCharacterMuted = func(until time.Time) string {
+		return fmt.Sprintf("You are muted until %s", until.UTC().Format(time.RFC1123))
+	}
+
+	MessageCharacterUnmuted = "You are no longer muted"
+
+	MessageCharacterBanned = func(until time.Time) string {
+		return fmt.Sprintf("You are banned until %s", until.UTC().Format(time.RFC1123))
+	}
 )
@@ -0,0 +1,111 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"time"
+)
+
+// Market fee percents taken from the seller depending on the relations of the trading empires,
+// the empires at war don't trade with each other
+const (
+	MarketFeePercent         = 5
+	PactMarketFeePercent     = 2
+	AllianceMarketFeePercent = 0
+)
+
+// DiplomaticRelation - non-neutral relations of the character with another empire
+type DiplomaticRelation struct {
+	CharacterID int64  `db:"character_id"`
+	OtherID     int64  `db:"other_id"`
+	Other       string // Name of the other character
+	State       rpc.DiplomaticState
+}
+
+// DiplomacyProposal - the proposal of the diplomatic state waiting for the acceptance of the other empire
+type DiplomacyProposal struct {
+	ID     int64
+	FromID int64  `db:"from_id"`
+	From   string // Name of the proposing character
+	ToID   int64  `db:"to_id"`
+	To     string // Name of the character the proposal is sent to
+	State  rpc.DiplomaticState
+	Time   time.Time `db:"created_at"`
+}
+
+func IsValidDiplomaticState(stateValue int32) bool {
+	_, found := rpc.DiplomaticState_name[stateValue]
+	return found
+}
+
+// IsTreaty - checks if the state is an agreement of both empires
+func IsTreaty(state rpc.DiplomaticState) bool {
+	return state == rpc.DiplomaticState_ALLIANCE || state == rpc.DiplomaticState_NON_AGGRESSION_PACT
+}
+
+// NeedsAcceptance - checks if the change of the state must be accepted by the other empire.
+// War is declared and treaties are broken at once, the peace and new treaties are agreed
+func NeedsAcceptance(current, proposed rpc.DiplomaticState) bool {
+	if proposed == rpc.DiplomaticState_WAR {
+		return false
+	}
+
+	return !(proposed == rpc.DiplomaticState_NEUTRAL && IsTreaty(current))
+}
+
+// CanFight - checks if the empires with the state fight each other
+func CanFight(state rpc.DiplomaticState) bool {
+	return state == rpc.DiplomaticState_WAR
+}
+
+// MarketFee - returns the fee percent of the trade between the empires with the state,
+// false if they don't trade
+func MarketFee(state rpc.DiplomaticState) (uint64, bool) {
+	switch state {
+	case rpc.DiplomaticState_WAR:
+		return 0, false
+	case rpc.DiplomaticState_ALLIANCE:
+		return AllianceMarketFeePercent, true
+	case rpc.DiplomaticState_NON_AGGRESSION_PACT:
+		return PactMarketFeePercent, true
+	default:
+		return MarketFeePercent, true
+	}
+}
+
+// RelationStates - returns states of the relations by IDs of the other empires
+func RelationStates(relations []DiplomaticRelation) map[int64]rpc.DiplomaticState {
+	result := make(map[int64]rpc.DiplomaticState, len(relations))
+	for _, relation := range relations {
+		result[relation.OtherID] = relation.State
+	}
+
+	return result
+}
+
+// Allies - returns the relations with the allied empires
+func Allies(relations []DiplomaticRelation) (result []DiplomaticRelation) {
+	for _, relation := range relations {
+		if relation.State == rpc.DiplomaticState_ALLIANCE {
+			result = append(result, relation)
+		}
+	}
+
+	return
+}
+
+func (r DiplomaticRelation) ToRPC() *rpc.DiplomaticRelation {
+	return &rpc.DiplomaticRelation{
+		EmpireName: r.Other,
+		State:      r.State,
+	}
+}
+
+func (p DiplomacyProposal) ToRPC() *rpc.DiplomacyProposal {
+	return &rpc.DiplomacyProposal{
+		Id:    p.ID,
+		From:  p.From,
+		To:    p.To,
+		State: p.State,
+		Time:  p.Time.Unix(),
+	}
+}
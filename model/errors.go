@@ -46,3 +46,37 @@ var ErrUsernameIsTaken = NewError("username is already registered", rpc.Error_US
 var ErrForbidden = NewError("action is forbidden", rpc.Error_FORBIDDEN)
 var ErrNotEnoughResources = NewError("not enough resources", rpc.Error_NOT_ENOUGH_RESOURCES)
 var ErrTownNotFound = NewError("town not found", rpc.Error_TOWN_NOT_FOUND)
+var ErrNotEnoughWorkers = NewError("not enough idle population", rpc.Error_NOT_ENOUGH_WORKERS)
+var ErrJobLocked = NewError("job isn't unlocked", rpc.Error_JOB_LOCKED)
+var ErrConstructionNotFound = NewError("construction not found", rpc.Error_CONSTRUCTION_NOT_FOUND)
+var ErrConstructionQueueFull = NewError("construction queue is full", rpc.Error_CONSTRUCTION_QUEUE_FULL)
+var ErrBuildingNotFound = NewError("building not found", rpc.Error_BUILDING_NOT_FOUND)
+var ErrMaxLevelReached = NewError("building has the maximum level", rpc.Error_MAX_LEVEL_REACHED)
+var ErrBuildingUnderConstruction = NewError("building is under construction", rpc.Error_BUILDING_UNDER_CONSTRUCTION)
+var ErrOutsideTown = NewError("location is outside the town build radius", rpc.Error_OUTSIDE_TOWN)
+var ErrLocationUnderWater = NewError("location is under water", rpc.Error_LOCATION_UNDER_WATER)
+var ErrLocationTooSteep = NewError("terrain is too steep", rpc.Error_LOCATION_TOO_STEEP)
+var ErrLocationOccupied = NewError("location is occupied by another building", rpc.Error_LOCATION_OCCUPIED)
+var ErrPrerequisitesNotMet = NewError("town doesn't meet the building requirements", rpc.Error_PREREQUISITES_NOT_MET)
+var ErrBuildingLimitReached = NewError("town has the maximum number of such buildings", rpc.Error_BUILDING_LIMIT_REACHED)
+var ErrTechnologyNotFound = NewError("technology not found", rpc.Error_TECHNOLOGY_NOT_FOUND)
+var ErrTechnologyAlreadyResearched = NewError("technology is already researched", rpc.Error_TECHNOLOGY_ALREADY_RESEARCHED)
+var ErrResearchInProgress = NewError("another technology is being researched", rpc.Error_RESEARCH_IN_PROGRESS)
+var ErrTechnologyRequired = NewError("required technology isn't researched", rpc.Error_TECHNOLOGY_REQUIRED)
+var ErrInvalidTaxRate = NewError("tax rate is out of range", rpc.Error_INVALID_TAX_RATE)
+var ErrOrderNotFound = NewError("market order not found", rpc.Error_ORDER_NOT_FOUND)
+var ErrOrderLimitReached = NewError("too many open market orders", rpc.Error_ORDER_LIMIT_REACHED)
+var ErrCaravanLimitReached = NewError("too many caravans on the way", rpc.Error_CARAVAN_LIMIT_REACHED)
+var ErrArmyNotFound = NewError("army not found", rpc.Error_ARMY_NOT_FOUND)
+var ErrNotEnoughUnits = NewError("not enough units", rpc.Error_NOT_ENOUGH_UNITS)
+var ErrArmyLimitReached = NewError("too many armies", rpc.Error_ARMY_LIMIT_REACHED)
+var ErrTrainingQueueFull = NewError("training queue is full", rpc.Error_TRAINING_QUEUE_FULL)
+var ErrTownProtected = NewError("town of a new player can't be attacked", rpc.Error_TOWN_PROTECTED)
+var ErrTownTooClose = NewError("town is too close to another town", rpc.Error_TOWN_TOO_CLOSE)
+var ErrForeignTerritory = NewError("location is inside the territory of another empire", rpc.Error_FOREIGN_TERRITORY)
+var ErrNotAtWar = NewError("empires are not at war", rpc.Error_NOT_AT_WAR)
+var ErrProposalNotFound = NewError("diplomacy proposal not found", rpc.Error_PROPOSAL_NOT_FOUND)
+var ErrNotChannelMember = NewError("not a member of the chat channel", rpc.Error_NOT_CHANNEL_MEMBER)
+var ErrCharacterMuted = NewError("character is muted", rpc.Error_CHARACTER_MUTED)
+var ErrCharacterBanned = NewError("character is banned", rpc.Error_CHARACTER_BANNED)
+var ErrMessageNotFound = NewError("chat message not found", rpc.Error_MESSAGE_NOT_FOUND)
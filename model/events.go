@@ -3,8 +3,25 @@ package model
 import (
 	"abbysoft/gardarike-online/model/consts"
 	rpc "abbysoft/gardarike-online/rpc/generated"
+	"fmt"
 )
 
+// CharacterTopic - returns topic of the events sent to the character only. The topic ends with a separator,
+// so the subscription of one character doesn't match the characters with longer IDs
+func CharacterTopic(characterID int64) string {
+	return fmt.Sprintf("CHARACTER.%d.", characterID)
+}
+
+// ChatChannelTopic - returns topic of the messages of the joinable chat channel, every region has its own topic.
+// The topic ends with a separator like the character topic
+func ChatChannelTopic(channel rpc.ChatMessage_Channel, region string) string {
+	if channel == rpc.ChatMessage_REGION {
+		return fmt.Sprintf("CHAT.%s.%s.", channel, region)
+	}
+
+	return fmt.Sprintf("CHAT.%s.", channel)
+}
+
 func NewChatMessageEvent(message ChatMessage) EventWrapper {
 	return EventWrapper{
 		Event: &rpc.Event{
@@ -26,3 +43,131 @@ func NewSystemChatMessageEvent(text string) EventWrapper {
 		IsSystem: true,
 	})
 }
+
+// NewPrivateSystemChatMessageEvent - system message sent to the character only
+func NewPrivateSystemChatMessageEvent(characterID int64, text string) EventWrapper {
+	event := NewSystemChatMessageEvent(text)
+	event.Topic = CharacterTopic(characterID)
+	return event
+}
+
+func NewBuildingCompletedEvent(characterID int64, construction Construction) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_BuildingCompletedEvent{
+				BuildingCompletedEvent: &rpc.BuildingCompletedEvent{
+					TownID:         construction.TownID,
+					ConstructionID: construction.ID,
+					Building:       construction.Building.ToRPC(),
+				},
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
+
+func NewResearchCompletedEvent(characterID int64, technology Technology) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_ResearchCompletedEvent{
+				ResearchCompletedEvent: &rpc.ResearchCompletedEvent{
+					TechnologyID: technology.ID,
+				},
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
+
+func NewMarketOrderFilledEvent(characterID int64, order MarketOrder, trade Trade) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_MarketOrderFilledEvent{
+				MarketOrderFilledEvent: &rpc.MarketOrderFilledEvent{
+					OrderID:    order.ID,
+					Trade:      trade.ToRPC(),
+					AmountLeft: order.Amount,
+				},
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
+
+func NewCaravanArrivedEvent(characterID int64, caravan Caravan) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_CaravanArrivedEvent{
+				CaravanArrivedEvent: &rpc.CaravanArrivedEvent{
+					Caravan: caravan.ToRPC(),
+				},
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
+
+// NewBattleEvent - returns the event about the battle for the owner of the army
+func NewBattleEvent(location Vector2D, army, enemy Army, losses, enemyLosses Units, won bool) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_BattleEvent{
+				BattleEvent: &rpc.BattleEvent{
+					Location:    location.ToRPC(),
+					ArmyID:      army.ID,
+					EnemyArmyID: enemy.ID,
+					Enemy:       enemy.Owner,
+					Losses:      losses.ToRPC(),
+					EnemyLosses: enemyLosses.ToRPC(),
+					Won:         won,
+				},
+			},
+		},
+		Topic: CharacterTopic(army.CharacterID),
+	}
+}
+
+// NewSiegeEvent - returns the event about the siege of the town for one of the sides
+// NewDiplomacyEvent - returns the event of the relations with the empire sent to the character,
+// the proposal is set for the proposed and rejected states
+func NewDiplomacyEvent(characterID int64, eventType rpc.DiplomacyEvent_Type, empireName string,
+	state rpc.DiplomaticState, proposal *DiplomacyProposal) EventWrapper {
+	event := &rpc.DiplomacyEvent{
+		Type:       eventType,
+		EmpireName: empireName,
+		State:      state,
+	}
+
+	if proposal != nil {
+		event.Proposal = proposal.ToRPC()
+	}
+
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_DiplomacyEvent{
+				DiplomacyEvent: event,
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
+
+func NewSiegeEvent(characterID int64, state rpc.SiegeEvent_State, town Town, army Army, battle Battle) EventWrapper {
+	return EventWrapper{
+		Event: &rpc.Event{
+			Payload: &rpc.Event_SiegeEvent{
+				SiegeEvent: &rpc.SiegeEvent{
+					State:          state,
+					TownID:         town.ID,
+					TownName:       town.Name,
+					ArmyID:         army.ID,
+					Attacker:       army.Owner,
+					Defender:       town.OwnerName,
+					AttackerLosses: battle.AttackerLosses.ToRPC(),
+					DefenderLosses: battle.DefenderLosses.ToRPC(),
+				},
+			},
+		},
+		Topic: CharacterTopic(characterID),
+	}
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"sort"
+)
+
+type Job struct {
+	ID         rpc.JobType
+	Name       string
+	Production Resources          // Resources produced by a single worker every game loop tick
+	Harvest    ChunkResources     // Resources taken by a single worker from the chunks around the towns every tick
+	UnlockedBy []rpc.BuildingType // Any of these buildings unlocks the job, the job is always available if empty
+}
+
+// WorkDistribution - number of workers assigned to each job
+type WorkDistribution map[rpc.JobType]uint64
+
+var (
+	Jobs = map[rpc.JobType]Job{
+		rpc.JobType_WOODCUTTER: {
+			ID:         rpc.JobType_WOODCUTTER,
+			Name:       "woodcutter",
+			Production: Resources{Wood: 1},
+			Harvest:    ChunkResources{Trees: 1},
+		},
+		rpc.JobType_MINER: {
+			ID:         rpc.JobType_MINER,
+			Name:       "miner",
+			Production: Resources{Stone: 1},
+			Harvest:    ChunkResources{Stones: 1},
+			UnlockedBy: []rpc.BuildingType{rpc.BuildingType_QUARRY},
+		},
+		rpc.JobType_HUNTER: {
+			ID:         rpc.JobType_HUNTER,
+			Name:       "hunter",
+			Production: Resources{Food: 1, Leather: 1},
+			Harvest:    ChunkResources{Animals: 1},
+			UnlockedBy: []rpc.BuildingType{rpc.BuildingType_HOUSE, rpc.BuildingType_HUNTER_LODGE},
+		},
+		rpc.JobType_FARMER: {
+			ID:         rpc.JobType_FARMER,
+			Name:       "farmer",
+			Production: Resources{Food: 2},
+			Harvest:    ChunkResources{Plants: 1},
+			UnlockedBy: []rpc.BuildingType{rpc.BuildingType_HOUSE, rpc.BuildingType_FARM},
+		},
+		rpc.JobType_BUILDER: {
+			ID:   rpc.JobType_BUILDER,
+			Name: "builder",
+		},
+	}
+)
+
+// IsUnlocked - checks if the character has any of the buildings unlocking the job
+func (j Job) IsUnlocked(buildings CharacterBuildings) bool {
+	if len(j.UnlockedBy) == 0 {
+		return true
+	}
+
+	for _, building := range j.UnlockedBy {
+		if buildings[building] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnlockedJobs - returns jobs available with the buildings sorted by ID
+func UnlockedJobs(buildings CharacterBuildings) (result []rpc.JobType) {
+	for id, job := range Jobs {
+		if job.IsUnlocked(buildings) {
+			result = append(result, id)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return
+}
+
+// Assigned - returns the number of workers assigned to any job
+func (w WorkDistribution) Assigned() (result uint64) {
+	for _, workers := range w {
+		result += workers
+	}
+
+	return
+}
+
+// Idle - returns the number of workers without a job
+func (w WorkDistribution) Idle(population uint64) uint64 {
+	assigned := w.Assigned()
+	if assigned > population {
+		return 0
+	}
+
+	return population - assigned
+}
+
+// Harvest - returns resources the workers need to take from the chunks every tick
+func (w WorkDistribution) Harvest() (result ChunkResources) {
+	for id, workers := range w {
+		result = result.Add(Jobs[id].Harvest.Multiply(workers))
+	}
+
+	return
+}
+
+// HarvestedProduction - returns resources produced by the workers if only 'taken' part of the Harvest was available.
+// Production of every job is reduced in proportion to the shortage of the resources it needs
+// and multiplied by the workers efficiency
+func (w WorkDistribution) HarvestedProduction(taken ChunkResources, efficiency float64) (result Resources) {
+	demand := w.Harvest()
+
+	for id, workers := range w {
+		job := Jobs[id]
+		ratio := job.Harvest.SatisfiedRatio(taken, demand) * efficiency
+
+		result.Wood += uint64(float64(job.Production.Wood*workers) * ratio)
+		result.Food += uint64(float64(job.Production.Food*workers) * ratio)
+		result.Stone += uint64(float64(job.Production.Stone*workers) * ratio)
+		result.Leather += uint64(float64(job.Production.Leather*workers) * ratio)
+	}
+
+	return
+}
+
+// ToRPC - returns workers of every job sorted by job ID
+func (w WorkDistribution) ToRPC() []*rpc.JobAssignment {
+	result := make([]*rpc.JobAssignment, 0, len(Jobs))
+	for id := range Jobs {
+		result = append(result, &rpc.JobAssignment{Job: id, Workers: w[id]})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Job < result[j].Job
+	})
+
+	return result
+}
+
+func IsValidJobType(typeValue int32) bool {
+	_, found := Jobs[rpc.JobType(typeValue)]
+	return found
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"time"
+)
+
+const (
+	MaxMarketOrders  = 20        // Open orders of a single character
+	MaxOrderPrice    = 1_000_000 // Gold per unit
+	MaxOrderAmount   = 1_000_000
+	MaxMatchedOrders = 100 // Orders of the other side a new order is matched with at once
+)
+
+// MarketOrder - order of the global market, the escrow of the amount left is held by the market
+type MarketOrder struct {
+	ID          int64
+	CharacterID int64  `db:"character_id"`
+	Owner       string // Name of the character
+	TownID      int64  `db:"town_id"` // Town the escrow is taken from and the goods are delivered to
+	Resource    rpc.ResourceType
+	Side        rpc.OrderSide
+	Price       uint64 // Gold per unit
+	Amount      uint64 // Amount left to fill
+}
+
+// Trade - filled part of a buy and a sell order
+type Trade struct {
+	ID          int64
+	Resource    rpc.ResourceType
+	Price       uint64
+	Amount      uint64
+	BuyOrderID  int64     `db:"buy_order_id"`
+	SellOrderID int64     `db:"sell_order_id"`
+	BuyerID     int64     `db:"buyer_id"`
+	SellerID    int64     `db:"seller_id"`
+	Buyer       string    // Name of the buyer
+	Seller      string    // Name of the seller
+	Time        time.Time `db:"created_at"`
+	Fee         uint64    // Gold taken from the seller
+}
+
+func IsValidResourceType(typeValue int32) bool {
+	_, found := rpc.ResourceType_name[typeValue]
+	return found
+}
+
+func IsValidOrderSide(sideValue int32) bool {
+	_, found := rpc.OrderSide_name[sideValue]
+	return found
+}
+
+// ResourceAmount - returns the amount of the resource
+func ResourceAmount(resource rpc.ResourceType, amount uint64) (result Resources) {
+	switch resource {
+	case rpc.ResourceType_WOOD:
+		result.Wood = amount
+	case rpc.ResourceType_STONE:
+		result.Stone = amount
+	case rpc.ResourceType_FOOD:
+		result.Food = amount
+	case rpc.ResourceType_LEATHER:
+		result.Leather = amount
+	}
+
+	return
+}
+
+// Escrow - returns the resources held by the market for the amount left,
+// the gold for buy orders and the resource for sell orders
+func (o MarketOrder) Escrow() Resources {
+	if o.Side == rpc.OrderSide_BUY {
+		return Resources{Gold: o.Price * o.Amount}
+	}
+
+	return ResourceAmount(o.Resource, o.Amount)
+}
+
+// Proceeds - returns the resources the order owner gets for the trade. The buyer gets the purchased resource
+// and the escrow gold not spent because of the lower trade price, the seller gets the gold without the fee
+func (o MarketOrder) Proceeds(trade Trade) Resources {
+	if o.Side == rpc.OrderSide_BUY {
+		result := ResourceAmount(trade.Resource, trade.Amount)
+		result.Gold = (o.Price - trade.Price) * trade.Amount
+		return result
+	}
+
+	return Resources{Gold: trade.Price*trade.Amount - trade.Fee}
+}
+
+// ChargeFee - sets the fee of the trade as percent of its gold
+func (t *Trade) ChargeFee(percent uint64) {
+	t.Fee = t.Price * t.Amount * percent / 100
+}
+
+// NewTrade - returns the trade of the new order with the order from the order book at the book order price
+func NewTrade(order, bookOrder MarketOrder, amount uint64) Trade {
+	buy, sell := order, bookOrder
+	if order.Side == rpc.OrderSide_SELL {
+		buy, sell = bookOrder, order
+	}
+
+	return Trade{
+		Resource:    order.Resource,
+		Price:       bookOrder.Price,
+		Amount:      amount,
+		BuyOrderID:  buy.ID,
+		SellOrderID: sell.ID,
+		BuyerID:     buy.CharacterID,
+		SellerID:    sell.CharacterID,
+		Buyer:       buy.Owner,
+		Seller:      sell.Owner,
+		Time:        time.Now(),
+	}
+}
+
+// ToRPC - returns the order, the town is set only for the orders of the character
+func (o MarketOrder) ToRPC(characterID int64) *rpc.MarketOrder {
+	result := &rpc.MarketOrder{
+		Id:       o.ID,
+		Owner:    o.Owner,
+		Resource: o.Resource,
+		Side:     o.Side,
+		Price:    o.Price,
+		Amount:   o.Amount,
+	}
+
+	if o.CharacterID == characterID {
+		result.TownID = o.TownID
+	}
+
+	return result
+}
+
+func (t Trade) ToRPC() *rpc.Trade {
+	return &rpc.Trade{
+		Id:       t.ID,
+		Resource: t.Resource,
+		Price:    t.Price,
+		Amount:   t.Amount,
+		Buyer:    t.Buyer,
+		Seller:   t.Seller,
+		Time:     t.Time.Unix(),
+		Fee:      t.Fee,
+	}
+}
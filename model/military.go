@@ -0,0 +1,434 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"math"
+	"sort"
+)
+
+const (
+	MaxArmies        = 10  // Armies of a single character
+	MaxTrainingQueue = 5   // Training orders queued in a single town
+	MaxTrainingCount = 100 // Units trained by a single order
+	ArmyBattleRadius = 5.0 // Armies closer than this fight each other
+	MaxBattleRounds  = 5
+)
+
+type Unit struct {
+	ID           rpc.UnitType
+	Name         string
+	Cost         Resources
+	TrainingTime uint64 // Game loop ticks to train a single unit
+	Attack       uint64 // Damage dealt every battle round
+	Health       uint64
+	Speed        float32 // World units per game loop tick
+}
+
+// UnitTypes - all units available for training
+var UnitTypes = map[rpc.UnitType]Unit{
+	rpc.UnitType_SPEARMAN: {
+		ID:           rpc.UnitType_SPEARMAN,
+		Name:         "spearman",
+		Cost:         Resources{Wood: 10, Food: 20},
+		TrainingTime: 2,
+		Attack:       3,
+		Health:       12,
+		Speed:        1,
+	},
+	rpc.UnitType_ARCHER: {
+		ID:           rpc.UnitType_ARCHER,
+		Name:         "archer",
+		Cost:         Resources{Wood: 20, Food: 15, Leather: 5},
+		TrainingTime: 3,
+		Attack:       5,
+		Health:       7,
+		Speed:        1,
+	},
+	rpc.UnitType_CAVALRY: {
+		ID:           rpc.UnitType_CAVALRY,
+		Name:         "cavalry",
+		Cost:         Resources{Food: 40, Leather: 20, Gold: 10},
+		TrainingTime: 5,
+		Attack:       6,
+		Health:       15,
+		Speed:        2,
+	},
+}
+
+// UnitTypeIDs - returns types of all units sorted by ID
+func UnitTypeIDs() (result []rpc.UnitType) {
+	for id := range UnitTypes {
+		result = append(result, id)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return
+}
+
+func IsValidUnitType(typeValue int32) bool {
+	_, found := UnitTypes[rpc.UnitType(typeValue)]
+	return found
+}
+
+func (u Unit) ToRPC() *rpc.UnitInfo {
+	return &rpc.UnitInfo{
+		UnitType:     u.ID,
+		Name:         u.Name,
+		Cost:         u.Cost.ToRPC(),
+		TrainingTime: u.TrainingTime,
+		Attack:       u.Attack,
+		Health:       u.Health,
+		Speed:        u.Speed,
+	}
+}
+
+// Units - number of the units of every type
+type Units struct {
+	Spearmen uint64
+	Archers  uint64
+	Cavalry  uint64
+}
+
+// UnitAmount - returns the units of the single type
+func UnitAmount(unitType rpc.UnitType, count uint64) (result Units) {
+	switch unitType {
+	case rpc.UnitType_SPEARMAN:
+		result.Spearmen = count
+	case rpc.UnitType_ARCHER:
+		result.Archers = count
+	case rpc.UnitType_CAVALRY:
+		result.Cavalry = count
+	}
+
+	return
+}
+
+func ToModelUnits(units *rpc.Units) Units {
+	if units == nil {
+		return Units{}
+	}
+
+	return Units{
+		Spearmen: units.Spearmen,
+		Archers:  units.Archers,
+		Cavalry:  units.Cavalry,
+	}
+}
+
+func (u Units) ToRPC() *rpc.Units {
+	return &rpc.Units{
+		Spearmen: u.Spearmen,
+		Archers:  u.Archers,
+		Cavalry:  u.Cavalry,
+	}
+}
+
+// Count - returns the number of the units of the type
+func (u Units) Count(unitType rpc.UnitType) uint64 {
+	switch unitType {
+	case rpc.UnitType_SPEARMAN:
+		return u.Spearmen
+	case rpc.UnitType_ARCHER:
+		return u.Archers
+	case rpc.UnitType_CAVALRY:
+		return u.Cavalry
+	}
+
+	return 0
+}
+
+func (u Units) Total() uint64 {
+	return u.Spearmen + u.Archers + u.Cavalry
+}
+
+func (u Units) IsEmpty() bool {
+	return u.Total() == 0
+}
+
+func (u Units) IsEnough(requested Units) bool {
+	return u.Spearmen >= requested.Spearmen &&
+		u.Archers >= requested.Archers &&
+		u.Cavalry >= requested.Cavalry
+}
+
+func (u *Units) Add(units Units) {
+	u.Spearmen += units.Spearmen
+	u.Archers += units.Archers
+	u.Cavalry += units.Cavalry
+}
+
+// Subtract - decrements the units if there are enough of them, returns true if the units were subtracted
+func (u *Units) Subtract(units Units) bool {
+	if !u.IsEnough(units) {
+		return false
+	}
+
+	u.Spearmen -= units.Spearmen
+	u.Archers -= units.Archers
+	u.Cavalry -= units.Cavalry
+
+	return true
+}
+
+// forEach - calls the function for every unit type the units have
+func (u Units) forEach(f func(unit Unit, count uint64)) {
+	for _, unitType := range UnitTypeIDs() {
+		if count := u.Count(unitType); count > 0 {
+			f(UnitTypes[unitType], count)
+		}
+	}
+}
+
+// Attack - returns damage dealt by all units every battle round
+func (u Units) Attack() (result uint64) {
+	u.forEach(func(unit Unit, count uint64) {
+		result += unit.Attack * count
+	})
+
+	return
+}
+
+// Health - returns health of all units
+func (u Units) Health() (result uint64) {
+	u.forEach(func(unit Unit, count uint64) {
+		result += unit.Health * count
+	})
+
+	return
+}
+
+// Speed - returns speed of the slowest units
+func (u Units) Speed() float32 {
+	result := float32(0)
+	u.forEach(func(unit Unit, count uint64) {
+		if result == 0 || unit.Speed < result {
+			result = unit.Speed
+		}
+	})
+
+	return result
+}
+
+// Losses - returns the units killed by the damage, the units of every type lose the same share rounded up
+func (u Units) Losses(damage, health uint64) Units {
+	if damage >= health {
+		return u
+	}
+
+	lost := func(count uint64) uint64 {
+		return (count*damage + health - 1) / health
+	}
+
+	return Units{
+		Spearmen: lost(u.Spearmen),
+		Archers:  lost(u.Archers),
+		Cavalry:  lost(u.Cavalry),
+	}
+}
+
+// UnitTraining - units waiting in the town training queue or being trained
+type UnitTraining struct {
+	ID        int64
+	TownID    int64        `db:"town_id"`
+	UnitType  rpc.UnitType `db:"unit_type"`
+	Count     uint64
+	TicksLeft uint64 `db:"ticks_left"` // Game loop ticks left to train all units
+}
+
+// NewUnitTraining - returns the training of the units in the town, the training takes the training time of every unit
+func NewUnitTraining(townID int64, unitType rpc.UnitType, count uint64) UnitTraining {
+	return UnitTraining{
+		TownID:    townID,
+		UnitType:  unitType,
+		Count:     count,
+		TicksLeft: UnitTypes[unitType].TrainingTime * count,
+	}
+}
+
+// TrainingTime - returns ticks the training takes in total
+func (t UnitTraining) TrainingTime() uint64 {
+	return UnitTypes[t.UnitType].TrainingTime * t.Count
+}
+
+// Units - returns the units being trained
+func (t UnitTraining) Units() Units {
+	return UnitAmount(t.UnitType, t.Count)
+}
+
+func (t UnitTraining) ToRPC(inProgress bool) *rpc.UnitTraining {
+	return &rpc.UnitTraining{
+		Id:           t.ID,
+		TownID:       t.TownID,
+		UnitType:     t.UnitType,
+		Count:        t.Count,
+		TicksLeft:    t.TicksLeft,
+		TrainingTime: t.TrainingTime(),
+		InProgress:   inProgress,
+	}
+}
+
+// CanTrainUnits - returns true if the town has an active barracks
+func (t Town) CanTrainUnits() bool {
+	for _, building := range t.Buildings {
+		if building.ID == rpc.BuildingType_BARRACKS && t.IsBuildingActive(building) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTrainingQueueFull - returns true if no more units can be queued
+func (t Town) IsTrainingQueueFull() bool {
+	return len(t.Training) >= MaxTrainingQueue
+}
+
+// AdvanceTraining - progresses the first training of the queue by one tick if the town can train units,
+// returns the finished training removed from the queue or nil
+func (t *Town) AdvanceTraining() *UnitTraining {
+	if len(t.Training) == 0 || !t.CanTrainUnits() {
+		return nil
+	}
+
+	training := t.Training[0]
+	if training.TicksLeft > 0 {
+		training.TicksLeft--
+	}
+
+	if training.TicksLeft > 0 {
+		t.Training[0] = training
+		return nil
+	}
+
+	t.Training = t.Training[1:]
+	return &training
+}
+
+// TrainingToRPC - returns the training queue, the first entry is in progress if the town can train units
+func (t Town) TrainingToRPC() []*rpc.UnitTraining {
+	result := make([]*rpc.UnitTraining, 0, len(t.Training))
+	for i, training := range t.Training {
+		result = append(result, training.ToRPC(i == 0 && t.CanTrainUnits()))
+	}
+
+	return result
+}
+
+// Army - units of the character outside of the town garrisons
+type Army struct {
+	ID           int64
+	CharacterID  int64  `db:"character_id"`
+	Owner        string // Name of the character
+	TownID       int64  `db:"town_id"` // Town the army was formed in
+	Units        Units  `db:"units"`
+	Location     Vector2D
+	Destination  Vector2D
+	IsMoving     bool   `db:"is_moving"`
+	TargetTownID int64  `db:"target_town_id"` // Town the army moves to or besieges, 0 if none
+	SiegeTicks   uint64 `db:"siege_ticks"`
+}
+
+// NewArmy - returns the army standing at the town
+func NewArmy(character Character, town Town, units Units) Army {
+	return Army{
+		CharacterID: character.ID,
+		Owner:       character.Name,
+		TownID:      town.ID,
+		Units:       units,
+		Location:    town.Location(),
+	}
+}
+
+// MoveTo - orders the army to move to the destination, the siege is lifted
+func (a *Army) MoveTo(destination Vector2D) {
+	a.Destination = destination
+	a.IsMoving = a.Location != destination
+	a.TargetTownID = 0
+	a.SiegeTicks = 0
+}
+
+// Advance - moves the army towards the destination by one game loop tick, returns true if the army has arrived
+func (a *Army) Advance() bool {
+	if !a.IsMoving {
+		return false
+	}
+
+	dx := float64(a.Destination.X - a.Location.X)
+	dy := float64(a.Destination.Y - a.Location.Y)
+	distance := math.Hypot(dx, dy)
+	speed := float64(a.Units.Speed())
+
+	if distance <= speed {
+		a.Location = a.Destination
+		a.IsMoving = false
+		return true
+	}
+
+	a.Location.X += float32(dx * speed / distance)
+	a.Location.Y += float32(dy * speed / distance)
+
+	return false
+}
+
+// DistanceTo - returns the distance between the army and the point
+func (a Army) DistanceTo(point Vector2D) float64 {
+	return math.Hypot(float64(point.X-a.Location.X), float64(point.Y-a.Location.Y))
+}
+
+func (a Army) ToRPC() *rpc.Army {
+	result := &rpc.Army{
+		Id:       a.ID,
+		Owner:    a.Owner,
+		TownID:   a.TownID,
+		Units:    a.Units.ToRPC(),
+		Location: a.Location.ToRPC(),
+	}
+
+	if a.IsMoving {
+		result.Destination = a.Destination.ToRPC()
+	}
+
+	result.TargetTownID = a.TargetTownID
+	result.SiegeTicks = a.SiegeTicks
+
+	return result
+}
+
+// Battle - result of the battle of two armies
+type Battle struct {
+	AttackerLosses Units
+	DefenderLosses Units
+	AttackerWon    bool
+}
+
+// ResolveBattle - fights the battle in rounds until one of the sides is destroyed or the rounds are over.
+// Both sides deal their damage every round at once, the defender health gets the bonus in percents.
+// The attacker wins only if the defender is destroyed and some of the attackers survive
+func ResolveBattle(attacker, defender Units, defenderBonus uint64) (result Battle) {
+	for round := 0; round < MaxBattleRounds && !attacker.IsEmpty() && !defender.IsEmpty(); round++ {
+		roundResult := ResolveBattleRound(attacker, defender, defenderBonus)
+
+		attacker.Subtract(roundResult.AttackerLosses)
+		defender.Subtract(roundResult.DefenderLosses)
+		result.AttackerLosses.Add(roundResult.AttackerLosses)
+		result.DefenderLosses.Add(roundResult.DefenderLosses)
+	}
+
+	result.AttackerWon = defender.IsEmpty() && !attacker.IsEmpty()
+	return
+}
+
+// ResolveBattleRound - returns the losses of a single battle round
+func ResolveBattleRound(attacker, defender Units, defenderBonus uint64) (result Battle) {
+	result.AttackerLosses = attacker.Losses(defender.Attack(), attacker.Health())
+	result.DefenderLosses = defender.Losses(attacker.Attack(), defender.Health()*(100+defenderBonus)/100)
+
+	attacker.Subtract(result.AttackerLosses)
+	defender.Subtract(result.DefenderLosses)
+	result.AttackerWon = defender.IsEmpty() && !attacker.IsEmpty()
+
+	return
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaskCharacter - replaces every letter of the banned words in the chat messages
+const MaskCharacter = '*'
+
+// MaxSanctionDuration - the longest mute or ban, the longer ones are rejected so the end time doesn't overflow
+const MaxSanctionDuration = 10 * 365 * 24 * time.Hour
+
+// Sanctions - moderator restrictions of the character, they are over when the time has passed
+type Sanctions struct {
+	MutedUntil  time.Time `db:"muted_until"`
+	BannedUntil time.Time `db:"banned_until"`
+}
+
+func (s Sanctions) IsMuted(now time.Time) bool {
+	return now.Before(s.MutedUntil)
+}
+
+func (s Sanctions) IsBanned(now time.Time) bool {
+	return now.Before(s.BannedUntil)
+}
+
+// ChatReport - complaint of the player about the chat message
+type ChatReport struct {
+	ID         int64
+	MessageID  int64 `db:"message_id"`
+	Message    ChatMessage
+	ReporterID int64 `db:"reporter_id"`
+	Reporter   string
+	Reason     string
+	Time       time.Time `db:"created_at"`
+}
+
+// ModerationRecord - action of the moderator stored in the audit log
+type ModerationRecord struct {
+	ID          int64
+	ModeratorID int64 `db:"moderator_id"`
+	Moderator   string
+	TargetID    int64 `db:"target_id"`
+	Target      string
+	Action      rpc.ModerateCharacterRequest_Action
+	Until       time.Time // Zero if the sanction was lifted
+	Reason      string
+	Time        time.Time `db:"created_at"`
+}
+
+func IsValidModerationAction(actionValue int32) bool {
+	_, found := rpc.ModerateCharacterRequest_Action_name[actionValue]
+	return found
+}
+
+// IsLiftingAction - checks if the action ends the sanction instead of imposing it
+func IsLiftingAction(action rpc.ModerateCharacterRequest_Action) bool {
+	return action == rpc.ModerateCharacterRequest_UNMUTE || action == rpc.ModerateCharacterRequest_UNBAN
+}
+
+// MaskBannedWords - replaces the banned words in the text with the mask characters, the case is ignored.
+// Words are matched anywhere in the text, so the words containing the banned ones are masked too
+func MaskBannedWords(text string, bannedWords []string) string {
+	runes := []rune(text)
+	lowered := toLowerRunes(runes)
+	masked := false
+
+	for _, word := range bannedWords {
+		pattern := toLowerRunes([]rune(strings.TrimSpace(word)))
+		if len(pattern) == 0 {
+			continue
+		}
+
+		for i := 0; i+len(pattern) <= len(lowered); i++ {
+			if !hasRunesAt(lowered, pattern, i) {
+				continue
+			}
+
+			for j := i; j < i+len(pattern); j++ {
+				runes[j] = MaskCharacter
+			}
+			masked = true
+		}
+	}
+
+	if !masked {
+		return text
+	}
+
+	return string(runes)
+}
+
+// toLowerRunes - lowers the runes one by one, so the indexes of the result match the source
+func toLowerRunes(runes []rune) []rune {
+	result := make([]rune, len(runes))
+	for i, r := range runes {
+		result[i] = unicode.ToLower(r)
+	}
+
+	return result
+}
+
+func hasRunesAt(text []rune, pattern []rune, index int) bool {
+	for i, r := range pattern {
+		if text[index+i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r ChatReport) ToRPC() *rpc.ChatReport {
+	return &rpc.ChatReport{
+		Id:       r.ID,
+		Message:  r.Message.ToRPC(),
+		Reporter: r.Reporter,
+		Reason:   r.Reason,
+		Time:     r.Time.Unix(),
+	}
+}
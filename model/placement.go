@@ -0,0 +1,110 @@
+package model
+
+import (
+	"math"
+)
+
+const (
+	TownBuildRadius  = 30   // Buildings are placed within this distance from the town center
+	MaxBuildingSlope = 0.05 // Maximum terrain height difference under the building footprint
+)
+
+// Corners - returns corners of the building footprint rotated by the building rotation in degrees
+func (b Building) Corners() [4]Vector2D {
+	angle := float64(b.Rotation) * math.Pi / 180
+	sin, cos := float32(math.Sin(angle)), float32(math.Cos(angle))
+
+	halfWidth, halfLength := b.Footprint.X/2, b.Footprint.Y/2
+	offsets := [4]Vector2D{
+		{X: -halfWidth, Y: -halfLength},
+		{X: halfWidth, Y: -halfLength},
+		{X: halfWidth, Y: halfLength},
+		{X: -halfWidth, Y: halfLength},
+	}
+
+	var result [4]Vector2D
+	for i, offset := range offsets {
+		result[i] = Vector2D{
+			X: b.Location.X + offset.X*cos - offset.Y*sin,
+			Y: b.Location.Y + offset.X*sin + offset.Y*cos,
+		}
+	}
+
+	return result
+}
+
+// Overlaps - checks if footprints of the buildings intersect, touching buildings don't overlap
+func (b Building) Overlaps(other Building) bool {
+	first, second := b.Corners(), other.Corners()
+
+	// Separating axis theorem: rectangles don't intersect if their projections on any edge normal don't
+	for _, corners := range [][4]Vector2D{first, second} {
+		for i := 0; i < 2; i++ {
+			axis := Vector2D{X: corners[i+1].Y - corners[i].Y, Y: corners[i].X - corners[i+1].X}
+
+			firstMin, firstMax := project(first, axis)
+			secondMin, secondMax := project(second, axis)
+			if firstMax <= secondMin || secondMax <= firstMin {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func project(corners [4]Vector2D, axis Vector2D) (min, max float32) {
+	min = corners[0].X*axis.X + corners[0].Y*axis.Y
+	max = min
+	for _, corner := range corners[1:] {
+		value := corner.X*axis.X + corner.Y*axis.Y
+		if value < min {
+			min = value
+		}
+		if value > max {
+			max = value
+		}
+	}
+
+	return
+}
+
+// IsWithinBuildRadius - checks if the whole footprint of the building is within the town build radius
+func (t Town) IsWithinBuildRadius(building Building) bool {
+	for _, corner := range building.Corners() {
+		dx, dy := float64(corner.X)-float64(t.X), float64(corner.Y)-float64(t.Y)
+		if math.Hypot(dx, dy) > TownBuildRadius {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsOccupied - checks if the building overlaps the town buildings or the new buildings in the construction queue.
+// The building itself is skipped if it's already placed in the town
+func (t Town) IsOccupied(building Building) bool {
+	for _, other := range t.Buildings {
+		if building.TownBuildingID != 0 && other.TownBuildingID == building.TownBuildingID {
+			continue
+		}
+
+		if building.Overlaps(other) {
+			return true
+		}
+	}
+
+	for _, construction := range t.Construction {
+		if !construction.IsUpgrade() && building.Overlaps(construction.Building) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FootprintPoints - returns points of the footprint the terrain is checked at
+func (b Building) FootprintPoints() []Vector2D {
+	corners := b.Corners()
+	return append(corners[:], b.Location)
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+const (
+	CitizensPerFood      = 4  // Citizens fed by a single food unit every game loop tick
+	StarvationGraceTicks = 3  // Ticks of the food shortage before the population starts to shrink
+	StarvationLossRatio  = 10 // Every tick of the starvation one of this many unfed citizens dies, at least one
+
+	DefaultHappiness = 50
+	MaxHappiness     = 100
+
+	FoodVarietyHappiness   = 10 // Bonus for every kind of food the population gets
+	HousingHappiness       = 10 // Bonus if there is free housing, penalty if there is no free housing at all
+	HousingHeadroomPercent = 10 // Free housing required for the bonus, percent of MaxPopulation
+	StarvationHappiness    = 30 // Penalty during the food shortage
+	TaxHappinessDivider    = 2  // Every TaxHappinessDivider percents of the tax rate cost one happiness point
+)
+
+// Order the workers are dismissed in if the population shrinks, food producers leave their jobs last
+var dismissalOrder = []rpc.JobType{
+	rpc.JobType_BUILDER,
+	rpc.JobType_MINER,
+	rpc.JobType_WOODCUTTER,
+	rpc.JobType_HUNTER,
+	rpc.JobType_FARMER,
+}
+
+// HappinessFactors - happiness bonuses and penalties, the happiness is the default value plus all factors
+type HappinessFactors struct {
+	FoodVariety int64
+	Housing     int64
+	Taxes       int64
+	Starvation  int64
+	Buildings   int64
+}
+
+// Happiness - returns the happiness limited by [0, MaxHappiness]
+func (f HappinessFactors) Happiness() uint64 {
+	happiness := DefaultHappiness + f.FoodVariety + f.Housing + f.Taxes + f.Starvation + f.Buildings
+	if happiness < 0 {
+		return 0
+	}
+
+	if happiness > MaxHappiness {
+		return MaxHappiness
+	}
+
+	return uint64(happiness)
+}
+
+func (f HappinessFactors) ToRPC() *rpc.HappinessFactors {
+	return &rpc.HappinessFactors{
+		FoodVariety: f.FoodVariety,
+		Housing:     f.Housing,
+		Taxes:       f.Taxes,
+		Starvation:  f.Starvation,
+		Buildings:   f.Buildings,
+	}
+}
+
+// FoodConsumption - returns food eaten by the population every game loop tick
+func FoodConsumption(population uint64) uint64 {
+	return (population + CitizensPerFood - 1) / CitizensPerFood
+}
+
+// StarvationLoss - returns number of citizens died of starvation during a single tick
+func StarvationLoss(unfed uint64) uint64 {
+	loss := unfed / StarvationLossRatio
+	if loss == 0 && unfed > 0 {
+		loss = 1
+	}
+
+	return loss
+}
+
+// ProductionModifier - returns multiplier of the workers production, 1 for the default happiness
+func ProductionModifier(happiness uint64) float64 {
+	return 0.5 + float64(happiness)/MaxHappiness
+}
+
+// FoodVariety - returns number of jobs producing food which have workers
+func (w WorkDistribution) FoodVariety() (result int64) {
+	for id, workers := range w {
+		if workers > 0 && Jobs[id].Production.Food > 0 {
+			result++
+		}
+	}
+
+	return
+}
+
+// Dismiss - removes up to 'count' workers from their jobs, returns true if anyone was dismissed
+func (w WorkDistribution) Dismiss(count uint64) bool {
+	dismissed := false
+	for _, id := range dismissalOrder {
+		if count == 0 {
+			break
+		}
+
+		removed := w[id]
+		if removed > count {
+			removed = count
+		}
+
+		if removed > 0 {
+			w[id] -= removed
+			count -= removed
+			dismissed = true
+		}
+	}
+
+	return dismissed
+}
+
+// FoodConsumption - returns food eaten by the population of all towns every game loop tick
+func (c Character) FoodConsumption() (result uint64) {
+	for _, town := range c.Towns {
+		result += FoodConsumption(town.Population)
+	}
+
+	return
+}
+
+// IsStarving - returns true if the population didn't get enough food during the last tick
+func (c Character) IsStarving() bool {
+	return c.StarvingTicks > 0
+}
+
+// HappinessFactors - calculates the happiness factors by the current state of the character
+func (c Character) HappinessFactors() (result HappinessFactors) {
+	result.FoodVariety = c.Workers.FoodVariety() * FoodVarietyHappiness
+
+	population, maxPopulation := c.Population(), c.MaxPopulation()
+	switch {
+	case population >= maxPopulation:
+		result.Housing = -HousingHappiness
+	case (maxPopulation-population)*100 >= maxPopulation*HousingHeadroomPercent:
+		result.Housing = HousingHappiness
+	}
+
+	result.Taxes = -int64(c.TaxRate / TaxHappinessDivider)
+
+	if c.IsStarving() {
+		result.Starvation = -StarvationHappiness
+	}
+
+	for _, town := range c.Towns {
+		for _, building := range town.Buildings {
+			if town.IsBuildingActive(building) {
+				result.Buildings += int64(building.HappinessBonus)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"sort"
+)
+
+type Technology struct {
+	ID              rpc.TechnologyType
+	Name            string
+	Cost            Resources
+	ResearchTime    uint64               // Game loop ticks required to research the technology
+	Requires        []rpc.TechnologyType // Technologies researched before
+	Unlocks         []rpc.BuildingType   // Buildings which can't be placed until the technology is researched
+	StorageBonus    Resources            // Storage capacity added to every town
+	ProductionBonus Resources            // Percent added to the production rate of each resource
+}
+
+// Technologies - set of the technologies researched by the character, shared by the character and its towns
+type Technologies map[rpc.TechnologyType]bool
+
+// Research - technology being researched by the character
+type Research struct {
+	TechnologyID rpc.TechnologyType `db:"technology_id"`
+	TicksLeft    uint64             `db:"ticks_left"`
+}
+
+var (
+	TechnologyTree = map[rpc.TechnologyType]Technology{
+		rpc.TechnologyType_CARPENTRY: {
+			ID:              rpc.TechnologyType_CARPENTRY,
+			Name:            "carpentry",
+			Cost:            Resources{Wood: 100, Food: 50},
+			ResearchTime:    20,
+			Unlocks:         []rpc.BuildingType{rpc.BuildingType_SAWMILL},
+			ProductionBonus: Resources{Wood: 20},
+		},
+		rpc.TechnologyType_AGRICULTURE: {
+			ID:              rpc.TechnologyType_AGRICULTURE,
+			Name:            "agriculture",
+			Cost:            Resources{Wood: 60, Food: 100},
+			ResearchTime:    20,
+			Unlocks:         []rpc.BuildingType{rpc.BuildingType_FARM},
+			ProductionBonus: Resources{Food: 20},
+		},
+		rpc.TechnologyType_TRAPPING: {
+			ID:           rpc.TechnologyType_TRAPPING,
+			Name:         "trapping",
+			Cost:         Resources{Wood: 50, Food: 50, Leather: 20},
+			ResearchTime: 15,
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_HUNTER_LODGE},
+		},
+		rpc.TechnologyType_TANNING: {
+			ID:              rpc.TechnologyType_TANNING,
+			Name:            "tanning",
+			Cost:            Resources{Wood: 80, Food: 60, Leather: 60},
+			ResearchTime:    30,
+			Requires:        []rpc.TechnologyType{rpc.TechnologyType_TRAPPING},
+			Unlocks:         []rpc.BuildingType{rpc.BuildingType_TANNERY},
+			ProductionBonus: Resources{Leather: 25},
+		},
+		rpc.TechnologyType_MASONRY: {
+			ID:              rpc.TechnologyType_MASONRY,
+			Name:            "masonry",
+			Cost:            Resources{Wood: 100, Food: 50, Stone: 100},
+			ResearchTime:    30,
+			StorageBonus:    Resources{Stone: 500},
+			ProductionBonus: Resources{Stone: 20},
+		},
+		rpc.TechnologyType_STOREHOUSES: {
+			ID:           rpc.TechnologyType_STOREHOUSES,
+			Name:         "storehouses",
+			Cost:         Resources{Wood: 150, Food: 50, Stone: 50},
+			ResearchTime: 30,
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_WAREHOUSE},
+			StorageBonus: Resources{Wood: 500, Food: 500, Stone: 500, Leather: 500},
+		},
+		rpc.TechnologyType_TRADE: {
+			ID:           rpc.TechnologyType_TRADE,
+			Name:         "trade",
+			Cost:         Resources{Wood: 200, Food: 100, Stone: 100, Leather: 100},
+			ResearchTime: 40,
+			Requires:     []rpc.TechnologyType{rpc.TechnologyType_STOREHOUSES},
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_MARKET},
+		},
+		rpc.TechnologyType_WARFARE: {
+			ID:           rpc.TechnologyType_WARFARE,
+			Name:         "warfare",
+			Cost:         Resources{Wood: 200, Food: 150, Stone: 100, Leather: 100},
+			ResearchTime: 40,
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_BARRACKS},
+		},
+		rpc.TechnologyType_FORTIFICATION: {
+			ID:           rpc.TechnologyType_FORTIFICATION,
+			Name:         "fortification",
+			Cost:         Resources{Wood: 200, Food: 100, Stone: 400},
+			ResearchTime: 60,
+			Requires:     []rpc.TechnologyType{rpc.TechnologyType_WARFARE, rpc.TechnologyType_MASONRY},
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_WALL},
+		},
+		rpc.TechnologyType_RELIGION: {
+			ID:           rpc.TechnologyType_RELIGION,
+			Name:         "religion",
+			Cost:         Resources{Wood: 300, Food: 200, Stone: 300, Leather: 100},
+			ResearchTime: 60,
+			Requires:     []rpc.TechnologyType{rpc.TechnologyType_TRADE},
+			Unlocks:      []rpc.BuildingType{rpc.BuildingType_TEMPLE},
+		},
+	}
+)
+
+func IsValidTechnologyType(typeValue int32) bool {
+	_, found := TechnologyTree[rpc.TechnologyType(typeValue)]
+	return found
+}
+
+// TechnologyTypes - returns types of all technologies of the tree sorted by ID
+func TechnologyTypes() (result []rpc.TechnologyType) {
+	for id := range TechnologyTree {
+		result = append(result, id)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return
+}
+
+// ToInfoRPC - returns the tree entry of the technology
+func (t Technology) ToInfoRPC(researched bool) *rpc.TechnologyInfo {
+	return &rpc.TechnologyInfo{
+		TechnologyID:         t.ID,
+		Name:                 t.Name,
+		Cost:                 t.Cost.ToRPC(),
+		ResearchTime:         t.ResearchTime,
+		RequiredTechnologies: t.Requires,
+		UnlockedBuildings:    t.Unlocks,
+		StorageBonus:         t.StorageBonus.ToRPC(),
+		ProductionBonus:      t.ProductionBonus.ToRPC(),
+		Researched:           researched,
+	}
+}
+
+func (r Research) ToRPC() *rpc.Research {
+	return &rpc.Research{
+		TechnologyID: r.TechnologyID,
+		TicksLeft:    r.TicksLeft,
+		ResearchTime: TechnologyTree[r.TechnologyID].ResearchTime,
+	}
+}
+
+// NewResearch - returns the research of the technology, the research takes the technology research time
+func NewResearch(technology Technology) Research {
+	return Research{
+		TechnologyID: technology.ID,
+		TicksLeft:    technology.ResearchTime,
+	}
+}
+
+// CanResearch - returns an error if the technology is researched already or its required technologies aren't
+func (t Technologies) CanResearch(technology Technology) Error {
+	if t[technology.ID] {
+		return ErrTechnologyAlreadyResearched
+	}
+
+	for _, required := range technology.Requires {
+		if !t[required] {
+			return ErrTechnologyRequired
+		}
+	}
+
+	return nil
+}
+
+// IsBuildingUnlocked - returns true if all technologies unlocking the building are researched
+func (t Technologies) IsBuildingUnlocked(id rpc.BuildingType) bool {
+	for _, technology := range TechnologyTree {
+		for _, unlocked := range technology.Unlocks {
+			if unlocked == id && !t[technology.ID] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// StorageBonus - returns the storage capacity added to every town by the technologies
+func (t Technologies) StorageBonus() (result Resources) {
+	for id := range t {
+		result.Add(TechnologyTree[id].StorageBonus)
+	}
+
+	return
+}
+
+// Boost - returns the production increased by the production bonuses of the technologies
+func (t Technologies) Boost(production Resources) Resources {
+	var percent Resources
+	for id := range t {
+		percent.Add(TechnologyTree[id].ProductionBonus)
+	}
+
+	return Resources{
+		Wood:    production.Wood * (100 + percent.Wood) / 100,
+		Food:    production.Food * (100 + percent.Food) / 100,
+		Stone:   production.Stone * (100 + percent.Stone) / 100,
+		Leather: production.Leather * (100 + percent.Leather) / 100,
+		Gold:    production.Gold * (100 + percent.Gold) / 100,
+	}
+}
+
+// ShareTechnologies - makes the towns use the technologies of the character, must be called
+// after the towns are loaded or added
+func (c *Character) ShareTechnologies() {
+	if c.Technologies == nil {
+		c.Technologies = make(Technologies)
+	}
+
+	for i := range c.Towns {
+		c.Towns[i].Technologies = c.Technologies
+	}
+}
+
+// AdvanceResearch - advances the current research by a tick, returns the technology if it's researched
+func (c *Character) AdvanceResearch() (completed *Technology) {
+	if c.Research == nil {
+		return nil
+	}
+
+	if c.Research.TicksLeft > 0 {
+		c.Research.TicksLeft--
+	}
+
+	if c.Research.TicksLeft > 0 {
+		return nil
+	}
+
+	technology := TechnologyTree[c.Research.TechnologyID]
+	c.Research = nil
+
+	c.ShareTechnologies()
+	c.Technologies[technology.ID] = true
+
+	return &technology
+}
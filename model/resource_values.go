@@ -8,7 +8,8 @@ var (
 		Leather: 0,
 	}
 
-	ResourcesLimit = Resources{
+	// Storage capacity of a town without buildings
+	TownStorageLimit = Resources{
 		Wood:    2000,
 		Food:    2000,
 		Stone:   2000,
@@ -0,0 +1,50 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"time"
+)
+
+const (
+	MinSiegeTicks            = 3  // The town can't be captured earlier even without the garrison
+	MaxSiegeTicks            = 20 // The siege is lifted if the town isn't captured in time
+	WallDefenseBonus         = 50 // Percent of the garrison health per wall level
+	CapturedResourcesPercent = 50 // Part of the stored resources the captured town keeps, the rest is plundered
+	NewPlayerProtection      = 72 * time.Hour
+)
+
+// DefenseBonus - returns percent added to the health of the garrison by the active walls
+func (t Town) DefenseBonus() (result uint64) {
+	for _, building := range t.Buildings {
+		if building.ID == rpc.BuildingType_WALL && t.IsBuildingActive(building) {
+			result += WallDefenseBonus * building.Level
+		}
+	}
+
+	return
+}
+
+// Capture - transfers the town with its buildings to the new owner, the town keeps a share of its resources.
+// The debt and the training queue of the previous owner are dropped
+func (t *Town) Capture(owner string) {
+	t.OwnerName = owner
+	t.Resources = t.Resources.Share(CapturedResourcesPercent, 100)
+	t.Debt = 0
+	t.Training = nil
+}
+
+// AttackTown - orders the army to move to the town and besiege it
+func (a *Army) AttackTown(town Town) {
+	a.MoveTo(town.Location())
+	a.TargetTownID = town.ID
+}
+
+// IsBesieging - returns true if the army has reached the target town
+func (a Army) IsBesieging() bool {
+	return a.TargetTownID != 0 && !a.IsMoving
+}
+
+// IsProtected - returns true if the towns of the character can't be attacked
+func (c Character) IsProtected(now time.Time) bool {
+	return now.Before(c.ProtectedUntil)
+}
@@ -0,0 +1,78 @@
+package model
+
+const (
+	MaxTaxRate = 50 // Percent
+)
+
+// TaxIncome - returns gold paid by the population every game loop tick,
+// every citizen pays the tax rate percent of a gold unit
+func TaxIncome(population, taxRate uint64) uint64 {
+	return population * taxRate / 100
+}
+
+// TaxIncome - returns gold paid by the town population every game loop tick
+func (t Town) TaxIncome(taxRate uint64) uint64 {
+	return TaxIncome(t.Population, taxRate)
+}
+
+// Upkeep - returns gold the town pays for its buildings every game loop tick
+func (t Town) Upkeep() (result uint64) {
+	for _, building := range t.Buildings {
+		result += building.Upkeep
+	}
+
+	return
+}
+
+// IsInDebt - returns true if the town failed to pay the upkeep and hasn't repaid it yet
+func (t Town) IsInDebt() bool {
+	return t.Debt > 0
+}
+
+// IsBuildingActive - returns false if the building is disabled by the debt of the town,
+// the buildings without upkeep are always active
+func (t Town) IsBuildingActive(building Building) bool {
+	return building.Upkeep == 0 || !t.IsInDebt()
+}
+
+// UpdateTreasury - collects the taxes to the town storage and pays the upkeep. The debt is repaid first,
+// the upkeep the town can't pay is added to the debt
+func (t *Town) UpdateTreasury(taxRate uint64) {
+	gold := t.Resources.Gold + t.TaxIncome(taxRate)
+	expenses := t.Debt + t.Upkeep()
+
+	if gold >= expenses {
+		t.Resources.Gold = gold - expenses
+		t.Debt = 0
+	} else {
+		t.Resources.Gold = 0
+		t.Debt = expenses - gold
+	}
+}
+
+// TaxIncome - returns gold paid by the population of all towns every game loop tick
+func (c Character) TaxIncome() (result uint64) {
+	for _, town := range c.Towns {
+		result += town.TaxIncome(c.TaxRate)
+	}
+
+	return
+}
+
+// Upkeep - returns gold all towns pay for their buildings every game loop tick
+func (c Character) Upkeep() (result uint64) {
+	for _, town := range c.Towns {
+		result += town.Upkeep()
+	}
+
+	return
+}
+
+// Debt - returns the debt of all towns
+func (c Character) Debt() (result uint64) {
+	for _, town := range c.Towns {
+		result += town.Debt
+	}
+
+	return
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	rpc "abbysoft/gardarike-online/rpc/generated"
+	"math"
+)
+
+const (
+	MinTownDistance         = 2 * TownBuildRadius // Build areas of the towns never overlap
+	BaseTerritoryRadius     = 40                  // Territory of the new town
+	TerritoryPopulationStep = 100                 // The territory radius grows by one per this many people
+	MaxTerritoryRadius      = 100
+)
+
+// TerritoryRadius - returns the radius of the land controlled by the town, it grows with the population
+func (t Town) TerritoryRadius() float64 {
+	return math.Min(BaseTerritoryRadius+float64(t.Population)/TerritoryPopulationStep, MaxTerritoryRadius)
+}
+
+// DistanceTo - returns the distance between the town center and the point
+func (t Town) DistanceTo(point Vector2D) float64 {
+	return math.Hypot(float64(point.X)-float64(t.X), float64(point.Y)-float64(t.Y))
+}
+
+// InTerritory - checks if the point lies within the territory of the town
+func (t Town) InTerritory(point Vector2D) bool {
+	return t.DistanceTo(point) < t.TerritoryRadius()
+}
+
+// TerritoryIntersects - checks if the territory of the town reaches the rect
+func (t Town) TerritoryIntersects(xStart, xEnd, yStart, yEnd int) bool {
+	// The closest point of the rect to the town center
+	x := math.Max(float64(xStart), math.Min(float64(t.X), float64(xEnd)))
+	y := math.Max(float64(yStart), math.Min(float64(t.Y), float64(yEnd)))
+
+	return t.DistanceTo(Vector2D{X: float32(x), Y: float32(y)}) < t.TerritoryRadius()
+}
+
+// CheckTownPlacement - checks if the owner can found a town at the point next to the existing towns.
+// Towns must keep the minimum distance from each other and can't be placed inside the foreign territory
+func CheckTownPlacement(owner string, point Vector2D, towns []Town) Error {
+	for _, town := range towns {
+		if town.DistanceTo(point) < MinTownDistance {
+			return ErrTownTooClose
+		}
+
+		if town.OwnerName != owner && town.InTerritory(point) {
+			return ErrForeignTerritory
+		}
+	}
+
+	return nil
+}
+
+// TerritoryToRPC - returns the territory of the town for the map overlay
+func (t Town) TerritoryToRPC() *rpc.Territory {
+	return &rpc.Territory{
+		TownID: t.ID,
+		Owner:  t.OwnerName,
+		Center: t.Location().ToRPC(),
+		Radius: float32(t.TerritoryRadius()),
+	}
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"abbysoft/gardarike-online/model/consts"
+	rpc "abbysoft/gardarike-online/rpc/generated"
+)
+
+func (t Town) ResourcesToRPC(taxRate uint64) *rpc.TownResources {
+	return &rpc.TownResources{
+		TownID:         t.ID,
+		Resources:      t.Resources.ToRPC(),
+		StorageLimit:   t.StorageLimit().ToRPC(),
+		ProductionRate: t.ProductionRate().ToRPC(),
+		TaxIncome:      t.TaxIncome(taxRate),
+		Upkeep:         t.Upkeep(),
+		Debt:           t.Debt,
+	}
+}
+
+// MaxPopulation - returns the housing provided by the town and its buildings
+func (t Town) MaxPopulation() uint64 {
+	result := uint64(consts.TownPopulationBonus)
+	for _, building := range t.Buildings {
+		result += building.PopulationBonus
+	}
+
+	return result
+}
+
+// ProductionRate - returns resources produced by the active town buildings every game loop tick
+// boosted by the technologies
+func (t Town) ProductionRate() (result Resources) {
+	for _, building := range t.Buildings {
+		if t.IsBuildingActive(building) {
+			result.Add(building.Production)
+		}
+	}
+
+	return t.Technologies.Boost(result)
+}
+
+// StorageLimit - returns the storage capacity of the town, its buildings and the technologies
+func (t Town) StorageLimit() Resources {
+	result := TownStorageLimit
+	result.Add(t.Technologies.StorageBonus())
+	for _, building := range t.Buildings {
+		result.Add(building.StorageBonus)
+	}
+
+	return result
+}
+
+// IsStorageFull - returns true if the town can't store any more resources
+func (t Town) IsStorageFull() bool {
+	return t.Resources.IsEnough(t.StorageLimit())
+}
+
+// Store - adds the resources to the town storage, returns the resources exceeding the storage limit.
+// Gold isn't limited by the storage
+func (t *Town) Store(resources Resources) (overflow Resources) {
+	t.Resources.Add(resources)
+
+	limit := t.StorageLimit()
+	limit.Gold = t.Resources.Gold
+	stored := minResources(t.Resources, limit)
+
+	overflow = t.Resources
+	overflow.Subtract(stored)
+	t.Resources = stored
+
+	return overflow
+}
+
+// Feed - consumes food of the town population from the town storage, returns number of citizens left without food
+func (t *Town) Feed() (unfed uint64) {
+	consumption := FoodConsumption(t.Population)
+	if t.Resources.Food >= consumption {
+		t.Resources.Food -= consumption
+		return 0
+	}
+
+	fed := t.Resources.Food * CitizensPerFood
+	t.Resources.Food = 0
+
+	return t.Population - fed
+}
+
+// IsStorageFull - returns true if storages of all towns are full
+func (c Character) IsStorageFull() bool {
+	for _, town := range c.Towns {
+		if !town.IsStorageFull() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Produce - distributes the workers production among the towns in proportion to their population,
+// the rest goes to the capital. Every town gets the production of its buildings as well
+func (c *Character) Produce(workersProduction Resources) {
+	capital := c.Capital()
+	if capital == nil {
+		return
+	}
+
+	workersProduction = c.Technologies.Boost(workersProduction)
+	population := c.Population()
+	remaining := workersProduction
+
+	for i := range c.Towns {
+		town := &c.Towns[i]
+
+		share := workersProduction.Share(town.Population, population)
+		remaining.Subtract(share)
+
+		town.Store(share)
+		town.Store(town.ProductionRate())
+	}
+
+	capital.Store(remaining)
+}
+
+// Delivery - goods waiting to be delivered to the town by the game loop of its owner
+type Delivery struct {
+	ID        int64
+	TownID    int64     `db:"town_id"`
+	Resources Resources `db:"resources"`
+}
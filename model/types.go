@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"time"
 )
 
 type EventWrapper struct {
@@ -22,10 +23,15 @@ type Account struct {
 }
 
 type ChatMessage struct {
-	ID       int64
-	Sender   string
-	Text     string
-	IsSystem bool `db:"is_system"`
+	ID        int64
+	Sender    string
+	Text      string
+	IsSystem  bool `db:"is_system"`
+	Channel   rpc.ChatMessage_Channel
+	Recipient string    // Name of the recipient of the direct message
+	Region    string    // Region of the region channel message
+	Time      time.Time `db:"created_at"` // Zero for the system messages which aren't stored
+	Audience  []int64   `db:"-"`          // IDs of the characters the alliance message is delivered to
 }
 
 func (c ChatMessage) ToRPC() *rpc.ChatMessage {
@@ -36,11 +42,20 @@ func (c ChatMessage) ToRPC() *rpc.ChatMessage {
 		messageType = rpc.ChatMessage_NORMAL
 	}
 
+	var sentTime int64
+	if !c.Time.IsZero() {
+		sentTime = c.Time.Unix()
+	}
+
 	return &rpc.ChatMessage{
-		Id:     c.ID,
-		Sender: c.Sender,
-		Text:   c.Text,
-		Type:   messageType,
+		Id:        c.ID,
+		Sender:    c.Sender,
+		Text:      c.Text,
+		Type:      messageType,
+		Channel:   c.Channel,
+		Recipient: c.Recipient,
+		Region:    c.Region,
+		Time:      sentTime,
 	}
 }
 
@@ -76,6 +91,13 @@ type Town struct {
 	Name       string
 	Buildings  []Building
 	Rotation   float32
+	Resources  Resources  `db:"resources"` // Resources stored in the town
+	Debt       uint64     // Upkeep the town failed to pay
+	CapturedAt *time.Time `db:"captured_at"` // Time the town was captured by the owner, nil if the owner founded it
+
+	Construction []Construction // Construction queue sorted by ID, the first entries occupy the construction slots
+	Training     []UnitTraining // Training queue sorted by ID, only the first entry is trained
+	Technologies Technologies   `db:"-"` // Technologies of the owner
 }
 
 func (t Town) ToRPC() *rpc.Town {
@@ -97,6 +119,11 @@ type ChunkRange struct {
 	MaxY int `db:"max_y"`
 }
 
+type ChunksCount struct {
+	Global int64 `db:"global"`
+	Local  int64 `db:"local"`
+}
+
 type ChunkResources struct {
 	Trees   uint64
 	Stones  uint64
@@ -104,6 +131,66 @@ type ChunkResources struct {
 	Plants  uint64
 }
 
+func (c ChunkResources) Add(other ChunkResources) ChunkResources {
+	return ChunkResources{
+		Trees:   c.Trees + other.Trees,
+		Stones:  c.Stones + other.Stones,
+		Animals: c.Animals + other.Animals,
+		Plants:  c.Plants + other.Plants,
+	}
+}
+
+// Subtract - returns the difference, resources which would become negative are set to 0
+func (c ChunkResources) Subtract(other ChunkResources) ChunkResources {
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return 0
+		}
+		return a - b
+	}
+
+	return ChunkResources{
+		Trees:   sub(c.Trees, other.Trees),
+		Stones:  sub(c.Stones, other.Stones),
+		Animals: sub(c.Animals, other.Animals),
+		Plants:  sub(c.Plants, other.Plants),
+	}
+}
+
+func (c ChunkResources) Multiply(n uint64) ChunkResources {
+	return ChunkResources{
+		Trees:   c.Trees * n,
+		Stones:  c.Stones * n,
+		Animals: c.Animals * n,
+		Plants:  c.Plants * n,
+	}
+}
+
+func (c ChunkResources) IsEmpty() bool {
+	return c.Trees == 0 && c.Stones == 0 && c.Animals == 0 && c.Plants == 0
+}
+
+// SatisfiedRatio - returns the smallest taken/demand ratio among the resources used by 'c'
+func (c ChunkResources) SatisfiedRatio(taken, demand ChunkResources) float64 {
+	ratio := 1.0
+	check := func(used, taken, demand uint64) {
+		if used == 0 || demand == 0 {
+			return
+		}
+
+		if r := float64(taken) / float64(demand); r < ratio {
+			ratio = r
+		}
+	}
+
+	check(c.Trees, taken.Trees, demand.Trees)
+	check(c.Stones, taken.Stones, demand.Stones)
+	check(c.Animals, taken.Animals, demand.Animals)
+	check(c.Plants, taken.Plants, demand.Plants)
+
+	return ratio
+}
+
 type WorldMapChunk struct {
 	Number int32
 	X      int64
@@ -188,41 +275,111 @@ func (w WorldMapChunk) ToLocalRPC() (*rpc.LocalMapChunk, error) {
 }
 
 type Character struct {
-	ID                int64
-	AccountID         int64 `db:"account_id"`
-	Name              string
-	MaxPopulation     uint64 `db:"max_population"`
-	CurrentPopulation uint64 `db:"current_population"`
-	Towns             []Town
-	Resources         Resources
-	ProductionRate    Resources
+	ID            int64
+	AccountID     int64 `db:"account_id"`
+	Name          string
+	Happiness     uint64
+	TaxRate       uint64 `db:"tax_rate"`
+	StarvingTicks uint64 `db:"starving_ticks"` // Consecutive ticks of the food shortage
+	Towns         []Town // Sorted by ID, the first town is the capital
+	Workers       WorkDistribution
+	Technologies  Technologies
+	Research      *Research // Nil if nothing is being researched
+	Caravans      []Caravan // Caravans of the character on the way
+
+	ProtectedUntil time.Time `db:"protected_until"` // Towns of the new players can't be attacked until this time
+	IsModerator    bool      `db:"is_moderator"`
+	IsAdmin        bool      `db:"is_admin"`
+
+	Sanctions
 }
 
 func (c Character) HasTown(townID int64) bool {
+	return c.Town(townID) != nil
+}
+
+// TownIDs - returns IDs of all towns of the character
+func (c Character) TownIDs() []int64 {
+	result := make([]int64, 0, len(c.Towns))
 	for _, town := range c.Towns {
-		if town.ID == townID {
-			return true
+		result = append(result, town.ID)
+	}
+
+	return result
+}
+
+// Town - returns the character's town with the ID or nil if there is no such town
+func (c *Character) Town(townID int64) *Town {
+	for i := range c.Towns {
+		if c.Towns[i].ID == townID {
+			return &c.Towns[i]
 		}
 	}
 
-	return false
+	return nil
+}
+
+// Capital - returns the first town of the character or nil if the character has no towns,
+// the captured towns are after the founded ones so the capital isn't changed by the capture
+func (c *Character) Capital() *Town {
+	if len(c.Towns) == 0 {
+		return nil
+	}
+
+	return &c.Towns[0]
+}
+
+// Population - returns the population of all towns
+func (c Character) Population() (result uint64) {
+	for _, town := range c.Towns {
+		result += town.Population
+	}
+
+	return
+}
+
+// MaxPopulation - returns the housing of all towns
+func (c Character) MaxPopulation() (result uint64) {
+	for _, town := range c.Towns {
+		result += town.MaxPopulation()
+	}
+
+	return
+}
+
+// Resources - returns the resources stored in all towns
+func (c Character) Resources() (result Resources) {
+	for _, town := range c.Towns {
+		result.Add(town.Resources)
+	}
+
+	return
+}
+
+// ProductionRate - returns the production of the buildings of all towns
+func (c Character) ProductionRate() (result Resources) {
+	for _, town := range c.Towns {
+		result.Add(town.ProductionRate())
+	}
+
+	return
 }
 
 func (c Character) ToRPC() *rpc.Character {
 	return &rpc.Character{
 		Id:                c.ID,
 		Name:              c.Name,
-		MaxPopulation:     c.MaxPopulation,
-		CurrentPopulation: c.CurrentPopulation,
+		MaxPopulation:     c.MaxPopulation(),
+		CurrentPopulation: c.Population(),
 	}
 }
 
 type Resources struct {
-	CharacterID int64 `db:"character_id"`
-	Wood        uint64
-	Food        uint64
-	Stone       uint64
-	Leather     uint64
+	Wood    uint64
+	Food    uint64
+	Stone   uint64
+	Leather uint64
+	Gold    uint64
 }
 
 func (r Resources) ToRPC() *rpc.Resources {
@@ -231,9 +388,29 @@ func (r Resources) ToRPC() *rpc.Resources {
 		Stone:   r.Stone,
 		Food:    r.Food,
 		Leather: r.Leather,
+		Gold:    r.Gold,
+	}
+}
+
+func ToModelResources(resources *rpc.Resources) Resources {
+	if resources == nil {
+		return Resources{}
+	}
+
+	return Resources{
+		Wood:    resources.Wood,
+		Food:    resources.Food,
+		Stone:   resources.Stone,
+		Leather: resources.Leather,
+		Gold:    resources.Gold,
 	}
 }
 
+// IsEmpty - returns true if there are no resources at all
+func (r Resources) IsEmpty() bool {
+	return r == Resources{}
+}
+
 // Subtract - decrement resources by the provided values if there is enough
 // resources or do nothing
 // return true if the resources were subtracted
@@ -246,6 +423,7 @@ func (r *Resources) Subtract(resources Resources) bool {
 	r.Wood -= resources.Wood
 	r.Stone -= resources.Stone
 	r.Leather -= resources.Leather
+	r.Gold -= resources.Gold
 
 	return true
 }
@@ -255,8 +433,27 @@ func (r *Resources) Add(resources Resources) {
 	r.Wood += resources.Wood
 	r.Stone += resources.Stone
 	r.Leather += resources.Leather
+	r.Gold += resources.Gold
+}
+
+// Share - returns 'part' of 'total' share of the resources rounded down
+func (r Resources) Share(part, total uint64) Resources {
+	if total == 0 {
+		return Resources{}
+	}
 
-	*r = minResources(*r, ResourcesLimit)
+	return Resources{
+		Wood:    r.Wood * part / total,
+		Food:    r.Food * part / total,
+		Stone:   r.Stone * part / total,
+		Leather: r.Leather * part / total,
+		Gold:    r.Gold * part / total,
+	}
+}
+
+// Multiply - returns the resources multiplied by the factor
+func (r Resources) Multiply(factor uint64) Resources {
+	return r.Share(factor, 1)
 }
 
 func minResources(a, b Resources) (r Resources) {
@@ -273,6 +470,9 @@ func minResources(a, b Resources) (r Resources) {
 	if a.Leather > b.Leather {
 		r.Leather = b.Leather
 	}
+	if a.Gold > b.Gold {
+		r.Gold = b.Gold
+	}
 
 	return
 }
@@ -281,9 +481,6 @@ func (r Resources) IsEnough(requested Resources) bool {
 	return r.Food >= requested.Food &&
 		r.Stone >= requested.Stone &&
 		r.Wood >= requested.Wood &&
-		r.Leather >= requested.Leather
-}
-
-func (r Resources) IsLimitReached() bool {
-	return r.IsEnough(ResourcesLimit)
+		r.Leather >= requested.Leather &&
+		r.Gold >= requested.Gold
 }
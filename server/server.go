@@ -1,25 +1,36 @@
 package server
 
 import (
+	"abbysoft/gardarike-online/admin"
 	"abbysoft/gardarike-online/db/postgres"
 	"abbysoft/gardarike-online/generation"
+	"abbysoft/gardarike-online/health"
 	"abbysoft/gardarike-online/logic"
+	"abbysoft/gardarike-online/metrics"
 	"abbysoft/gardarike-online/model"
+	"context"
 	"fmt"
 	"github.com/golang/protobuf/proto"
 	zmq "github.com/pebbe/zmq4"
 	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+)
+
+const (
+	eventsChanSize = 10
 )
 
 type Server struct {
-	context     *zmq.Context
-	requestSock *zmq.Socket
-	eventSock   *zmq.Socket
-	config      Config
-	log         *log.Entry
-	logic       logic.Logic
-	handler     logic.PacketHandler
-	eventsChan  chan model.EventWrapper
+	context      *zmq.Context
+	requestSock  *zmq.Socket
+	eventSock    *zmq.Socket
+	config       Config
+	log          *log.Entry
+	logic        logic.Logic
+	handler      logic.PacketHandler
+	eventsChan   chan model.EventWrapper
+	admin        *admin.Server
+	socketsBound int32
 }
 
 type Config struct {
@@ -30,6 +41,7 @@ type Config struct {
 func NewServer(
 	config Config,
 	logicConfig logic.Config,
+	adminConfig admin.Config,
 	dbConfig postgres.Config,
 	generatorConfig generation.TerrainGeneratorConfig) (*Server, error) {
 	context, err := zmq.NewContext()
@@ -49,7 +61,7 @@ func NewServer(
 
 	logger := log.WithField("module", "server")
 
-	eventsChan := make(chan model.EventWrapper, 10)
+	eventsChan := make(chan model.EventWrapper, eventsChanSize)
 
 	generatorConfig.Debug = logicConfig.DebugTerrain
 
@@ -72,7 +84,7 @@ func NewServer(
 
 	handler := logic.NewPacketHandler(gameLogic)
 
-	return &Server{
+	server := &Server{
 		requestSock: sock,
 		eventSock:   eventSock,
 		config:      config,
@@ -81,24 +93,65 @@ func NewServer(
 		handler:     handler,
 		context:     context,
 		eventsChan:  eventsChan,
-	}, nil
+	}
+
+	if len(adminConfig.Endpoint) != 0 {
+		server.admin, err = admin.NewServer(adminConfig, gameLogic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init admin server: %w", err)
+		}
+
+		checker := health.NewChecker()
+		checker.AddLivenessCheck("gameLoop", gameLogic.CheckGameLoop)
+		checker.AddReadinessCheck("database", gameLogic.Database().Ping)
+		checker.AddReadinessCheck("sockets", server.checkSockets)
+		checker.AddReadinessCheck("events", server.checkEventsBacklog)
+
+		server.admin.Handle("/metrics", metrics.Handler())
+		server.admin.Handle(health.LivenessPath, checker.LivenessHandler())
+		server.admin.Handle(health.ReadinessPath, checker.ReadinessHandler())
+	}
+
+	return server, nil
+}
+
+// checkSockets - fails until the request and event sockets are bound
+func (s *Server) checkSockets(ctx context.Context) error {
+	if atomic.LoadInt32(&s.socketsBound) == 0 {
+		return fmt.Errorf("sockets aren't bound")
+	}
+
+	return nil
+}
+
+// checkEventsBacklog - fails if the events channel is full, request handlers are blocked in this case
+func (s *Server) checkEventsBacklog(ctx context.Context) error {
+	if backlog := len(s.eventsChan); backlog >= cap(s.eventsChan) {
+		return fmt.Errorf("events channel is full (%d events)", backlog)
+	}
+
+	return nil
 }
 
 func (s *Server) publishEvent(event model.EventWrapper) {
 	logger := s.log.
 		WithField("event", fmt.Sprintf("%T", event.Event.Payload)).
-		WithField("topic", event.Topic)
+		WithField("topic", event.Topic).
+		WithField("requestID", event.Event.RequestID)
 
 	bytes, err := proto.Marshal(event.Event)
 	if err != nil {
 		logger.Errorf("Failed to marshal server event: %v", err)
+		metrics.EventPublishFailures.WithLabelValues("marshal").Inc()
 		return
 	}
 
 	if _, err := s.eventSock.SendMessageDontwait(event.Topic, string(bytes)); err != nil {
 		logger.WithError(err).Error("Failed to push server event")
+		metrics.EventPublishFailures.WithLabelValues("send").Inc()
 	} else {
 		logger.WithField("payload", event.Event).Info("EventWrapper published to the clients")
+		metrics.EventsPublished.Inc()
 	}
 }
 
@@ -117,6 +170,8 @@ func (s *Server) Serve() error {
 		return fmt.Errorf("failed to bind server eventSock to address: %s: %w", s.config.EventEndpoint, err)
 	}
 
+	atomic.StoreInt32(&s.socketsBound, 1)
+
 	s.log.WithFields(log.Fields{
 		"requestEndpoint": s.config.RequestEndpoint,
 		"eventEndpoint":   s.config.EventEndpoint,
@@ -124,6 +179,12 @@ func (s *Server) Serve() error {
 
 	go s.serveEvents()
 
+	if s.admin != nil {
+		go func() {
+			s.log.WithError(s.admin.Serve()).Error("Admin server stopped")
+		}()
+	}
+
 	for {
 		packet, err := s.requestSock.Recv(0)
 		if err != nil {
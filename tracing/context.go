@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+type requestInfoKey struct{}
+
+// RequestInfo - identifies the client request the code is working on
+type RequestInfo struct {
+	RequestID     string
+	RequestName   string
+	SessionID     string
+	AccountID     int64
+	CharacterID   int64
+	CharacterName string
+}
+
+// WithRequestInfo - returns the context carrying the request info
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext - returns the request info stored in the context or the empty info
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	if ctx == nil {
+		return RequestInfo{}
+	}
+
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// RequestID - returns ID of the request the context belongs to
+func RequestID(ctx context.Context) string {
+	return RequestInfoFromContext(ctx).RequestID
+}
+
+// Logger - returns the logger entry tagged with the request info from the context
+func Logger(ctx context.Context, logger *log.Entry) *log.Entry {
+	info := RequestInfoFromContext(ctx)
+	fields := log.Fields{}
+
+	if info.RequestID != "" {
+		fields["requestID"] = info.RequestID
+	}
+	if info.RequestName != "" {
+		fields["requestName"] = info.RequestName
+	}
+	if info.SessionID != "" {
+		fields["sessionID"] = info.SessionID
+	}
+	if info.AccountID != 0 {
+		fields["accountID"] = info.AccountID
+	}
+	if info.CharacterName != "" {
+		fields["character"] = info.CharacterName
+	}
+
+	if len(fields) == 0 {
+		return logger
+	}
+
+	return logger.WithFields(fields)
+}
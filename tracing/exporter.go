@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+	defaultServiceName  = "gardarike-online"
+
+	exportQueueSize     = 2048
+	exportBatchSize     = 512
+	exportFlushInterval = 5 * time.Second
+	shutdownTimeout     = 10 * time.Second
+)
+
+type Config struct {
+	Exporter    string // Where to export spans: "stdout", "otlp" or nothing
+	Endpoint    string // OTLP/HTTP traces endpoint of the collector
+	ServiceName string
+}
+
+// newOTLPExporter - returns the OTLP/HTTP exporter sending spans to the collector URL,
+// failed exports are retried with the exponential backoff
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	if endpoint == "" {
+		endpoint = defaultOTLPEndpoint
+	}
+
+	collector, err := url.Parse(endpoint)
+	if err != nil || collector.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(collector.Host),
+		otlptracehttp.WithURLPath(collector.Path),
+	}
+	if collector.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+// requestIDGenerator - uses the request ID as the trace ID of the request spans,
+// so the trace for any log line can be found by its request ID
+type requestIDGenerator struct{}
+
+func (g requestIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, err := trace.TraceIDFromHex(RequestID(ctx))
+	if err != nil {
+		traceID, _ = trace.TraceIDFromHex(NewTraceID())
+	}
+
+	return traceID, g.NewSpanID(ctx, traceID)
+}
+
+func (g requestIDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	spanID, _ := trace.SpanIDFromHex(randomID(8))
+	return spanID
+}
+
+// Setup - configures the span exporter, returns the function flushing all pending spans.
+// onError is called on failed exports
+func Setup(config Config, onError func(err error)) (shutdown func(), err error) {
+	if config.ServiceName == "" {
+		config.ServiceName = defaultServiceName
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case ExporterNone:
+		return func() {}, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = newOTLPExporter(config.Endpoint)
+	default:
+		return nil, fmt.Errorf("unknown span exporter %q", config.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span exporter: %w", err)
+	}
+
+	// Spans are dropped if the exporter can't keep up, tracing must not slow down the game
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxQueueSize(exportQueueSize),
+			sdktrace.WithMaxExportBatchSize(exportBatchSize),
+			sdktrace.WithBatchTimeout(exportFlushInterval)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(config.ServiceName))),
+		sdktrace.WithIDGenerator(requestIDGenerator{}),
+	)
+
+	if onError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(onError))
+	}
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}, nil
+}
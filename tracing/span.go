@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"math"
+)
+
+const tracerName = "abbysoft/gardarike-online"
+
+// Span - unit of work inside the request trace
+type Span struct {
+	span trace.Span
+}
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("failed to generate random id: %v", err))
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// NewTraceID - returns new random 16 bytes trace ID encoded in hex
+func NewTraceID() string {
+	return randomID(16)
+}
+
+// StartSpan - starts the span as a child of the span stored in the context.
+// If the context has no span the new trace is started, its ID is the request ID if the context has it
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	kind := trace.SpanKindInternal
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		kind = trace.SpanKindServer
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &Span{span: span}
+}
+
+// SpanFromContext - returns the current span or nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+
+	return &Span{span: span}
+}
+
+func (s *Span) TraceID() string {
+	return s.span.SpanContext().TraceID().String()
+}
+
+// SetName - renames the span, e.g. when the operation becomes known after the span is started
+func (s *Span) SetName(name string) {
+	s.span.SetName(name)
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.span.SetAttributes(newAttribute(key, value))
+}
+
+func newAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			return attribute.Int64(key, int64(v))
+		}
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	}
+
+	return attribute.String(key, fmt.Sprint(value))
+}
+
+// SetError - marks the span as failed
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End - finishes the span and passes it to the exporter, subsequent calls do nothing
+func (s *Span) End() {
+	s.span.End()
+}
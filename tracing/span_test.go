@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	otlpcollector "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	otlpcommon "go.opentelemetry.io/proto/otlp/common/v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordSpans - routes the spans started by the test to the recorder
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithIDGenerator(requestIDGenerator{}),
+	))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	return recorder
+}
+
+func TestStartSpan_UsesRequestIDAsTraceID(t *testing.T) {
+	recorder := recordSpans(t)
+	requestID := NewTraceID()
+	ctx := WithRequestInfo(context.Background(), RequestInfo{RequestID: requestID})
+
+	ctx, parent := StartSpan(ctx, "Request")
+	_, child := StartSpan(ctx, "DatabaseTransaction")
+	child.End()
+	parent.End()
+
+	assert.Equal(t, requestID, parent.TraceID())
+	assert.Equal(t, requestID, child.TraceID())
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind())
+		assert.Equal(t, trace.SpanKindServer, spans[1].SpanKind())
+	}
+}
+
+func TestStartSpan_NewTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, first := StartSpan(context.Background(), "GameLoopTick")
+	_, second := StartSpan(context.Background(), "GameLoopTick")
+	first.End()
+
+	assert.Len(t, first.TraceID(), 32)
+	assert.NotEqual(t, first.TraceID(), second.TraceID())
+	assert.False(t, recorder.Ended()[0].Parent().IsValid())
+}
+
+func TestSpan_End(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := StartSpan(context.Background(), "Request")
+	span.SetName("Login")
+	span.SetAttribute("characterID", int64(5))
+	span.SetError(assert.AnError)
+	span.End()
+	span.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "Login", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, assert.AnError.Error(), spans[0].Status().Description)
+		assert.Equal(t, []attribute.KeyValue{attribute.Int64("characterID", 5)}, spans[0].Attributes())
+	}
+}
+
+func TestSetup(t *testing.T) {
+	_, err := Setup(Config{Exporter: "zipkin"}, nil)
+	assert.Error(t, err)
+
+	_, err = Setup(Config{Exporter: ExporterOTLP, Endpoint: "localhost"}, nil)
+	assert.Error(t, err)
+}
+
+func TestSetup_OTLP(t *testing.T) {
+	requests := make(chan *otlpcollector.ExportTraceServiceRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		request := &otlpcollector.ExportTraceServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer collector.Close()
+
+	shutdown, err := Setup(Config{Exporter: ExporterOTLP, Endpoint: collector.URL + "/v1/traces"}, func(err error) {
+		t.Error(err)
+	})
+	require.NoError(t, err)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	requestID := NewTraceID()
+	_, span := StartSpan(WithRequestInfo(context.Background(), RequestInfo{RequestID: requestID}), "Login")
+	span.End()
+	shutdown()
+
+	// The spans are sent in the OTLP/HTTP protobuf encoding with the service name of the resource
+	request := <-requests
+	require.Len(t, request.ResourceSpans, 1)
+	resource := request.ResourceSpans[0]
+	assert.Contains(t, resource.Resource.Attributes, &otlpcommon.KeyValue{
+		Key:   "service.name",
+		Value: &otlpcommon.AnyValue{Value: &otlpcommon.AnyValue_StringValue{StringValue: defaultServiceName}},
+	})
+
+	require.Len(t, resource.InstrumentationLibrarySpans, 1)
+	spans := resource.InstrumentationLibrarySpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Login", spans[0].Name)
+	assert.Equal(t, requestID, hex.EncodeToString(spans[0].TraceId))
+}
+
+func TestLogger(t *testing.T) {
+	entry := log.WithField("module", "test")
+
+	assert.Equal(t, entry, Logger(context.Background(), entry))
+
+	ctx := WithRequestInfo(context.Background(), RequestInfo{
+		RequestID:     "request",
+		RequestName:   "Login",
+		SessionID:     "session",
+		AccountID:     1,
+		CharacterName: "Rurik",
+	})
+
+	fields := Logger(ctx, entry).Data
+	assert.Equal(t, "test", fields["module"])
+	assert.Equal(t, "request", fields["requestID"])
+	assert.Equal(t, "Login", fields["requestName"])
+	assert.Equal(t, "session", fields["sessionID"])
+	assert.Equal(t, int64(1), fields["accountID"])
+	assert.Equal(t, "Rurik", fields["character"])
+}